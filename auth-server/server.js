@@ -568,8 +568,28 @@ app.post("/api/user", validateOrigin, async (req, res) => {
 });
 
 const MAX_LEVEL = 40;
+const MAX_PROCESSED_SESSIONS = 20;
+
+// Skin IDs unlocked by in-game achievements, keyed by achievement key (e.g. "conqueror": [120])
+const ACHIEVEMENT_SKINS = {};
+
+// Adds the counters of a session to the stored totals, peak values keep the maximum
+const mergeSessionStats = (totals = {}, session = {}) => {
+    const merged = { ...totals };
+    for (const [key, value] of Object.entries(session)) {
+        if (Array.isArray(value)) {
+            const current = Array.isArray(merged[key]) ? merged[key] : [];
+            merged[key] = value.map((count, i) => (current[i] || 0) + (count || 0));
+        } else if (typeof value === "number") {
+            merged[key] = key.startsWith("peak")
+                ? Math.max(merged[key] || 0, value)
+                : (merged[key] || 0) + value;
+        }
+    }
+    return merged;
+};
 app.post("/api/user/update/stats", validateOrigin, async (req, res) => {
-    const { userId, data } = req.body;
+    const { userId, sessionId, data } = req.body;
 
     if (!userId || !data) {
         return res.status(400).send("User ID and data are required.");
@@ -585,6 +605,16 @@ app.post("/api/user/update/stats", validateOrigin, async (req, res) => {
         }
 
         const userData = userDoc.data();
+
+        // Game servers retry failed submissions, a session must only be counted once
+        const processedSessions = userData.processedSessions || [];
+        if (sessionId && processedSessions.includes(sessionId)) {
+            return res.status(200).json({
+                message: "Stats already recorded for this session",
+                newlyUnlockedSkins: [],
+            });
+        }
+
         const currentStats = userData.statistics || {};
         const currentAchievements = userData.achievements || {};
 
@@ -593,6 +623,7 @@ app.post("/api/user/update/stats", validateOrigin, async (req, res) => {
             highscore: (currentStats.highscore || 0) < data.score ? data.score : currentStats.highscore,
             kills: (currentStats.kills || 0) + (data.kills || 0),
             playtime: (currentStats.playtime || 0) + (data.playtime || 0),
+            session: mergeSessionStats(currentStats.session, data.session),
         };
 
         // Calculate level progression
@@ -629,13 +660,35 @@ app.post("/api/user/update/stats", validateOrigin, async (req, res) => {
             progression.xp = Math.min(progression.xp, calculateRequiredXP(MAX_LEVEL));
         }
 
+        // Achievements earned in game are kept forever and may unlock skins tied to them
+        const gameAchievements = userData.gameAchievements || [];
+        for (const achievement of data.achievements || []) {
+            if (gameAchievements.includes(achievement)) {
+                continue;
+            }
+            gameAchievements.push(achievement);
+
+            for (const skinId of ACHIEVEMENT_SKINS[achievement] || []) {
+                skins.unlocked = skins.unlocked || [];
+                if (!skins.unlocked.includes(skinId)) {
+                    skins.unlocked.push(skinId);
+                    newUnlockedSkins.push(skinId);
+                }
+            }
+        }
+
         // Ensure that updatedAchievement is not undefined before updating Firestore
         const updateData = {
             statistics: newStats,
             progression: progression,
-            skins: skins
+            skins: skins,
+            gameAchievements: gameAchievements
         };
 
+        if (sessionId) {
+            updateData.processedSessions = [...processedSessions, sessionId].slice(-MAX_PROCESSED_SESSIONS);
+        }
+
         const unlockedAchievement = Object.keys(ACHIEVEMENTS).reduce((best, key) => {
             const { scoreRequired, priority, roleId } = ACHIEVEMENTS[key];
             // Check if the score qualifies for this achievement
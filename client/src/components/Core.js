@@ -101,13 +101,13 @@ export default class Core {
         bush = new Bush({ x: 900, y: -500 });
         this.renderer.addToQueue(bush, QueueType.OVERLAY);
 
-        let rock = new Rock({ x: 200, y: 600 }, 50, 60);
+        let rock = new Rock({ x: 200, y: 600 }, 50, 1, 60);
         this.renderer.addToQueue(rock, QueueType.STATIC);
-        rock = new Rock({ x: -1150, y: 0 }, 20, 220);
+        rock = new Rock({ x: -1150, y: 0 }, 20, 1, 220);
         this.renderer.addToQueue(rock, QueueType.STATIC);
-        rock = new Rock({ x: -1200, y: 110 }, 40, -120);
+        rock = new Rock({ x: -1200, y: 110 }, 40, 1, -120);
         this.renderer.addToQueue(rock, QueueType.STATIC);
-        rock = new Rock({ x: -1250, y: 0 }, 80, 120);
+        rock = new Rock({ x: -1250, y: 0 }, 80, 1, 120);
         this.renderer.addToQueue(rock, QueueType.STATIC);
 
         // Add the player to the render queue
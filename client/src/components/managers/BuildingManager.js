@@ -156,7 +156,8 @@ export class BuildingManager {
         }
 
         if (this.selectedBuildings.length > 0) {
-            const buildingIDs = this.selectedBuildings.map(building => building.id);
+            // The generation tells the server which building an ID named when it has been reused since
+            const buildingRefs = this.selectedBuildings.map(building => ({ id: building.id, generation: building.generation }));
             const onUpgradeClicked = (data) => {
 
                 if (data) {
@@ -175,7 +176,7 @@ export class BuildingManager {
                     this.deselectBuildings();
                     this.core.uiManager.hideUpgrades();
 
-                    this.core.networkManager.upgradeBuildings(buildingIDs, data.buildingVariant, neutralBaseID);
+                    this.core.networkManager.upgradeBuildings(buildingRefs, data.buildingVariant, neutralBaseID);
                 }else if(this.selectedBuildings.length === 1 && this.selectedBuildings[0].type === BuildingTypes.BARRACKS){
                     const neutralBaseID = isNeutralBase ? closestBase.id : null;
                     this.core.networkManager.toggleUnitSpawning(this.selectedBuildings[0].id, neutralBaseID)
@@ -188,7 +189,7 @@ export class BuildingManager {
 
                 const neutralBaseID = isNeutralBase ? closestBase.id : null;
 
-                this.core.networkManager.removeBuildings(buildingIDs, neutralBaseID);
+                this.core.networkManager.removeBuildings(buildingRefs, neutralBaseID);
             };
 
             this.core.uiManager.showUpgrades({
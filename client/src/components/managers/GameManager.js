@@ -13,6 +13,8 @@ class GameManager {
         this.rocks = [];
         this.rallyPoints = [];
         this.hasCommander = false;
+        this.mapInfo = null; // Map the server generated or loaded, sent before the game state
+        this.fogOfWar = false;
         this.activeBarracks = {
             current: 0,
             max: 5
@@ -73,6 +75,11 @@ class GameManager {
         this.core.miniMap.update();
     }
 
+    setMapInfo (mapInfo) {
+        this.mapInfo = mapInfo;
+        this.fogOfWar = mapInfo.fogOfWar;
+    }
+
     setCommander(bool){
         this.hasCommander = bool;
         this.core.buildingManager.showCoreUpgradePanel(); // Re-Render
@@ -26,6 +26,12 @@ export default class NetworkManager {
         // Initialize resource update timeout
         this.lastPing = null;
 
+        // Estimated server time minus local time, from heartbeats
+        this.serverTimeOffset = null;
+
+        // Sequence number of the last message received, a resync only asks for what came after it
+        this.lastSequence = 0;
+
         // Initialize login status and user data
         this.loggedIn = false;
         this.userData = null;
@@ -203,7 +209,8 @@ export default class NetworkManager {
     handleNetworkMessage (message) {
         this.dataReceived += message.bytes; // Track the size of the message received    
 
-        const { type, payload } = message;
+        const { type, payload, sequence } = message;
+        this.lastSequence = sequence;
 
         // Reset ping for RESOURCE_UPDATE
         if (type === MessageTypes.RESOURCE_UPDATE) {
@@ -229,6 +236,9 @@ export default class NetworkManager {
             [MessageTypes.UNITS_ROTATION_UPDATE, () => this.handleUnitsRotationUpdate(payload)],
             [MessageTypes.REMOVE_UNIT, () => this.handleRemoveUnit(payload)],
             [MessageTypes.SPAWN_UNIT, () => this.handleSpawnUnit(payload)],
+            [MessageTypes.UNIT_VISIBILITY, () => this.handleUnitVisibility(payload)],
+            [MessageTypes.BASE_VISIBILITY, () => this.handleBaseVisibility(payload)],
+            [MessageTypes.MAP_INFO, () => this.core.gameManager.setMapInfo(payload)],
             [MessageTypes.PLAYER_JOINED, () => this.handlePlayerJoined(payload)],
             [MessageTypes.PLAYER_LEFT, () => this.handlePlayerLeft(payload)],
             [MessageTypes.KILLED, () => this.handleKilled(payload)],
@@ -250,6 +260,7 @@ export default class NetworkManager {
             [MessageTypes.TURRET_ROTATION_UPDATE, () => this.handleTurretRotationUpdate(payload)],
             [MessageTypes.NEUTRAL_BASE_CAPTURED, () => this.handleNeutralBaseCaptured(payload)],
             [MessageTypes.SKIN_DATA, () => this.handleSkinData(payload)],
+            [MessageTypes.HEARTBEAT, () => this.handleHeartbeat(payload)],
             [MessageTypes.SERVER_VERSION, () => this.handleServerVersion(payload)],
             [MessageTypes.REBOOT_ALERT, () => this.handleRebootAlert(payload)],
             [MessageTypes.ERROR, () => this.handleError(payload)],
@@ -299,6 +310,21 @@ export default class NetworkManager {
         SkinCache.setSkinData(skinData);
     }
 
+    handleHeartbeat (payload) {
+        const { serverTime, rtt, receivedAt } = payload;
+
+        // The server sent its time about half a round trip ago
+        const localReceivedAt = receivedAt - performance.timeOrigin;
+        const offset = serverTime + rtt / 2 - localReceivedAt;
+
+        // Smooth the estimate so a single slow heartbeat does not shift it
+        if (this.serverTimeOffset === null) {
+            this.serverTimeOffset = offset;
+        } else {
+            this.serverTimeOffset += (offset - this.serverTimeOffset) * 0.1;
+        }
+    }
+
     handleServerVersion(payload) {
         const { version } = payload;
         const expectedVersion = localStorage.getItem('expectedServerVersion');
@@ -356,6 +382,7 @@ export default class NetworkManager {
                     return;
                 }
                 const newBuilding = new BuildingClass(owner.color, building.position, building.variant, building.id);
+                newBuilding.generation = building.generation;
                 owner.addBuilding(newBuilding);
 
                 if (building.type === BuildingTypes.BARRACKS) {
@@ -413,10 +440,10 @@ export default class NetworkManager {
 
         // Process neutral bases
         neutralBases.forEach(neutral => {
-            const { id, ownerID, health, position, buildings } = neutral;
+            const { id, ownerID, type, health, maxHealth, position, buildings } = neutral;
 
             // Create new neutral base instance
-            const newNeutral = new NeutralBase(id, position, health, ownerID);
+            const newNeutral = new NeutralBase(id, position, health, ownerID, type, maxHealth);
 
             if (clientPlayer && ownerID != -1) {
                 if (ownerID == clientPlayer.id) {
@@ -445,7 +472,7 @@ export default class NetworkManager {
 
         // Process rocks 
         rocks.forEach(rock => {
-            this.core.gameManager.addRock(new Rock(rock.position, rock.size, rock.rotation));
+            this.core.gameManager.addRock(new Rock(rock.position, rock.size, rock.shape, rock.rotation));
         });
 
         // Hide the connecting overlay once game state is synced
@@ -578,6 +605,32 @@ export default class NetworkManager {
         }
     }
 
+    // Enemy units that entered a bush disappear without an explosion, revealed units are created from scratch
+    handleUnitVisibility (payload) {
+        const { hidden, revealed } = payload;
+
+        hidden.forEach(({ playerID, unitID }) => {
+            const player = this.core.gameManager.getPlayerById(playerID);
+            if (!player) return;
+            player.removeUnit(unitID);
+        });
+
+        revealed.forEach(unit => {
+            const player = this.core.gameManager.getPlayerById(unit.playerID);
+            if (!player) return;
+
+            const UnitClass = UnitManager.getUnitClassByType(unit.type);
+            if (!UnitClass) {
+                console.warn(`Unit type '${unit.type}' not defined!`);
+                return;
+            }
+
+            // Replace a copy that was never hidden, e.g. when the visibility changed twice within one update
+            player.removeUnit(unit.id);
+            player.addUnit(new UnitClass(player.color, unit.position, unit.variant, unit.id));
+        });
+    }
+
     handleTurretRotationUpdate (payload) {
         // ! Currently  only receives normal turret data (not unit turrets, they are still calculated locally)
         const { isPlayer, ownerID, turretID, rotation } = payload;
@@ -630,6 +683,7 @@ export default class NetworkManager {
                 }
                 // Create a new building if no cached building is found
                 const newBuilding = new BuildingClass(player.color, building.position, building.variant, building.id);
+                newBuilding.generation = building.generation;
                 neutral.addBuilding(newBuilding);
 
                 if (player.isClient) {
@@ -712,11 +766,11 @@ export default class NetworkManager {
     }
 
     handleKilled (payload) {
-        const { killerID, score, xp, kills, playtime } = payload;
+        const { killerID, score, xp, kills, playtime, stats } = payload;
         const killer = this.core.gameManager.getPlayerById(killerID);
         if (!killer) return;
         this.core.gameManager.player = null; //? Invalidate the client player, to make GameState work correcly
-        this.core.uiManager.gameOver(killer, score);
+        this.core.uiManager.gameOver(killer, score, kills, stats);
 
         this._updateUserDataLocally(score, xp, kills, playtime);
     }
@@ -742,8 +796,44 @@ export default class NetworkManager {
         }
     }
 
+    // Enemy bases in the fog of war look like the server sends them before they are seen: full health and no buildings
+    handleBaseVisibility (payload) {
+        const { hidden, revealed } = payload;
+        const gameManager = this.core.gameManager;
+
+        hidden.forEach(playerID => {
+            const player = gameManager.getPlayerById(playerID);
+            if (!player) return;
+            player.buildings = [];
+            player.setHealth(player.health.max);
+            player.hiddenByFog = true;
+        });
+
+        revealed.forEach(({ playerID, health, buildings }) => {
+            const player = gameManager.getPlayerById(playerID);
+            if (!player) return;
+
+            player.buildings = [];
+            buildings.forEach(building => {
+                const BuildingClass = BuildingManager.getBuildingClassByType(building.type);
+                if (!BuildingClass) {
+                    console.warn(`Building type '${building.type}' not defined!`);
+                    return;
+                }
+                const newBuilding = new BuildingClass(player.color, building.position, building.variant, building.id);
+                newBuilding.generation = building.generation;
+                if (building.type === BuildingTypes.BARRACKS) {
+                    newBuilding.activated = building.unitSpawningActive;
+                }
+                player.addBuilding(newBuilding);
+            });
+            player.setHealth(health);
+            player.hiddenByFog = false;
+        });
+    }
+
     handleBuildingPlaced (payload) {
-        const { isPlayer, ownerID, buildingID, buildingType, position, unitSpawningActive } = payload;
+        const { isPlayer, ownerID, buildingID, generation, buildingType, position, unitSpawningActive } = payload;
         let base = null;
         let player = null;
         let isClient = false;
@@ -797,6 +887,8 @@ export default class NetworkManager {
             building = new BuildingClass(player.color, position, 0, buildingID);
         }
 
+        building.generation = generation;
+
         if (building.type === BuildingTypes.BARRACKS) {
             building.activated = unitSpawningActive;
             if (building.activated && isClient) {
@@ -902,7 +994,7 @@ export default class NetworkManager {
     }
 
     handleSpawnBullet (payload) {
-        const { isPlayer, ownerID, objectID, bulletID, targetPosition } = payload;
+        const { isPlayer, ownerID, objectID, bulletID, spawnPosition, targetPosition, speed, startTick, startTime } = payload;
         const buildingID = objectID;
         let base = null;
         if (isPlayer) {
@@ -914,23 +1006,35 @@ export default class NetworkManager {
 
         const turret = base.getBuilding(buildingID);
         if (!turret) return;
-        const bullet = new Bullet(turret.bulletDetails, base.color, targetPosition, bulletID);
+        const bullet = new Bullet(turret.bulletDetails, base.color, spawnPosition, bulletID);
+        bullet.setTrajectory(spawnPosition, targetPosition, speed, startTick, this.getBulletStartTime(startTime));
         base.spawnBullet(bullet, targetPosition, turret);
     }
 
     handleUnitSpawnBullet (payload) {
-        const { playerID, objectID, bulletID, targetPosition } = payload;
+        const { playerID, objectID, bulletID, spawnPosition, targetPosition, speed, startTick, startTime } = payload;
         const unitID = objectID;
         const player = this.core.gameManager.getPlayerById(playerID);
         if (!player) return;
 
         const unit = player.getUnit(unitID);
-        const bullet = new Bullet(unit.bulletDetails, player.color, targetPosition, bulletID);
+        if (!unit) return;
+        const bullet = new Bullet(unit.bulletDetails, player.color, spawnPosition, bulletID);
+        bullet.setTrajectory(spawnPosition, targetPosition, speed, startTick, this.getBulletStartTime(startTime));
         player.spawnBullet(bullet, targetPosition, unit);
     }
 
+    // Local time the bullet takes its first step at.
+    // Until the first heartbeat there is no clock offset, the server sends spawns right before that entity update
+    getBulletStartTime (startTime) {
+        if (this.serverTimeOffset === null) {
+            return performance.now();
+        }
+        return startTime - this.serverTimeOffset;
+    }
+
     handleRemoveBullet (payload) {
-        const { isPlayer, ownerID, bulletID } = payload;
+        const { isPlayer, ownerID, bulletID, position } = payload;
         let base = null;
         if (isPlayer) {
             base = this.core.gameManager.getPlayerById(ownerID);
@@ -939,11 +1043,16 @@ export default class NetworkManager {
         }
         if (!base) return;
 
+        // Snap to where the server removed the bullet before fading it out
+        const bullet = base.getBullet(bulletID);
+        if (bullet) {
+            bullet.finishTrajectory(position);
+        }
         base.markBulletForRemoval(bulletID);
     }
 
     handleBulletPositionUpdate (payload) {
-        const { isPlayer, ownerID, bulletID, targetPosition } = payload;
+        const { isPlayer, ownerID, bulletID, position, targetPosition, tick } = payload;
         let base = null;
         if (isPlayer) {
             base = this.core.gameManager.getPlayerById(ownerID);
@@ -954,7 +1063,7 @@ export default class NetworkManager {
 
         const bullet = base.getBullet(bulletID);
         if (bullet) {
-            bullet.setTargetPosition(targetPosition);
+            bullet.continueTrajectory(position, targetPosition, tick - bullet.startTick + 1);
         }
     }
 
@@ -997,13 +1106,13 @@ export default class NetworkManager {
         this.sendMessage(message);
     }
 
-    upgradeBuildings (buildingIDs, buildingVariant, neutralBaseID = null) {
-        const message = Message.createUpgradeBuildingsMessage(buildingIDs, buildingVariant, neutralBaseID)
+    upgradeBuildings (buildingRefs, buildingVariant, neutralBaseID = null) {
+        const message = Message.createUpgradeBuildingsMessage(buildingRefs, buildingVariant, neutralBaseID)
         this.sendMessage(message);
     }
 
-    removeBuildings (buildingIDs, neutralBaseID = null) {
-        const message = Message.createRemoveBuildingsMessage(buildingIDs, neutralBaseID);
+    removeBuildings (buildingRefs, neutralBaseID = null) {
+        const message = Message.createRemoveBuildingsMessage(buildingRefs, neutralBaseID);
         this.sendMessage(message);
     }
 
@@ -1038,7 +1147,7 @@ export default class NetworkManager {
     }
 
     sendResyncRequest () {
-        const message = Message.createRequestResyncMessage();
+        const message = Message.createRequestResyncMessage(this.lastSequence);
         this.sendMessage(message);
     }
 
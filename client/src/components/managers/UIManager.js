@@ -1423,18 +1423,28 @@ export default class UIManager {
         }
     }
 
-    updateGameOverStats (killerName, score) {
+    updateGameOverStats (killerName, score, kills = 0, stats = null) {
         const { time } = this.core.gameManager.stats;
         document.getElementById("game-over-killed-by").textContent = killerName;
         document.getElementById("game-over-time-survived").textContent = this.formatTime(time);
         document.getElementById("game-over-score").textContent = score || 0;
+        document.getElementById("game-over-kills").textContent = kills;
+
+        // The session summary is only sent when the player got killed
+        const sum = (counters, key) => counters.reduce((total, counter) => total + counter[key], 0);
+        document.getElementById("game-over-session-stats").style.display = stats ? "contents" : "none";
+        if (!stats) return;
+        document.getElementById("game-over-buildings").textContent = sum(stats.buildings, "placed");
+        document.getElementById("game-over-units").textContent = sum(stats.units, "spawned");
+        document.getElementById("game-over-damage").textContent = stats.damageDealt;
+        document.getElementById("game-over-captures").textContent = stats.neutralBasesCaptured;
     }
 
-    gameOver (killer, score) {
+    gameOver (killer, score, kills, stats) {
         this.core.camera.setPosition(killer.position, true);
         this.core.camera.setZoom(0.75);
 
-        this.updateGameOverStats(killer.name, score);
+        this.updateGameOverStats(killer.name, score, kills, stats);
 
         this.showGameUIElements(false);
         this.showGameOverUIElements(true);
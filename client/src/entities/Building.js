@@ -11,6 +11,7 @@ export default class Building extends Renderable {
     constructor (id, type, color, size, position, variant = 0, range = 0) {
         super();
         this.id = id;
+        this.generation = 0; // Counts reuses of the ID, set from the server
         this.type = type;
         this.color = color;
         this.position = position;
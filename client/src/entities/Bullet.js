@@ -1,5 +1,5 @@
 import Renderable from "../components/Renderable.js";
-import { BulletTypes } from "../network/constants.js";
+import { BulletTypes, ENTITY_UPDATE_INTERVAL } from "../network/constants.js";
 import Shapes from "../components/Shapes.js";
 
 export default class Bullet extends Renderable {
@@ -9,10 +9,18 @@ export default class Bullet extends Renderable {
         this.type = details.type;
         this.size = details.size;
         this.targetPosition = position;
-        this.position = position;
+        this.position = { ...position };
         this.color = color;
         this.speed = details.speed; // Speed in pixels per second
 
+        // Trajectory simulation, set once the spawn message arrives
+        this.startTick = 0; // Server tick of the first entity update the bullet moves in
+        this.startTime = null; // Local time of that entity update
+        this.step = 0; // Entity updates simulated so far
+        this.stepPosition = null; // Position after the current step
+        this.previousStepPosition = null; // Position after the previous step
+        this.reachedTarget = false;
+
         // Fading properties
         this.isFadingOut = false;
         this.fadeDuration = 250; // Duration of fade-out in milliseconds
@@ -75,6 +83,79 @@ export default class Bullet extends Renderable {
         this.targetPosition = targetPosition;
     }
 
+    // Start simulating the trajectory the server moves the bullet along
+    setTrajectory (spawnPosition, targetPosition, speed, startTick, startTime) {
+        this.position = { ...spawnPosition };
+        this.speed = speed;
+        this.startTick = startTick;
+        this.startTime = startTime;
+        this.continueTrajectory(spawnPosition, targetPosition, 0);
+    }
+
+    // Continue the simulation from the position the server reached after the given step
+    continueTrajectory (position, targetPosition, step) {
+        this.step = step;
+        this.stepPosition = { ...position };
+        this.previousStepPosition = { ...position };
+        this.reachedTarget = false;
+        this.setTargetPosition(targetPosition);
+    }
+
+    // Snap to where the server removed the bullet
+    finishTrajectory (position) {
+        this.continueTrajectory(position, position, this.step);
+        this.position = { ...position };
+    }
+
+    // Mirrors Bullet.UpdatePosition on the server for one entity update
+    _simulateStep () {
+        const dx = this.targetPosition.x - this.stepPosition.x;
+        const dy = this.targetPosition.y - this.stepPosition.y;
+        const distance = Math.sqrt(dx * dx + dy * dy);
+
+        let distanceToMove = this.speed * ENTITY_UPDATE_INTERVAL / 1000;
+
+        // Slow down to 30% of the speed when close to the target
+        const easeThreshold = 100;
+        if (distance < easeThreshold) {
+            let easedProgress = 1 - Math.pow(1 - distance / easeThreshold, 3);
+            if (easedProgress * distanceToMove < 0.05) {
+                easedProgress = 1;
+            }
+            distanceToMove *= 0.3 + 0.7 * easedProgress;
+        }
+
+        if (distanceToMove > distance) {
+            this.stepPosition = { ...this.targetPosition };
+            this.reachedTarget = true;
+            return;
+        }
+
+        this.stepPosition = {
+            x: this.stepPosition.x + dx / distance * distanceToMove,
+            y: this.stepPosition.y + dy / distance * distanceToMove
+        };
+    }
+
+    _updateTrajectory () {
+        const elapsed = performance.now() - this.startTime;
+        if (elapsed < 0) return; // Not moving yet
+
+        // Render one entity update behind the simulation to interpolate between steps
+        const completedSteps = Math.floor(elapsed / ENTITY_UPDATE_INTERVAL);
+        while (this.step < completedSteps + 1) {
+            this.previousStepPosition = this.stepPosition;
+            if (!this.reachedTarget) {
+                this._simulateStep();
+            }
+            this.step++;
+        }
+
+        const t = Math.min(1, (elapsed - completedSteps * ENTITY_UPDATE_INTERVAL) / ENTITY_UPDATE_INTERVAL);
+        this.position.x = this.previousStepPosition.x + (this.stepPosition.x - this.previousStepPosition.x) * t;
+        this.position.y = this.previousStepPosition.y + (this.stepPosition.y - this.previousStepPosition.y) * t;
+    }
+
     update (deltaTime) {
         if (this.isFadingOut) {
             const elapsed = Date.now() - this.fadeStartTime;
@@ -99,6 +180,8 @@ export default class Bullet extends Renderable {
 
                 this.size += deltaTime / 1000 * this.sizeIncrement;  
             }
+        } else if (this.startTime !== null) {
+            this._updateTrajectory();
         } else {
             // Define LERP function
             const lerp = (start, end, t) => start + (end - start) * t;
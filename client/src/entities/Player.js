@@ -37,6 +37,7 @@ export default class Player extends Renderable {
         this.spawningUnits = [];
         this.borderRotation = 0;
         this.hasSpawnProtection = hasSpawnProtection;
+        this.hiddenByFog = false; // Enemy base in the fog of war, its buildings are unknown until it is revealed
         this.spawnProtectionRadius = this.buildingRadius.max + 145;
 
         this._loadSkin(skinID);
@@ -415,10 +416,16 @@ export default class Player extends Renderable {
             circleBorder(this.spawnProtectionRadius, protectionColor, this.borderRotation);
         }
 
+        // Bases in the fog of war are drawn faded, their buildings are unknown
+        context.save();
+        if (this.hiddenByFog) {
+            context.globalAlpha = 0.5;
+        }
         const borderColor = ThemeManager.currentThemeProperties.lineColor;
         circleBorder(this.buildingRadius.min + 8, borderColor, this.borderRotation);
         circleBorder(this.buildingRadius.max, borderColor, this.borderRotation);
         baseCore();
+        context.restore();
 
 
         // Render the player's buildings
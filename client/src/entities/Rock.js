@@ -1,15 +1,26 @@
 import Renderable from "../components/Renderable.js";
 import Shapes from "../components/Shapes.js";
 
+// Rock shapes in the order of the server's PolygonType
+const ROCK_SHAPES = [
+    size => Shapes.getCirclePoints(size + 2),
+    size => Shapes.getHexagonPoints(size),
+    size => Shapes.getPentagonPoints(size),
+    size => Shapes.getRectanglePoints(size, size),
+    size => Shapes.getTrianglePoints(size),
+];
+
 export default class Rock extends Renderable {
-    constructor(position, size, rotation) {
+    constructor(position, size, shape, rotation) {
         super();
         this.position = position;
         this.size = size;     
+        this.shape = shape;
         this.rotation = rotation;  
 
-        this.mainRockEdgePoints = Shapes.getHexagonPoints(this.size);
-        this.topRockEdgePoints = Shapes.getHexagonPoints(this.size * 0.6);
+        const getPoints = ROCK_SHAPES[shape] || ROCK_SHAPES[1];
+        this.mainRockEdgePoints = getPoints(this.size);
+        this.topRockEdgePoints = getPoints(this.size * 0.6);
     }
 
     // Rendering
@@ -1,18 +1,20 @@
 import Player from "../Player.js";
 import SkinCache from "../../components/SkinCache.js";
+import { NeutralBaseNames, NeutralBaseTypes } from "../../network/constants.js";
 
 export default class NeutralBase extends Player {
-    constructor (id, position = { x: 0, y: 0 }, health = 1000, ownerId = null) {
+    constructor (id, position = { x: 0, y: 0 }, health = 1000, ownerId = null, type = NeutralBaseTypes.POWER_OUTPOST, maxHealth = 1000) {
         super();
         this.id = id;
         this.ownerID = null;
-        this.defaultName = "Neutral";
+        this.type = type;
+        this.defaultName = NeutralBaseNames[type] || "Neutral";
         this.name = this.defaultName;
         this.nameWidth = null;
         this.defaultColor = "#CCCCCC";
         this.color = this.defaultColor;
         this.position = position;
-        this.health = { current: health, max: 1000 };
+        this.health = { current: health, max: maxHealth };
         this.targetHealth = health; // Target health value for animation
         this.buildingRadius = {
             max: 260,
@@ -1,4 +1,4 @@
 import Core from "./components/Core.js";
 
 // Create the Core instance
-new Core("https://fra1.blobl.io", 6);
\ No newline at end of file
+new Core("https://fra1.blobl.io", 19);
\ No newline at end of file
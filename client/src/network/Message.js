@@ -53,8 +53,10 @@ export default class Message {
     }
 
 
-    static createRequestResyncMessage () {
-        const payload = new Uint8Array(1);
+    // The server replays the messages after the last received one if it still has them, otherwise it sends the full game state
+    static createRequestResyncMessage (lastSequence) {
+        const payload = new Uint8Array(4);
+        new DataView(payload.buffer).setUint32(0, lastSequence);
         return new Message(MessageTypes.CLIENT_REQUEST_RESYNC, payload);
     }
 
@@ -83,94 +85,102 @@ export default class Message {
         return new Message(MessageTypes.CLIENT_PLACE_BUILDING, payload);
     }
 
-    static createRemoveBuildingsMessage(buildingIDs, neutralBaseID = null) {
-        if (!Array.isArray(buildingIDs) || buildingIDs.length === 0) {
-            throw new Error("buildingIDs must be a non-empty array");
+    static createRemoveBuildingsMessage(buildingRefs, neutralBaseID = null) {
+        if (!Array.isArray(buildingRefs) || buildingRefs.length === 0) {
+            throw new Error("buildingRefs must be a non-empty array");
         }
     
         // Determine if neutralBaseID is provided (it can be 0, so explicitly check for null/undefined)
         const isNeutralBaseProvided = neutralBaseID !== null && neutralBaseID !== undefined;
     
         // Calculate the payload length:
-        // 1 byte for the flag, buildingIDs length, and 1 byte for neutralBaseID if provided
-        const payloadLength = buildingIDs.length + (isNeutralBaseProvided ? 2 : 1);
+        // 1 byte for the flag, 3 bytes per buildingRef (2 bytes ID, 1 byte generation), and 2 bytes for neutralBaseID if provided
+        const payloadLength = 1 + buildingRefs.length * 3 + (isNeutralBaseProvided ? 2 : 0);
         const payload = new Uint8Array(payloadLength); // Create the array with the correct length
+        const dataView = new DataView(payload.buffer);
     
         let offset = 0;
     
         // Set the flag byte: 0 means no neutralBaseID, 1 means neutralBaseID is included
         payload[offset++] = isNeutralBaseProvided ? 1 : 0;
     
-        // If neutralBaseID is provided, store it in the next byte
+        // If neutralBaseID is provided, store it in the next two bytes
         if (isNeutralBaseProvided) {
-            payload[offset++] = neutralBaseID;
+            dataView.setUint16(offset, neutralBaseID);
+            offset += 2;
         }
     
-        // Fill the payload with building IDs starting at the current offset
-        buildingIDs.forEach((buildingID) => {
-            payload[offset++] = buildingID; // Store each buildingID starting from the current offset
+        // Fill the payload with building references starting at the current offset
+        buildingRefs.forEach((buildingRef) => {
+            dataView.setUint16(offset, buildingRef.id); // Store each buildingRef starting from the current offset
+            dataView.setUint8(offset + 2, buildingRef.generation);
+            offset += 3;
         });
     
         return new Message(MessageTypes.CLIENT_REMOVE_BUILDINGS, payload);
     }
 
-    static createUpgradeBuildingsMessage(buildingIDs, buildingVariant, neutralBaseID = null) {
-        if (!Array.isArray(buildingIDs) || buildingIDs.length === 0) {
-            throw new Error("buildingIDs must be a non-empty array");
+    static createUpgradeBuildingsMessage(buildingRefs, buildingVariant, neutralBaseID = null) {
+        if (!Array.isArray(buildingRefs) || buildingRefs.length === 0) {
+            throw new Error("buildingRefs must be a non-empty array");
         }
     
         // Determine if neutralBaseID is provided (it can be 0, so explicitly check for null/undefined)
         const isNeutralBaseProvided = neutralBaseID !== null && neutralBaseID !== undefined;
     
         // Calculate the payload length:
-        // 1 byte for the flag, buildingIDs length, 1 byte for neutralBaseID if provided, and 1 byte for buildingVariant
-        const payloadLength = buildingIDs.length + (isNeutralBaseProvided ? 2 : 1) + 1;
+        // 1 byte for the flag, 3 bytes per buildingRef (2 bytes ID, 1 byte generation), 2 bytes for neutralBaseID if provided, and 1 byte for buildingVariant
+        const payloadLength = 1 + buildingRefs.length * 3 + (isNeutralBaseProvided ? 2 : 0) + 1;
         const payload = new Uint8Array(payloadLength); // Create the array with the correct length
+        const dataView = new DataView(payload.buffer);
     
         let offset = 0;
     
         // Set the flag byte: 0 means no neutralBaseID, 1 means neutralBaseID is included
         payload[offset++] = isNeutralBaseProvided ? 1 : 0;
     
-        // If neutralBaseID is provided, store it in the next byte
+        // If neutralBaseID is provided, store it in the next two bytes
         if (isNeutralBaseProvided) {
-            payload[offset++] = neutralBaseID;
+            dataView.setUint16(offset, neutralBaseID);
+            offset += 2;
         }
     
         // Store buildingVariant
         payload[offset++] = buildingVariant;
     
-        // Fill the payload with building IDs starting at the current offset
-        buildingIDs.forEach((buildingID) => {
-            payload[offset++] = buildingID; // Store each buildingID starting from the current offset
+        // Fill the payload with building references starting at the current offset
+        buildingRefs.forEach((buildingRef) => {
+            dataView.setUint16(offset, buildingRef.id); // Store each buildingRef starting from the current offset
+            dataView.setUint8(offset + 2, buildingRef.generation);
+            offset += 3;
         });
     
         return new Message(MessageTypes.CLIENT_UPGRADE_BUILDINGS, payload);
     }
 
     static createMoveUnitsMessage (units, targetPosition) {
-        const payload = new Uint8Array(1 + units.length + 4); // 4 bytes for position
-        payload[0] = units.length;
-        payload[1] = (targetPosition.x >> 8) & 0xFF; // High byte of x
-        payload[2] = targetPosition.x & 0xFF;        // Low byte of x
-        payload[3] = (targetPosition.y >> 8) & 0xFF; // High byte of y
-        payload[4] = targetPosition.y & 0xFF;        // Low byte of y
+        const payload = new Uint8Array(2 + 4 + units.length * 2); // 2 bytes for the count, 4 bytes for position
+        const dataView = new DataView(payload.buffer);
+        dataView.setUint16(0, units.length);
+        dataView.setInt16(2, targetPosition.x);
+        dataView.setInt16(4, targetPosition.y);
 
         // Set unit IDs in payload
         for (let i = 0; i < units.length; i++) {
-            payload[5 + i] = units[i].id;
+            dataView.setUint16(6 + i * 2, units[i].id);
         }
 
         return new Message(MessageTypes.CLIENT_MOVE_UNITS, payload);
     }
 
     static createToggleUnitSpawning (barracksID, neutralBaseID = null) {
-        const payloadLength = neutralBaseID !== null ? 2 : 1;
+        const payloadLength = neutralBaseID !== null ? 4 : 2;
         const payload = new Uint8Array(payloadLength);
+        const dataView = new DataView(payload.buffer);
 
-        payload[0] = barracksID;
-        if (neutralBaseID) {
-            payload[1] = neutralBaseID;
+        dataView.setUint16(0, barracksID);
+        if (neutralBaseID !== null) {
+            dataView.setUint16(2, neutralBaseID);
         }
 
         return new Message(MessageTypes.TOGGLE_UNIT_SPAWNING, payload);
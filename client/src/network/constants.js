@@ -66,6 +66,9 @@ export const MessageTypes = {
     BUY_COMMANDER: 39,
     CLIENT_REQUEST_SKIN_DATA: 40,
     SKIN_DATA: 41,
+    MAP_INFO: 58,
+    UNIT_VISIBILITY: 59,
+    BASE_VISIBILITY: 60,
     HEARTBEAT: 69,
     SERVER_VERSION: 98,
     REBOOT_ALERT: 99,
@@ -422,6 +425,12 @@ export const BulletTypes = {
     TRAPPER: 1
 }
 
+// Entity IDs are 2 bytes, the server sends this where an entity is absent
+export const NO_ID = 0xFFFF;
+
+// The server moves bullets once per entity update, in milliseconds
+export const ENTITY_UPDATE_INTERVAL = 50;
+
 export const BulletDetails = {
     SIMPLE_TURRET: {
         BASIC: {
@@ -474,6 +483,20 @@ export const BulletDetails = {
     }
 };
 
+export const NeutralBaseTypes = {
+    POWER_OUTPOST: 0,
+    BARRACKS_OUTPOST: 1,
+    FORTRESS: 2,
+    CITADEL: 3
+}
+
+export const NeutralBaseNames = {
+    [NeutralBaseTypes.POWER_OUTPOST]: "Power Outpost",
+    [NeutralBaseTypes.BARRACKS_OUTPOST]: "Barracks Outpost",
+    [NeutralBaseTypes.FORTRESS]: "Fortress",
+    [NeutralBaseTypes.CITADEL]: "Citadel"
+}
+
 export const UnitTypes = {
     SOLDIER: 0,
     TANK: 1,
@@ -1,4 +1,4 @@
-import { BuildingTypes, MessageTypes } from "./constants.js";
+import { BuildingTypes, MessageTypes, NO_ID } from "./constants.js";
 
 
 // Helper function to read a fixed-length string 
@@ -31,6 +31,7 @@ function readColor (dataView, offset) {
 }
 
 let socket = null;
+let receivedMessages = 0; // Sequence number of the last message received on the socket, the server numbers them the same way
 
 self.onmessage = (event) => {
     const { type, data } = event.data;
@@ -56,24 +57,11 @@ function connect (address) {
     }
 
     socket = new WebSocket(address);
+    receivedMessages = 0;
     socket.binaryType = "arraybuffer"; // Set binary type to ArrayBuffer
 
     socket.onopen = () => {
         self.postMessage({ type: 'connected', data: { address } });
-
-        // Start the heartbeat mechanism
-        socket.onopen = () => {
-            self.postMessage({ type: 'connected', data: { address } });
-
-            // Start the heartbeat mechanism
-            setInterval(() => {
-                if (socket.readyState === WebSocket.OPEN) {
-                    const heartbeatMessage = new Uint8Array([MessageTypes.HEARTBEAT]); 
-                    socket.send(heartbeatMessage);
-                }
-            }, 30000);
-        };
-
     };
 
     socket.onclose = (event) => {
@@ -84,6 +72,11 @@ function connect (address) {
     socket.onmessage = (event) => {
         const buffer = event.data;
         const message = decodeMessage(buffer);
+        message.sequence = ++receivedMessages;
+        if (message.type === MessageTypes.HEARTBEAT) {
+            // Answer right away so the round trip the server measures stays accurate
+            sendHeartbeatEcho(message.payload.serverTime);
+        }
         self.postMessage({ type: 'message', data: message });
     };
 
@@ -114,6 +107,22 @@ function sendMessage (message) {
     }
 }
 
+// Echo the server time with the local time, the server estimates round trip and clock offset from both
+function sendHeartbeatEcho (serverTime) {
+    const buffer = new ArrayBuffer(9);
+    const dataView = new DataView(buffer);
+    dataView.setUint8(0, MessageTypes.HEARTBEAT);
+    dataView.setUint32(1, serverTime);
+    dataView.setUint32(5, Math.floor(localTime()) >>> 0);
+    sendMessage(buffer);
+}
+
+// Milliseconds since the epoch with the precision of performance.now().
+// The worker and the main thread have different performance.now() origins, this is shared by both
+function localTime () {
+    return performance.timeOrigin + performance.now();
+}
+
 function decodeMessage (buffer) {
 
     const dataView = new DataView(buffer);
@@ -150,6 +159,9 @@ function decodePayload (messageType, payload) {
         [MessageTypes.UNITS_POSITION_UPDATE]: decodeUnitsPositionUpdate,
         [MessageTypes.UNITS_ROTATION_UPDATE]: decodeUnitsRotationUpdate,
         [MessageTypes.REMOVE_UNIT]: decodeRemoveUnit,
+        [MessageTypes.UNIT_VISIBILITY]: decodeUnitVisibility,
+        [MessageTypes.BASE_VISIBILITY]: decodeBaseVisibility,
+        [MessageTypes.MAP_INFO]: decodeMapInfo,
         [MessageTypes.SPAWN_BULLET]: decodeSpawnBullet,
         [MessageTypes.UNIT_SPAWN_BULLET]: decodeSpawnUnitBullet,
         [MessageTypes.REMOVE_BULLET]: decodeRemoveBullet,
@@ -162,14 +174,46 @@ function decodePayload (messageType, payload) {
         [MessageTypes.TURRET_ROTATION_UPDATE]: decodeTurretRotationUpdate,
         [MessageTypes.NEUTRAL_BASE_CAPTURED]: decodeNeutralBaseCaptured,
         [MessageTypes.SKIN_DATA]: decodeSkinData,
+        [MessageTypes.HEARTBEAT]: decodeHeartbeat,
         [MessageTypes.SERVER_VERSION]: decodeServerVersion,
         [MessageTypes.REBOOT_ALERT]: decodeRebootAlert,
         [MessageTypes.ERROR]: decodeError,
     };
 
+    // State messages start with the entity update they describe
+    let tick = null;
+    if (tickedMessageTypes.has(messageType)) {
+        tick = new DataView(payload).getUint32(0);
+        payload = payload.slice(4);
+    }
+
     // Retrieve the decoder function or default to returning the payload as-is
     const decoder = decoderMap[messageType];
-    return decoder ? decoder(payload) : payload;
+    if (!decoder) return payload;
+
+    const decoded = decoder(payload);
+    return tick === null ? decoded : { ...decoded, tick };
+}
+
+const tickedMessageTypes = new Set([
+    MessageTypes.BASE_HEALTH_UPDATE,
+    MessageTypes.GAME_STATE,
+    MessageTypes.RESOURCE_UPDATE,
+    MessageTypes.SPAWN_UNIT,
+    MessageTypes.UNITS_POSITION_UPDATE,
+    MessageTypes.UNITS_ROTATION_UPDATE,
+    MessageTypes.TURRET_ROTATION_UPDATE,
+    MessageTypes.UNIT_VISIBILITY,
+    MessageTypes.BASE_VISIBILITY,
+]);
+
+function decodeHeartbeat (payload) {
+    const dataView = new DataView(payload);
+    const serverTime = dataView.getUint32(0);
+    const tick = dataView.getUint32(4);
+    const rtt = dataView.getUint16(8);
+
+    return { serverTime, tick, rtt, receivedAt: localTime() };
 }
 
 function decodeBuildingPlacementFailed (payload) {
@@ -188,10 +232,12 @@ function decodeInitialBulletStates (payload) {
         const isPlayer = dataView.getUint8(offset++);
 
         // Read Owner ID based on the type
-        const ownerID = dataView.getUint8(offset++);
+        const ownerID = dataView.getUint16(offset);
+        offset += 2;
 
         // Read Bullet ID
-        const bulletID = dataView.getUint8(offset++);
+        const bulletID = dataView.getUint16(offset);
+        offset += 2;
 
         // Read Bullet Position X
         const positionX = dataView.getFloat32(offset, false); // false for big-endian
@@ -218,38 +264,39 @@ function decodeInitialBulletStates (payload) {
 function decodeNeutralBaseCaptured (payload) {
     const dataView = new DataView(payload);
 
-    const neutralID = dataView.getUint8(0);
+    const neutralID = dataView.getUint16(0);
 
-    // If payload size is 1, it indicates the base is not captured
-    if (dataView.byteLength === 1) {
+    // If payload size is 2, it indicates the base is not captured
+    if (dataView.byteLength === 2) {
         return { neutralID, playerID: null, buildings: [] };
     }
 
-    // Read playerID from the payload (now we know it will always exist if size > 1)
-    const playerID = dataView.getUint8(1);
+    // Read playerID from the payload (now we know it will always exist if size > 2)
+    const playerID = dataView.getUint16(2);
 
     // Initialize an array to hold buildings
     const buildings = [];
 
     // Start reading building data from the byte offset after playerID
-    let offset = 2; // Start after neutralID and playerID
+    let offset = 4; // Start after neutralID and playerID
     while (offset < dataView.byteLength) {
         // Check if there is enough data to read a building
-        if (offset + 11 > dataView.byteLength) {
-            break; // Not enough data to read the building (1 ID + 1 Type + 1 Variant + 4 X + 4 Y)
+        if (offset + 13 > dataView.byteLength) {
+            break; // Not enough data to read the building (2 ID + 1 Generation + 1 Type + 1 Variant + 4 X + 4 Y)
         }
 
-        const buildingID = dataView.getUint8(offset);
-        const buildingType = dataView.getUint8(offset + 1);
-        const buildingVariant = dataView.getUint8(offset + 2);
-        const positionX = dataView.getFloat32(offset + 3);
-        const positionY = dataView.getFloat32(offset + 7);
+        const buildingID = dataView.getUint16(offset);
+        const generation = dataView.getUint8(offset + 2);
+        const buildingType = dataView.getUint8(offset + 3);
+        const buildingVariant = dataView.getUint8(offset + 4);
+        const positionX = dataView.getFloat32(offset + 5);
+        const positionY = dataView.getFloat32(offset + 9);
 
         // Add building information to the array
-        buildings.push({ id: buildingID, type: buildingType, variant: buildingVariant, position: { x: positionX, y: positionY } });
+        buildings.push({ id: buildingID, generation, type: buildingType, variant: buildingVariant, position: { x: positionX, y: positionY } });
 
         // Move offset to the next building 
-        offset += 11;
+        offset += 13;
     }
 
     return { neutralID, playerID, buildings };
@@ -258,9 +305,9 @@ function decodeNeutralBaseCaptured (payload) {
 function decodeTurretRotationUpdate (payload) {
     const dataView = new DataView(payload);
     const isPlayer = dataView.getUint8(0);
-    const ownerID = dataView.getUint8(1);
-    const turretID = dataView.getUint8(2);
-    const rotation = dataView.getFloat32(3);
+    const ownerID = dataView.getUint16(1);
+    const turretID = dataView.getUint16(3);
+    const rotation = dataView.getFloat32(5);
     return { isPlayer, ownerID, turretID, rotation }
 }
 
@@ -334,9 +381,9 @@ function decodeError (payload) {
 
 function decodeChatMessage (payload) {
     const dataView = new DataView(payload);
-    const playerID = dataView.getUint8(0);
+    const playerID = dataView.getUint16(0);
     const maxMessageLength = 64; // Maximum expected length
-    const offset = 1;
+    const offset = 2;
 
     // Calculate the length of the message
     let actualMessageLength = 0;
@@ -373,7 +420,8 @@ function decodePlayerJoined (payload) {
     const dataView = new DataView(payload);
     let offset = 0;
 
-    const playerID = dataView.getUint8(offset++);
+    const playerID = dataView.getUint16(offset);
+    offset += 2;
     const baseColorResult = readColor(dataView, offset); // 3 bytes for hex color code
     offset = baseColorResult.offset;
     let color = baseColorResult.color;
@@ -419,20 +467,56 @@ function decodePlayerJoined (payload) {
 
 function decodePlayerLeft (payload) {
     const dataView = new DataView(payload);
-    const playerID = dataView.getUint8(0);
+    const playerID = dataView.getUint16(0);
     return { playerID };
 }
 
 
 function decodeKilled (payload) {
     const dataView = new DataView(payload);
-    const killerID = dataView.getUint8(0);
-    const score = dataView.getUint32(1);
-    const xp = dataView.getUint32(5);
-    const kills = dataView.getUint32(9);
-    const playtime = dataView.getUint32(13);
+    const killerID = dataView.getUint16(0);
+    const score = dataView.getUint32(2);
+    const xp = dataView.getUint32(6);
+    const kills = dataView.getUint32(10);
+    const playtime = dataView.getUint32(14);
+    const stats = decodeSessionStats(dataView, 18);
+
+    return { killerID, score, xp, kills, playtime, stats };
+}
 
-    return { killerID, score, xp, kills, playtime };
+// End-of-life summary, counters are indexed by building and unit type
+function decodeSessionStats (dataView, offset) {
+    const buildings = [];
+    const buildingTypeCount = dataView.getUint8(offset++);
+    for (let type = 0; type < buildingTypeCount; type++) {
+        buildings.push({
+            placed: dataView.getUint32(offset),
+            upgraded: dataView.getUint32(offset + 4),
+            destroyed: dataView.getUint32(offset + 8)
+        });
+        offset += 12;
+    }
+
+    const units = [];
+    const unitTypeCount = dataView.getUint8(offset++);
+    for (let type = 0; type < unitTypeCount; type++) {
+        units.push({
+            spawned: dataView.getUint32(offset),
+            lost: dataView.getUint32(offset + 4)
+        });
+        offset += 8;
+    }
+
+    return {
+        buildings,
+        units,
+        damageDealt: dataView.getUint32(offset),
+        damageTaken: dataView.getUint32(offset + 4),
+        neutralBasesCaptured: dataView.getUint32(offset + 8),
+        neutralBasesLost: dataView.getUint32(offset + 12),
+        peakPower: dataView.getUint16(offset + 16),
+        peakPopulation: dataView.getUint16(offset + 18)
+    };
 }
 
 function decodeKickNotification (payload) {
@@ -449,19 +533,20 @@ function decodeKickNotification (payload) {
 function decodeBaseHealthUpdate (payload) {
     const dateView = new DataView(payload);
     const isPlayer = dateView.getUint8(0);
-    const ownerID = dateView.getUint8(1);
-    const health = dateView.getUint16(2);
+    const ownerID = dateView.getUint16(1);
+    const health = dateView.getUint16(3);
     return { isPlayer, ownerID, health };
 }
 
 function decodeBuildingPlaced (payload) {
     const dataView = new DataView(payload);
     const isPlayer = dataView.getUint8(0); // 1 = Player, 0 = Neutral
-    const ownerID = dataView.getUint8(1);
-    const buildingID = dataView.getUint8(2);
-    const buildingType = dataView.getUint8(3);
+    const ownerID = dataView.getUint16(1);
+    const buildingID = dataView.getUint16(3);
+    const generation = dataView.getUint8(5);
+    const buildingType = dataView.getUint8(6);
 
-    let offset = 4; // Start reading position data after building type
+    let offset = 7; // Start reading position data after building type
     const position = {
         x: dataView.getFloat32(offset),
         y: dataView.getFloat32(offset + 4)
@@ -474,19 +559,19 @@ function decodeBuildingPlaced (payload) {
         unitSpawningActive = dataView.getUint8(offset) === 1; // 1 for active, 0 for inactive
     }
 
-    return { isPlayer, ownerID, buildingID, buildingType, position, unitSpawningActive };
+    return { isPlayer, ownerID, buildingID, generation, buildingType, position, unitSpawningActive };
 }
 
 
 function decodeBuildingsRemoved (payload) {
     const dataView = new DataView(payload);
     const isPlayer = dataView.getUint8(0); // 0 = Player, 1 = Neutral
-    const ownerID = dataView.getUint8(1);
+    const ownerID = dataView.getUint16(1);
 
-    // Read building IDs starting from byte 2 until the end of the payload
+    // Read building IDs starting from byte 3 until the end of the payload
     const buildingIDs = [];
-    for (let i = 2; i < dataView.byteLength; i++) {
-        buildingIDs.push(dataView.getUint8(i));
+    for (let i = 3; i + 1 < dataView.byteLength; i += 2) {
+        buildingIDs.push(dataView.getUint16(i));
     }
 
     return { isPlayer, ownerID, buildingIDs };
@@ -495,13 +580,13 @@ function decodeBuildingsRemoved (payload) {
 function decodeBuildingsUpgraded (payload) {
     const dataView = new DataView(payload);
     const isPlayer = dataView.getUint8(0); // 0 = Player, 1 = Neutral
-    const ownerID = dataView.getUint8(1);
-    const buildingVariant = dataView.getUint8(2);
+    const ownerID = dataView.getUint16(1);
+    const buildingVariant = dataView.getUint8(3);
 
-    // Read building IDs starting from byte 3 until the end of the payload
+    // Read building IDs starting from byte 4 until the end of the payload
     const buildingIDs = [];
-    for (let i = 3; i < dataView.byteLength; i++) {
-        buildingIDs.push(dataView.getUint8(i));
+    for (let i = 4; i + 1 < dataView.byteLength; i += 2) {
+        buildingIDs.push(dataView.getUint16(i));
     }
 
     return { isPlayer, ownerID, buildingVariant, buildingIDs };
@@ -510,9 +595,9 @@ function decodeBuildingsUpgraded (payload) {
 function decodeBarracksActivationUpdate (payload) {
     const dataView = new DataView(payload);
     const isPlayer = dataView.getUint8(0);
-    const ownerID = dataView.getUint8(1);
-    const buildingID = dataView.getUint8(2);
-    const isActivated = dataView.getUint8(3) === 1;
+    const ownerID = dataView.getUint16(1);
+    const buildingID = dataView.getUint16(3);
+    const isActivated = dataView.getUint8(5) === 1;
     return { isPlayer, ownerID, buildingID, isActivated };
 }
 
@@ -521,7 +606,8 @@ function decodeInitialGameState (payload) {
     let offset = 0;
 
     const decodePlayer = () => {
-        const id = dataView.getUint8(offset++);
+        const id = dataView.getUint16(offset);
+        offset += 2;
         const hasSpawnProtection = dataView.getUint8(offset++);
         const health = dataView.getUint16(offset);
         offset += 2;
@@ -563,61 +649,43 @@ function decodeInitialGameState (payload) {
     };
 
     const decodeBuildings = () => {
-        const numBuildings = dataView.getUint8(offset++);
+        const numBuildings = dataView.getUint16(offset);
+        offset += 2;
         const buildings = [];
 
         for (let i = 0; i < numBuildings; i++) {
-            const buildingId = dataView.getUint8(offset++);
-            const buildingType = dataView.getUint8(offset++);
-            const buildingVariant = dataView.getUint8(offset++);
-
-            const buildingX = dataView.getFloat32(offset);
-            const buildingY = dataView.getFloat32(offset + 4);
-            offset += 8;
-
-            // Check if building is a barrack and read UnitSpawning status if applicable
-            let unitSpawningActive = null;
-            if (buildingType === BuildingTypes.BARRACKS) {
-                unitSpawningActive = dataView.getUint8(offset++) === 1; // 1 for active, 0 for inactive
-            }
-
-            buildings.push({
-                id: buildingId,
-                type: buildingType,
-                variant: buildingVariant,
-                position: { x: buildingX, y: buildingY },
-                unitSpawningActive // Add to building data if it's a barrack
-            });
+            const result = decodeBuildingData(dataView, offset);
+            buildings.push(result.building);
+            offset = result.offset;
         }
 
         return buildings;
     };
 
     const decodeUnits = () => {
-        const numUnits = dataView.getUint8(offset++);
+        const numUnits = dataView.getUint16(offset);
+        offset += 2;
         const units = [];
         for (let i = 0; i < numUnits; i++) {
-            const unitId = dataView.getUint8(offset++);
-            const unitType = dataView.getUint8(offset++);
-            const unitVariant = dataView.getUint8(offset++);
-            const unitX = dataView.getFloat32(offset);
-            const unitY = dataView.getFloat32(offset + 4);
-            offset += 8;
-            units.push({ id: unitId, type: unitType, variant: unitVariant, position: { x: unitX, y: unitY } });
+            units.push(decodeUnitData(dataView, offset));
+            offset += UNIT_DATA_BYTES;
         }
         return units;
     };
 
     const decodeNeutralBase = () => {
-        const id = dataView.getInt8(offset++);
-        let ownerID = dataView.getInt8(offset++);
-        ownerID = ownerID === 255 ? null : ownerID;
+        const id = dataView.getUint16(offset);
+        let ownerID = dataView.getUint16(offset + 2);
+        ownerID = ownerID === NO_ID ? null : ownerID;
+        offset += 4;
         const position = { x: dataView.getInt16(offset), y: dataView.getInt16(offset + 2) };
         offset += 4;
+        const type = dataView.getUint8(offset++);
         const health = dataView.getUint16(offset);
-        offset += 2;
+        const maxHealth = dataView.getUint16(offset + 2);
+        offset += 4;
         const buildings = decodeBuildings();
-        return { id, ownerID, health, position, buildings };
+        return { id, ownerID, type, health, maxHealth, position, buildings };
     };
 
     const decodeBush = () => {
@@ -633,31 +701,38 @@ function decodeInitialGameState (payload) {
         const size = dataView.getUint8(offset);
         offset += 1;
 
+        const shape = dataView.getUint8(offset);
+        offset += 1;
+
         const rotation = dataView.getFloat32(offset);
         offset += 4;
 
-        return { position, size, rotation };
+        return { position, size, shape, rotation };
     };
 
-    const numPlayers = dataView.getUint8(offset++);
+    const numPlayers = dataView.getUint16(offset);
+    offset += 2;
     const players = [];
     for (let i = 0; i < numPlayers; i++) {
         players.push(decodePlayer());
     }
 
-    const numNeutralBases = dataView.getUint8(offset++);
+    const numNeutralBases = dataView.getUint16(offset);
+    offset += 2;
     const neutralBases = [];
     for (let i = 0; i < numNeutralBases; i++) {
         neutralBases.push(decodeNeutralBase());
     }
 
-    const numBushes = dataView.getUint8(offset++);
+    const numBushes = dataView.getUint16(offset);
+    offset += 2;
     const bushes = [];
     for (let i = 0; i < numBushes; i++) {
         bushes.push(decodeBush());
     }
 
-    const numRocks = dataView.getUint8(offset++);
+    const numRocks = dataView.getUint16(offset);
+    offset += 2;
     const rocks = [];
     for (let i = 0; i < numRocks; i++) {
         rocks.push(decodeRock());
@@ -673,21 +748,19 @@ function decodeResourceUpdate (payload) {
 }
 
 function decodeSpawnUnit (payload) {
-    // Barrack ID (255) when commander spawns -> reflects that it doesnt spawn in a barrack
-    const invalidBarrackID = 255;
-
     const dataView = new DataView(payload)
     const isPlayer = dataView.getUint8(0);
-    const ownerID = dataView.getUint8(1);
-    let barracksID = dataView.getUint8(2);
-    barracksID = barracksID === invalidBarrackID ? -1 : barracksID;
-    const unitID = dataView.getUint8(3);
-    const unitType = dataView.getUint8(4);
-    const unitVariant = dataView.getUint8(5);
+    const ownerID = dataView.getUint16(1);
+    // No barracks ID when a commander spawns -> reflects that it doesnt spawn in a barrack
+    let barracksID = dataView.getUint16(3);
+    barracksID = barracksID === NO_ID ? -1 : barracksID;
+    const unitID = dataView.getUint16(5);
+    const unitType = dataView.getUint8(7);
+    const unitVariant = dataView.getUint8(8);
 
     // Read the float32 X and Y positions
-    const x = dataView.getFloat32(6);
-    const y = dataView.getFloat32(10);
+    const x = dataView.getFloat32(9);
+    const y = dataView.getFloat32(13);
 
     const targetPosition = { x, y };
 
@@ -696,24 +769,24 @@ function decodeSpawnUnit (payload) {
 
 function decodeUnitsPositionUpdate (payload) {
     const dataView = new DataView(payload);
-    const playerID = dataView.getUint8(0); // First byte is the player ID
+    const playerID = dataView.getUint16(0); // First two bytes are the player ID
     const units = [];
 
     // Start reading after the player ID
-    let offset = 1;
+    let offset = 2;
 
     while (offset < payload.byteLength) {
-        const unitID = dataView.getUint8(offset);
-        const unitX = dataView.getFloat32(offset + 1);
-        const unitY = dataView.getFloat32(offset + 5);
+        const unitID = dataView.getUint16(offset);
+        const unitX = dataView.getFloat32(offset + 2);
+        const unitY = dataView.getFloat32(offset + 6);
 
         units.push({
             id: unitID,
             targetPosition: { x: unitX, y: unitY },
         });
 
-        // Move to the next unit's data (1 byte for ID + 4 bytes for X + 4 bytes for Y = 9 bytes)
-        offset += 9;
+        // Move to the next unit's data (2 bytes for ID + 4 bytes for X + 4 bytes for Y = 10 bytes)
+        offset += 10;
     }
 
     return {
@@ -725,17 +798,17 @@ function decodeUnitsPositionUpdate (payload) {
 
 function decodeUnitsRotationUpdate (payload) {
     const dataView = new DataView(payload);
-    const playerID = dataView.getUint8(0);
+    const playerID = dataView.getUint16(0);
 
     // Initialize an array to hold the units' data
     const units = [];
 
-    // Start reading from the second byte
-    let offset = 1; // Skip the playerID
+    // Start reading from the third byte
+    let offset = 2; // Skip the playerID
 
     while (offset < payload.byteLength) {
-        const unitID = dataView.getUint8(offset); // Read unit ID
-        offset += 1;
+        const unitID = dataView.getUint16(offset); // Read unit ID
+        offset += 2;
 
         // Read the rotation value
         const rotation = dataView.getFloat32(offset);
@@ -754,10 +827,112 @@ function decodeUnitsRotationUpdate (payload) {
     };
 }
 
+// Building data as written by the server for the game state and revealed bases, barracks carry their spawning status
+function decodeBuildingData (dataView, offset) {
+    const id = dataView.getUint16(offset);
+    offset += 2;
+    const generation = dataView.getUint8(offset++);
+    const type = dataView.getUint8(offset++);
+    const variant = dataView.getUint8(offset++);
+
+    const position = { x: dataView.getFloat32(offset), y: dataView.getFloat32(offset + 4) };
+    offset += 8;
+
+    // Check if building is a barrack and read UnitSpawning status if applicable
+    let unitSpawningActive = null;
+    if (type === BuildingTypes.BARRACKS) {
+        unitSpawningActive = dataView.getUint8(offset++) === 1; // 1 for active, 0 for inactive
+    }
+
+    return { building: { id, generation, type, variant, position, unitSpawningActive }, offset };
+}
+
+function decodeBaseVisibility (payload) {
+    const dataView = new DataView(payload);
+    let offset = 0;
+
+    const numHidden = dataView.getUint16(offset);
+    offset += 2;
+    const hidden = [];
+    for (let i = 0; i < numHidden; i++) {
+        hidden.push(dataView.getUint16(offset));
+        offset += 2;
+    }
+
+    const numRevealed = dataView.getUint16(offset);
+    offset += 2;
+    const revealed = [];
+    for (let i = 0; i < numRevealed; i++) {
+        const playerID = dataView.getUint16(offset);
+        const health = dataView.getUint16(offset + 2);
+        const numBuildings = dataView.getUint16(offset + 4);
+        offset += 6;
+
+        const buildings = [];
+        for (let j = 0; j < numBuildings; j++) {
+            const result = decodeBuildingData(dataView, offset);
+            buildings.push(result.building);
+            offset = result.offset;
+        }
+        revealed.push({ playerID, health, buildings });
+    }
+
+    return { hidden, revealed };
+}
+
+function decodeMapInfo (payload) {
+    const dataView = new DataView(payload);
+    const seed = dataView.getBigUint64(0);
+    const layout = dataView.getUint8(8);
+    const expectedPlayers = dataView.getUint16(9);
+    const radius = dataView.getUint16(11);
+    const fogOfWar = dataView.getUint8(13) === 1;
+    const name = new TextDecoder().decode(payload.slice(14));
+
+    return { seed, layout, expectedPlayers, radius, fogOfWar, name };
+}
+
+// Unit data as written by the server for the game state and revealed units: ID, type, variant and position
+const UNIT_DATA_BYTES = 12;
+
+function decodeUnitData (dataView, offset) {
+    const id = dataView.getUint16(offset);
+    const type = dataView.getUint8(offset + 2);
+    const variant = dataView.getUint8(offset + 3);
+    const position = { x: dataView.getFloat32(offset + 4), y: dataView.getFloat32(offset + 8) };
+
+    return { id, type, variant, position };
+}
+
+function decodeUnitVisibility (payload) {
+    const dataView = new DataView(payload);
+    let offset = 0;
+
+    const numHidden = dataView.getUint16(offset);
+    offset += 2;
+    const hidden = [];
+    for (let i = 0; i < numHidden; i++) {
+        hidden.push({ playerID: dataView.getUint16(offset), unitID: dataView.getUint16(offset + 2) });
+        offset += 4;
+    }
+
+    const numRevealed = dataView.getUint16(offset);
+    offset += 2;
+    const revealed = [];
+    for (let i = 0; i < numRevealed; i++) {
+        const playerID = dataView.getUint16(offset);
+        offset += 2;
+        revealed.push({ playerID, ...decodeUnitData(dataView, offset) });
+        offset += UNIT_DATA_BYTES;
+    }
+
+    return { hidden, revealed };
+}
+
 function decodeRemoveUnit (payload) {
     const dataView = new DataView(payload);
-    const playerID = dataView.getUint8(0);
-    const unitID = dataView.getUint8(1);
+    const playerID = dataView.getUint16(0);
+    const unitID = dataView.getUint16(2);
 
     return { playerID, unitID }
 }
@@ -765,45 +940,57 @@ function decodeRemoveUnit (payload) {
 function decodeSpawnBullet (payload) {
     const dataView = new DataView(payload);
     const isPlayer = dataView.getUint8(0);
-    const ownerID = dataView.getUint8(1);
-    const objectID = dataView.getUint8(2);
-    const bulletID = dataView.getUint8(3);
-    const targetPositionX = dataView.getFloat32(4);
-    const targetPositionY = dataView.getFloat32(8);
+    const ownerID = dataView.getUint16(1);
+    const objectID = dataView.getUint16(3);
+    const bulletID = dataView.getUint16(5);
 
-    return { isPlayer, ownerID, objectID, bulletID, targetPosition: { x: targetPositionX, y: targetPositionY } };
+    return { isPlayer, ownerID, objectID, bulletID, ...decodeBulletTrajectory(dataView, 7) };
 }
 
 function decodeSpawnUnitBullet (payload) {
     const dataView = new DataView(payload);
-    const playerID = dataView.getUint8(0);
-    const objectID = dataView.getUint8(1);
-    const bulletID = dataView.getUint8(2);
-    const targetPositionX = dataView.getFloat32(3);
-    const targetPositionY = dataView.getFloat32(7);
+    const playerID = dataView.getUint16(0);
+    const objectID = dataView.getUint16(2);
+    const bulletID = dataView.getUint16(4);
 
-    return { playerID, objectID, bulletID, targetPosition: { x: targetPositionX, y: targetPositionY } };
+    return { playerID, objectID, bulletID, ...decodeBulletTrajectory(dataView, 6) };
 }
 
+// Spawn position, target, speed, behavior and the entity update the bullet starts moving in
+function decodeBulletTrajectory (dataView, offset) {
+    const spawnPosition = { x: dataView.getFloat32(offset), y: dataView.getFloat32(offset + 4) };
+    const targetPosition = { x: dataView.getFloat32(offset + 8), y: dataView.getFloat32(offset + 12) };
+    const speed = dataView.getFloat32(offset + 16);
+    const behavior = dataView.getUint8(offset + 20);
+    const startTick = dataView.getUint32(offset + 21);
+    const startTime = dataView.getUint32(offset + 25);
+
+    return { spawnPosition, targetPosition, speed, behavior, startTick, startTime };
+}
 
 function decodeRemoveBullet (payload) {
     const dataView = new DataView(payload);
     const isPlayer = dataView.getUint8(0);
-    const ownerID = dataView.getUint8(1);
-    const bulletID = dataView.getUint8(2);
+    const ownerID = dataView.getUint16(1);
+    const bulletID = dataView.getUint16(3);
+    const positionX = dataView.getFloat32(5);
+    const positionY = dataView.getFloat32(9);
 
-    return { isPlayer, ownerID, bulletID };
+    return { isPlayer, ownerID, bulletID, position: { x: positionX, y: positionY } };
 }
 
 function decodeBulletPositionUpdate (payload) {
     const dataView = new DataView(payload);
     const isPlayer = dataView.getUint8(0);
-    const ownerID = dataView.getUint8(1);
-    const bulletID = dataView.getUint8(2);
-    const targetPositionX = dataView.getFloat32(3);
-    const targetPositionY = dataView.getFloat32(7);
-
-    return { isPlayer, ownerID, bulletID, targetPosition: { x: targetPositionX, y: targetPositionY } };
+    const ownerID = dataView.getUint16(1);
+    const bulletID = dataView.getUint16(3);
+    const positionX = dataView.getFloat32(5);
+    const positionY = dataView.getFloat32(9);
+    const targetPositionX = dataView.getFloat32(13);
+    const targetPositionY = dataView.getFloat32(17);
+    const tick = dataView.getUint32(21);
+
+    return { isPlayer, ownerID, bulletID, position: { x: positionX, y: positionY }, targetPosition: { x: targetPositionX, y: targetPositionY }, tick };
 }
 
 function decodeLeaderboardUpdate (payload) {
@@ -818,8 +1005,8 @@ function decodeLeaderboardUpdate (payload) {
 
     for (let i = 0; i < changesCount; i++) {
         // Decode Player ID
-        const playerId = dataView.getUint8(offset);
-        offset += 1;
+        const playerId = dataView.getUint16(offset);
+        offset += 2;
 
         // Decode LeaderboardScore
         const unitCode = dataView.getUint8(offset);
@@ -879,7 +1066,7 @@ function decodeLeaderboardUpdate (payload) {
 
 function decodeRemoveSpawnProtection (payload) {
     const dataView = new DataView(payload);
-    const playerID = dataView.getUint8(0);
+    const playerID = dataView.getUint16(0);
     return { playerID }
 }
 
@@ -887,7 +1074,8 @@ function decodeInitialPlayerData (payload) {
     const dataView = new DataView(payload);
     let offset = 0;
 
-    const playerID = dataView.getUint8(offset++);
+    const playerID = dataView.getUint16(offset);
+    offset += 2;
 
     const baseColorResult = readColor(dataView, offset); // 3 bytes for hex color code
     offset = baseColorResult.offset;
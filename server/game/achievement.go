@@ -0,0 +1,239 @@
+package game
+
+import (
+	"log"
+	"time"
+)
+
+const achievementFlushTimeout = 2 * time.Second
+
+// AchievementRule describes when an achievement is earned. Every event of the rule's type
+// that matches credits one step to a player, the achievement is earned once Count steps
+// were reached in one life
+type AchievementRule struct {
+	ID    AchievementID
+	Key   string // Stable name reported with the stats, skins are tied to it
+	Name  string
+	Event EventType
+	Match func(event Event) (*Player, bool) // Returns the credited player
+	Count uint32
+}
+
+var achievementRules = []AchievementRule{
+	{
+		ID:    ACHIEVEMENT_FIRST_BLOOD,
+		Key:   "first_blood",
+		Name:  "First Blood",
+		Event: PlayerKilled,
+		Match: matchPlayerKill(nil),
+		Count: 1,
+	},
+	{
+		ID:    ACHIEVEMENT_RAMPAGE,
+		Key:   "rampage",
+		Name:  "Rampage",
+		Event: PlayerKilled,
+		Match: matchPlayerKill(nil),
+		Count: 3,
+	},
+	{
+		ID:    ACHIEVEMENT_COMMANDING_VICTORY,
+		Key:   "commanding_victory",
+		Name:  "Commanding Victory",
+		Event: PlayerKilled,
+		Match: matchPlayerKill(func(unit *Unit) bool { return unit.Type == COMMANDER }),
+		Count: 1,
+	},
+	{
+		ID:    ACHIEVEMENT_CONQUEROR,
+		Key:   "conqueror",
+		Name:  "Conqueror",
+		Event: NeutralBaseCaptured,
+		Match: matchNeutralBaseCaptured(nil),
+		Count: 3,
+	},
+	{
+		ID:    ACHIEVEMENT_CITADEL_LORD,
+		Key:   "citadel_lord",
+		Name:  "Citadel Lord",
+		Event: NeutralBaseCaptured,
+		Match: matchNeutralBaseCaptured(func(neutral *NeutralBase) bool { return neutral.Type == CITADEL }),
+		Count: 1,
+	},
+	{
+		ID:    ACHIEVEMENT_ARCHITECT,
+		Key:   "architect",
+		Name:  "Architect",
+		Event: BuildingPlaced,
+		Match: matchBuildingPlaced(nil),
+		Count: 50,
+	},
+	{
+		ID:    ACHIEVEMENT_ARMORED_DIVISION,
+		Key:   "armored_division",
+		Name:  "Armored Division",
+		Event: UnitSpawn,
+		Match: matchUnitSpawned(func(unit *Unit) bool { return unit.Type == TANK || unit.Type == SIEGE_TANK }),
+		Count: 20,
+	},
+}
+
+// GetAchievementRule returns the rule of an achievement
+func GetAchievementRule(id AchievementID) (AchievementRule, bool) {
+	for _, rule := range achievementRules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return AchievementRule{}, false
+}
+
+// GetEarnedAchievements returns the keys of the achievements the player earned in this life
+func (p *Player) GetEarnedAchievements() []string {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+
+	earned := make([]string, 0, len(p.Achievements))
+	for _, id := range p.Achievements {
+		if rule, ok := GetAchievementRule(id); ok {
+			earned = append(earned, rule.Key)
+		}
+	}
+	return earned
+}
+
+// startAchievementListener subscribes to the events used by the rules and evaluates them in the background
+func startAchievementListener() {
+	topics := []EventType{AchievementFlush}
+	for _, rule := range achievementRules {
+		topics = append(topics, rule.Event)
+	}
+
+	// Earned achievements unlock skins and must not be lost. The queue never stalls publishing, which matters
+	// because unlocking an achievement publishes an event itself
+	subscription := Bus.Subscribe(SubscriptionOptions{
+		Topics:     topics,
+		BufferSize: 1024,
+		Policy:     BUFFER_QUEUE,
+	})
+
+	go func() {
+		for event := range subscription.Events() {
+			if flush, ok := event.(*AchievementFlushEvent); ok {
+				close(flush.Done)
+				continue
+			}
+			evaluateAchievements(event)
+		}
+	}()
+}
+
+// FlushAchievements waits until every event triggered before the call was evaluated, so the
+// achievements of a finished session are complete when they are submitted
+func FlushAchievements() {
+	done := make(chan struct{})
+	eventChan <- &AchievementFlushEvent{Done: done}
+
+	select {
+	case <-done:
+	case <-time.After(achievementFlushTimeout):
+		log.Println("Achievement evaluation is behind, submitting the achievements earned so far")
+	}
+}
+
+func evaluateAchievements(event Event) {
+	for _, rule := range achievementRules {
+		if rule.Event != event.Type() {
+			continue
+		}
+
+		player, ok := rule.Match(event)
+		if !ok || player == nil {
+			continue
+		}
+
+		if player.advanceAchievement(rule) {
+			TriggerAchievementUnlockedEvent(player, rule.ID)
+		}
+	}
+}
+
+// advanceAchievement counts one step of a rule and reports if the achievement was just earned
+func (p *Player) advanceAchievement(rule AchievementRule) bool {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+
+	if p.AchievementProgress == nil {
+		p.AchievementProgress = make(map[AchievementID]uint32)
+	}
+
+	progress := p.AchievementProgress[rule.ID]
+	if progress >= rule.Count {
+		return false // Already earned
+	}
+
+	progress++
+	p.AchievementProgress[rule.ID] = progress
+	if progress < rule.Count {
+		return false
+	}
+
+	p.Achievements = append(p.Achievements, rule.ID)
+	return true
+}
+
+// matchPlayerKill credits the killer, optionally only if the killing unit passes the filter
+func matchPlayerKill(filter func(unit *Unit) bool) func(event Event) (*Player, bool) {
+	return func(event Event) (*Player, bool) {
+		e, ok := event.(*PlayerKilledEvent)
+		if !ok || e.Killer == nil {
+			return nil, false
+		}
+		if filter != nil && (e.Unit == nil || !filter(e.Unit)) {
+			return nil, false
+		}
+		return e.Killer, true
+	}
+}
+
+// matchNeutralBaseCaptured credits the captor, optionally only if the base passes the filter
+func matchNeutralBaseCaptured(filter func(neutral *NeutralBase) bool) func(event Event) (*Player, bool) {
+	return func(event Event) (*Player, bool) {
+		e, ok := event.(*NeutralBaseCapturedEvent)
+		if !ok || e.NeutralBase == nil || e.Captor == nil {
+			return nil, false
+		}
+		if filter != nil && !filter(e.NeutralBase) {
+			return nil, false
+		}
+		return e.Captor, true
+	}
+}
+
+// matchBuildingPlaced credits the player owning the base, optionally only if the building passes the filter
+func matchBuildingPlaced(filter func(building *Building) bool) func(event Event) (*Player, bool) {
+	return func(event Event) (*Player, bool) {
+		e, ok := event.(*BuildingPlacedEvent)
+		if !ok || e.Base == nil || e.Building == nil {
+			return nil, false
+		}
+		if filter != nil && !filter(e.Building) {
+			return nil, false
+		}
+		return getOwningPlayer(e.Base.Owner), true
+	}
+}
+
+// matchUnitSpawned credits the owner of the unit, optionally only if the unit passes the filter
+func matchUnitSpawned(filter func(unit *Unit) bool) func(event Event) (*Player, bool) {
+	return func(event Event) (*Player, bool) {
+		e, ok := event.(*UnitSpawnEvent)
+		if !ok || e.Unit == nil {
+			return nil, false
+		}
+		if filter != nil && !filter(e.Unit) {
+			return nil, false
+		}
+		return e.Unit.Player, true
+	}
+}
@@ -0,0 +1,222 @@
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+type Alliance struct {
+	ID      ID
+	Members []*Player
+}
+
+var (
+	// AllianceMaxSize limits how many players can be part of one alliance
+	AllianceMaxSize = ALLIANCE_DEFAULT_MAX_SIZE
+
+	availableAllianceIDs = InitAvailableIDs(MAX_ALLIANCES)
+	alliances            = make(map[ID]*Alliance)
+
+	// Guards every alliance and the alliance fields of all players
+	allianceMutex sync.RWMutex
+)
+
+// AreAllied checks if two different players are members of the same alliance
+func AreAllied(player *Player, otherPlayer *Player) bool {
+	if player == nil || otherPlayer == nil || player == otherPlayer {
+		return false
+	}
+
+	allianceMutex.RLock()
+	defer allianceMutex.RUnlock()
+
+	return player.Alliance != nil && player.Alliance == otherPlayer.Alliance
+}
+
+// GetAlliances returns a snapshot of all current alliances
+func GetAlliances() []Alliance {
+	allianceMutex.RLock()
+	defer allianceMutex.RUnlock()
+
+	snapshot := make([]Alliance, 0, len(alliances))
+	for _, alliance := range alliances {
+		snapshot = append(snapshot, copyAlliance(alliance))
+	}
+	return snapshot
+}
+
+// GetAllianceMembers returns the members of the player's alliance, including the player
+func GetAllianceMembers(player *Player) ([]*Player, bool) {
+	allianceMutex.RLock()
+	defer allianceMutex.RUnlock()
+
+	if player.Alliance == nil {
+		return nil, false
+	}
+
+	members := make([]*Player, len(player.Alliance.Members))
+	copy(members, player.Alliance.Members)
+	return members, true
+}
+
+// RequestAlliance stores an alliance request from one player to another
+func RequestAlliance(player *Player, target *Player) bool {
+	if player == target {
+		return false
+	}
+
+	allianceMutex.Lock()
+	defer allianceMutex.Unlock()
+
+	if player.Alliance != nil && player.Alliance == target.Alliance {
+		return false // Already allied
+	}
+
+	if !canJoinAlliance(player) || !canJoinAlliance(target) {
+		return false
+	}
+
+	// Spam prevention, a request can only be renewed once the previous one expired
+	if requestTime, ok := target.AllianceRequests[player.ID]; ok && time.Since(requestTime) < ALLIANCE_REQUEST_TIMEOUT*time.Second {
+		return false
+	}
+
+	target.AllianceRequests[player.ID] = time.Now()
+	return true
+}
+
+// AcceptAlliance accepts a pending alliance request, the accepting player joins the
+// requester's alliance or the other way around. Two existing alliances are never merged
+func AcceptAlliance(player *Player, requester *Player) bool {
+	alliance, ok := acceptAlliance(player, requester)
+	if ok {
+		TriggerAllianceUpdateEvent(alliance)
+	}
+	return ok
+}
+
+func acceptAlliance(player *Player, requester *Player) (Alliance, bool) {
+	allianceMutex.Lock()
+	defer allianceMutex.Unlock()
+
+	requestTime, ok := player.AllianceRequests[requester.ID]
+	if !ok {
+		return Alliance{}, false
+	}
+	delete(player.AllianceRequests, requester.ID)
+
+	if time.Since(requestTime) > ALLIANCE_REQUEST_TIMEOUT*time.Second {
+		return Alliance{}, false
+	}
+
+	if !canJoinAlliance(player) || !canJoinAlliance(requester) {
+		return Alliance{}, false
+	}
+
+	switch {
+	case player.Alliance != nil && requester.Alliance != nil:
+		return Alliance{}, false
+	case requester.Alliance != nil:
+		if !addAllianceMember(requester.Alliance, player) {
+			return Alliance{}, false
+		}
+	case player.Alliance != nil:
+		if !addAllianceMember(player.Alliance, requester) {
+			return Alliance{}, false
+		}
+	default:
+		if AllianceMaxSize < 2 {
+			return Alliance{}, false
+		}
+
+		allianceID, ok := availableAllianceIDs.getNextAvailableID()
+		if !ok {
+			return Alliance{}, false
+		}
+
+		alliance := &Alliance{ID: allianceID}
+		alliances[allianceID] = alliance
+		addAllianceMember(alliance, requester)
+		addAllianceMember(alliance, player)
+	}
+
+	return copyAlliance(player.Alliance), true
+}
+
+// LeaveAlliance removes the player from their alliance and starts the betrayal cooldown.
+// An alliance with a single member left is dissolved
+func LeaveAlliance(player *Player) bool {
+	allianceMutex.Lock()
+	alliance, ok := removeAllianceMember(player)
+	if ok {
+		player.AllianceCooldownEndTime = time.Now().Add(ALLIANCE_BETRAYAL_COOLDOWN * time.Minute)
+	}
+	allianceMutex.Unlock()
+
+	if ok {
+		TriggerAllianceUpdateEvent(alliance)
+	}
+	return ok
+}
+
+// removePlayerFromAlliances drops every alliance reference of a player leaving the game
+func removePlayerFromAlliances(player *Player) (Alliance, bool) {
+	allianceMutex.Lock()
+	defer allianceMutex.Unlock()
+
+	for _, otherPlayer := range State.Players {
+		delete(otherPlayer.AllianceRequests, player.ID)
+	}
+
+	return removeAllianceMember(player)
+}
+
+// canJoinAlliance checks the betrayal cooldown, the caller must hold the alliance lock
+func canJoinAlliance(player *Player) bool {
+	return time.Now().After(player.AllianceCooldownEndTime)
+}
+
+// addAllianceMember adds a player to an alliance if the size cap allows it, the caller must hold the alliance lock
+func addAllianceMember(alliance *Alliance, player *Player) bool {
+	if len(alliance.Members) >= AllianceMaxSize {
+		return false
+	}
+
+	alliance.Members = append(alliance.Members, player)
+	player.Alliance = alliance
+	return true
+}
+
+// removeAllianceMember removes a player from their alliance, the caller must hold the alliance lock
+func removeAllianceMember(player *Player) (Alliance, bool) {
+	alliance := player.Alliance
+	if alliance == nil {
+		return Alliance{}, false
+	}
+
+	for i, member := range alliance.Members {
+		if member == player {
+			alliance.Members = append(alliance.Members[:i], alliance.Members[i+1:]...)
+			break
+		}
+	}
+	player.Alliance = nil
+
+	// An alliance of one is no alliance
+	if len(alliance.Members) < 2 {
+		for _, member := range alliance.Members {
+			member.Alliance = nil
+		}
+		alliance.Members = nil
+		delete(alliances, alliance.ID)
+		availableAllianceIDs.returnID(alliance.ID)
+	}
+
+	return copyAlliance(alliance), true
+}
+
+func copyAlliance(alliance *Alliance) Alliance {
+	members := make([]*Player, len(alliance.Members))
+	copy(members, alliance.Members)
+	return Alliance{ID: alliance.ID, Members: members}
+}
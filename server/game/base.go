@@ -102,6 +102,7 @@ func (b *Base) AddBullet(spawning *BulletSpawning, targetPosition PositionFloat,
 	}
 
 	polygon := bulletStats.Polygon
+	startTick, startTime := nextEntityTick()
 
 	// Create the bullet object
 	bullet := &Bullet{
@@ -117,6 +118,9 @@ func (b *Base) AddBullet(spawning *BulletSpawning, targetPosition PositionFloat,
 		StayDuration:     bulletStats.StayDuration,
 		DamageMultiplier: bulletStats.DamageMultiplier,
 		Behavior:         bulletStats.Behavior,
+		SpawnPosition:    bulletPosition,
+		StartTick:        startTick,
+		StartTime:        startTime,
 	}
 
 	// Lock the base and add the bullet to the list
@@ -128,16 +132,13 @@ func (b *Base) AddBullet(spawning *BulletSpawning, targetPosition PositionFloat,
 }
 
 func (b *Base) RemoveBullet(bulletID ID) bool {
-	b.RLock()
-	// Retrieve the bullet
+	// Looking up and removing the bullet under one lock lets only one caller return its ID
+	b.Lock()
 	_, ok := b.Bullets[bulletID]
-	b.RUnlock()
 	if !ok {
+		b.Unlock()
 		return false // Bullet not found
 	}
-
-	b.Lock()
-	// Remove the bullet
 	delete(b.Bullets, bulletID)
 	b.Unlock()
 
@@ -206,7 +207,7 @@ func (b *Base) AddBuilding(buildingType BuildingType, position PositionFloat) (*
 		return nil, false
 	}
 
-	buildingID, ok := b.AvailableBuildingIDs.getNextAvailableID()
+	buildingID, generation, ok := b.AvailableBuildingIDs.getNextAvailableIDWithGeneration()
 	if !ok {
 		log.Println("No available building IDs")
 		return nil, false
@@ -228,13 +229,14 @@ func (b *Base) AddBuilding(buildingType BuildingType, position PositionFloat) (*
 
 	// Create the building
 	building := &Building{
-		Owner:    owner,
-		ID:       buildingID,
-		Type:     buildingType,
-		Variant:  BASIC_BUILDING, // Default variant value
-		Position: position,
-		Polygon:  polygon,
-		Health:   GetInitialHealth(buildingType, BASIC_BUILDING),
+		Owner:      owner,
+		ID:         buildingID,
+		Generation: generation,
+		Type:       buildingType,
+		Variant:    BASIC_BUILDING, // Default variant value
+		Position:   position,
+		Polygon:    polygon,
+		Health:     GetInitialHealth(buildingType, BASIC_BUILDING),
 	}
 
 	b.Lock()
@@ -259,6 +261,8 @@ func (b *Base) AddBuilding(buildingType BuildingType, position PositionFloat) (*
 		b.AddBulletSpawning(building)
 	}
 
+	player.recordBuildingPlaced(buildingType)
+
 	return building, true
 }
 
@@ -290,9 +294,23 @@ func (b *Base) UpgradeBuilding(buildingID ID, variant BuildingVariant) bool {
 	//? Upgraded building => New health
 	building.Health = GetInitialHealth(building.Type, variant)
 
+	player.recordBuildingUpgraded(building.Type)
+
 	return true // Building was successfully upgraded
 }
 
+// GetBuilding returns a building named by a client, a building that reused the ID of an older one is not returned
+func (b *Base) GetBuilding(buildingID ID, generation Generation) (*Building, bool) {
+	b.RLock()
+	defer b.RUnlock()
+
+	building, ok := b.Buildings[buildingID]
+	if !ok || building.Generation != generation {
+		return nil, false
+	}
+	return building, true
+}
+
 func (b *Base) RemoveBuilding(buildingID ID) bool {
 	var player *Player
 
@@ -303,17 +321,23 @@ func (b *Base) RemoveBuilding(buildingID ID) bool {
 		player = n.CapturedBy // CapturedBy is a field of type *Player
 	}
 
-	b.RLock()
-	// Retrieve the building
+	// Looking up and removing the building under one lock lets only one caller undo its effects and return its ID
+	b.Lock()
 	building, ok := b.Buildings[buildingID]
 	if !ok {
-		b.RUnlock()
+		b.Unlock()
 		return false // Building not found
 	}
-	b.RUnlock()
+	delete(b.Buildings, buildingID)
+	b.Unlock()
 
 	building.MarkForRemoval()
 
+	// Preset buildings of a neutral base never counted towards the generation and limits of the captor
+	if building.Preset {
+		player = nil
+	}
+
 	// Act based on building type
 	switch building.Type {
 	case BARRACKS:
@@ -343,12 +367,6 @@ func (b *Base) RemoveBuilding(buildingID ID) bool {
 		b.RemoveBulletSpawning(building)
 	}
 
-	// Lock to modify buildings and limits
-	b.Lock()
-	// Remove the building from the base's list of buildings
-	delete(b.Buildings, buildingID)
-	b.Unlock()
-
 	if player != nil {
 		player.Base.Lock()
 		// Decrement the building limit for the specific building type
@@ -8,12 +8,14 @@ import (
 type Building struct {
 	Owner      Owner // Specifies the owner of the building, which can be a player or a neutral base
 	ID         ID
+	Generation Generation // Clients name buildings by ID and generation
 	Type       BuildingType
 	Variant    BuildingVariant
 	Position   PositionFloat
 	Polygon    Polygon
 	Health     Health
 	RemoveFlag bool // Flag to mark unit for removal
+	Preset     bool // Placed with a neutral base, it is never credited to the captor
 	sync.RWMutex
 }
 
@@ -267,6 +269,16 @@ var buildingSizes = map[BuildingType]int{
 	HOUSE:         35,
 }
 
+// Sight ranges under fog of war, turrets see at least as far as they shoot
+var buildingSightRanges = map[BuildingType]int{
+	WALL:          200,
+	SIMPLE_TURRET: 450,
+	SNIPER_TURRET: 550,
+	BARRACKS:      400,
+	GENERATOR:     300,
+	HOUSE:         300,
+}
+
 type BuildingLimit struct {
 	Current int
 	Max     int
@@ -408,6 +420,10 @@ func GetBuildingSize(buildingType BuildingType) int {
 	return buildingSizes[buildingType]
 }
 
+func GetBuildingSightRange(buildingType BuildingType) int {
+	return buildingSightRanges[buildingType]
+}
+
 func GetBuildingCost(buildingType BuildingType, buildingVariant BuildingVariant) (uint16, bool) {
 	if upgrades, ok := buildingTypes[buildingType]; ok {
 		if upgrade, ok := upgrades[buildingVariant]; ok {
@@ -470,7 +486,8 @@ func ValidateUpgradePath(buildingType BuildingType, currentVariant, targetVarian
 }
 
 // ? For preventing wall spamming
-func CheckBuildingOverlapWithUnits(player *Player, buildingType BuildingType, position PositionFloat) bool {
+// Units are checked where they were at the given tick, the state the placing client saw
+func CheckBuildingOverlapWithUnits(player *Player, buildingType BuildingType, position PositionFloat, tick uint32) bool {
 	State.RLock()
 	otherPlayers := make([]*Player, 0, len(State.Players))
 	for _, p := range State.Players {
@@ -498,7 +515,7 @@ func CheckBuildingOverlapWithUnits(player *Player, buildingType BuildingType, po
 				continue
 			}
 			// Get the position and size of the unit
-			unitPosition := unit.Position
+			unitPosition := unit.PositionAt(tick)
 			unitSize := unit.Size
 
 			// Calculate the distance between the building center and the unit center
@@ -21,6 +21,14 @@ type Bullet struct {
 	DamageMultiplier float32
 	Behavior         BulletBehavior
 
+	// Trajectory sent with the spawn message, clients simulate the bullet from it
+	SpawnPosition PositionFloat
+	StartTick     uint32 // First entity update the bullet moves in
+	StartTime     uint32 // Server time that update is expected to start at
+	diverged      bool   // Set when the bullet left the trajectory clients predict
+
+	viewers map[*Player]bool // Players the bullet was sent to, they also get its corrections and removal
+
 	// Trapper Bullet
 	ReachedTargetPosition bool
 	StayDuration          time.Duration
@@ -80,8 +88,45 @@ func (b *Bullet) SetTargetPosition(pos PositionFloat) {
 		X: float32(pos.X),
 		Y: float32(pos.Y),
 	}
+	// Clients still fly towards the old target
+	b.diverged = true
+}
+
+// GetTrajectory returns the current and the target position
+func (b *Bullet) GetTrajectory() (PositionFloat, PositionFloat) {
+	b.RLock()
+	defer b.RUnlock()
+	return b.Position, b.TargetPosition
+}
+
+// AddViewer records that a player was sent the bullet
+func (b *Bullet) AddViewer(player *Player) {
+	b.Lock()
+	defer b.Unlock()
+	if b.viewers == nil {
+		b.viewers = make(map[*Player]bool)
+	}
+	b.viewers[player] = true
+}
+
+// IsKnownTo reports if the player was sent the bullet, even if it flew out of the player's sight since
+func (b *Bullet) IsKnownTo(player *Player) bool {
+	b.RLock()
+	defer b.RUnlock()
+	return b.viewers[player]
+}
+
+// takeDiverged reports if the bullet diverged from the predicted trajectory since the last call
+func (b *Bullet) takeDiverged() bool {
+	b.Lock()
+	defer b.Unlock()
+	diverged := b.diverged
+	b.diverged = false
+	return diverged
 }
 
+// UpdatePosition moves the bullet one step towards its target. Clients replay this exact step once
+// per entity update from StartTick on, any change here has to be mirrored in the client
 func (b *Bullet) UpdatePosition(deltaTime time.Duration) bool {
 	b.Lock()
 	defer b.Unlock()
@@ -0,0 +1,97 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+const bulletStep = ENTITY_UPDATE_INTERVAL * time.Millisecond
+
+func TestBulletUpdatePosition(t *testing.T) {
+	bullet := &Bullet{
+		Position:       PositionFloat{X: 0, Y: 0},
+		TargetPosition: PositionFloat{X: 1000, Y: 0},
+		Speed:          600,
+	}
+
+	if !bullet.UpdatePosition(bulletStep) || bullet.Position.X != 30 {
+		t.Fatalf("first step moved to %v, expected the full speed step to X 30", bullet.Position)
+	}
+
+	steps := 1
+	last := bullet.Position.X
+	for bullet.UpdatePosition(bulletStep) {
+		steps++
+		if bullet.Position.X <= last || bullet.Position.Y != 0 {
+			t.Fatalf("step %d moved from X %v to %v", steps, last, bullet.Position)
+		}
+		if moved := bullet.Position.X - last; bullet.TargetPosition.X-last < 100 && moved >= 30 {
+			t.Errorf("step %d moved %v within the ease-out distance, expected less than the full step", steps, moved)
+		}
+		last = bullet.Position.X
+		if steps > 100 {
+			t.Fatal("bullet never reached its target")
+		}
+	}
+
+	if bullet.Position != bullet.TargetPosition || !bullet.ReachedTargetPosition {
+		t.Errorf("bullet stopped at %v, expected it snapped onto its target", bullet.Position)
+	}
+}
+
+func TestUpdateBulletsOnlySendsDivergence(t *testing.T) {
+	sub := Bus.Subscribe(SubscriptionOptions{Topics: []EventType{BulletPositionUpdate}, BufferSize: 16})
+	defer Bus.Unsubscribe(sub)
+
+	bullet := &Bullet{
+		Position:       PositionFloat{X: 0, Y: 0},
+		TargetPosition: PositionFloat{X: 0, Y: 1000},
+		Speed:          600,
+		StartTick:      5,
+	}
+	base := &Base{Bullets: map[ID]*Bullet{1: bullet}}
+
+	updateBullets(base, 4, bulletStep)
+	if bullet.Position.Y != 0 {
+		t.Fatalf("bullet moved before its start tick")
+	}
+
+	// Clients follow the trajectory from the spawn message on their own
+	updateBullets(base, 5, bulletStep)
+	if bullet.Position.Y != 30 {
+		t.Fatalf("bullet at %v after its first update, expected Y 30", bullet.Position)
+	}
+
+	bullet.SetTargetPosition(PositionFloat{X: 500, Y: 500})
+	updateBullets(base, 6, bulletStep)
+	updateBullets(base, 7, bulletStep)
+
+	select {
+	case event := <-sub.Events():
+		update := event.(*BulletPositionUpdateEvent)
+		if update.Bullet != bullet || update.Tick != 6 || update.TargetPosition.X != 500 {
+			t.Errorf("position update %+v, expected the new target at tick 6", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a bullet that changed its target sent no position update")
+	}
+
+	select {
+	case event := <-sub.Events():
+		t.Errorf("unexpected position update at tick %d", event.(*BulletPositionUpdateEvent).Tick)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBulletViewers(t *testing.T) {
+	bullet := &Bullet{}
+	viewer, other := &Player{}, &Player{}
+
+	if bullet.IsKnownTo(viewer) {
+		t.Fatal("new bullet is known to a player")
+	}
+	bullet.AddViewer(viewer)
+	if !bullet.IsKnownTo(viewer) || bullet.IsKnownTo(other) {
+		t.Errorf("bullet known to viewer %v and other %v, expected only the viewer", bullet.IsKnownTo(viewer), bullet.IsKnownTo(other))
+	}
+}
@@ -48,9 +48,27 @@ func (c *Capacity) Get() uint16 {
 	return c.Current
 }
 
-// hasMaxCapacity checks if the Current value is equal to Max value.
+// IncrementMax raises the Max value by the given amount.
+func (c *Capacity) IncrementMax(amount uint16) {
+	c.Lock()
+	defer c.Unlock()
+	c.Max += amount
+}
+
+// DecrementMax lowers the Max value by the given amount, Current is kept so it can be counted down again.
+func (c *Capacity) DecrementMax(amount uint16) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Max <= amount {
+		c.Max = 0
+	} else {
+		c.Max -= amount
+	}
+}
+
+// hasMaxCapacity checks if the Current value has reached the Max value.
 func (c *Capacity) HasMaxCapacity() bool {
-	c.RLock() // Read lock for checking if Current is equal to Max
+	c.RLock() // Read lock for checking if Current has reached Max
 	defer c.RUnlock()
-	return c.Current == c.Max
+	return c.Current >= c.Max
 }
@@ -5,6 +5,12 @@ type BuildingVariant byte
 type UnitType byte
 type UnitVariant byte
 type Permission byte
+type Formation byte
+type NeutralBaseType byte
+type HighScorePeriod byte
+type AchievementID byte
+type MapLayout byte
+type WorldEventType byte
 
 const (
 	WALL          BuildingType = 0
@@ -13,6 +19,8 @@ const (
 	BARRACKS      BuildingType = 4
 	GENERATOR     BuildingType = 5
 	HOUSE         BuildingType = 6
+
+	BUILDING_TYPE_COUNT = 7 // Highest building type + 1, used for per type statistics
 )
 
 const (
@@ -66,6 +74,8 @@ const (
 	TANK       UnitType = 1
 	SIEGE_TANK UnitType = 2
 	COMMANDER  UnitType = 3
+
+	UNIT_TYPE_COUNT = 4 // Highest unit type + 1, used for per type statistics
 )
 
 const (
@@ -86,6 +96,55 @@ const (
 	BOOSTER_ENGINE_CANNON_SIEGE_TANK      UnitVariant = 5
 )
 
+const (
+	POWER_OUTPOST    NeutralBaseType = 0 // Generates additional power
+	BARRACKS_OUTPOST NeutralBaseType = 1 // Allows more active barracks
+	FORTRESS         NeutralBaseType = 2 // Defended by turrets
+	CITADEL          NeutralBaseType = 3 // The strongest base in the centre of the map
+)
+
+const (
+	FORMATION_RING  Formation = 0 // Units spread in rings around the target (default)
+	FORMATION_LINE  Formation = 1 // Units line up side by side facing the target
+	FORMATION_WEDGE Formation = 2 // Units form a V with the tip on the target
+	FORMATION_BOX   Formation = 3 // Units fill a square grid centered on the target
+)
+
+const (
+	HIGH_SCORE_ALL_TIME HighScorePeriod = 0
+	HIGH_SCORE_DAILY    HighScorePeriod = 1 // Resets at midnight UTC
+	HIGH_SCORE_WEEKLY   HighScorePeriod = 2 // Resets on Monday midnight UTC
+)
+
+const (
+	ACHIEVEMENT_FIRST_BLOOD        AchievementID = 0 // Kill a player
+	ACHIEVEMENT_RAMPAGE            AchievementID = 1 // Kill 3 players in one life
+	ACHIEVEMENT_COMMANDING_VICTORY AchievementID = 2 // Kill a player with a commander
+	ACHIEVEMENT_CONQUEROR          AchievementID = 3 // Capture 3 neutral bases in one life
+	ACHIEVEMENT_CITADEL_LORD       AchievementID = 4 // Capture the citadel
+	ACHIEVEMENT_ARCHITECT          AchievementID = 5 // Place 50 buildings in one life
+	ACHIEVEMENT_ARMORED_DIVISION   AchievementID = 6 // Spawn 20 tanks in one life
+)
+
+const (
+	MAP_LAYOUT_HEX_RING MapLayout = 0 // Hexagons of spawn positions with a neutral base in each centre
+	MAP_LAYOUT_ISLANDS  MapLayout = 1 // Small groups of spawns around a neutral base, far apart from each other
+	MAP_LAYOUT_LANES    MapLayout = 2 // Parallel lanes separated by rock walls with a few crossings
+	MAP_LAYOUT_POISSON  MapLayout = 3 // Evenly spread random positions
+
+	MAP_LAYOUT_COUNT = 4
+
+	MAP_LAYOUT_FILE MapLayout = 255 // Loaded from a map file instead of generated
+)
+
+const (
+	WORLD_EVENT_POWER_CRATE  WorldEventType = 0 // Power for the first unit reaching it
+	WORLD_EVENT_HAZARD_ZONE  WorldEventType = 1 // Damages every unit inside
+	WORLD_EVENT_DOUBLE_SCORE WorldEventType = 2 // Every score gain counts twice
+
+	WORLD_EVENT_TYPE_COUNT = 3
+)
+
 const (
 	PERMISSION_NONE      Permission = 0 // User with no special permissions
 	PERMISSION_MODERATOR Permission = 1 // Moderator has limited access
@@ -122,11 +181,99 @@ const (
 	NEUTRAL_BASE_MIN_BUILDING_RADIUS           = 82
 	NEUTRAL_BASE_MAX_CORE_RADIUS               = NEUTRAL_BASE_MIN_BUILDING_RADIUS - 2
 	NEUTRAL_BASE_CAPTURE_SCORE                 = 10 // Per Second
+	NEUTRAL_BASE_CAPTURE_RADIUS                = NEUTRAL_BASE_MAX_BUILDING_RADIUS
+	NEUTRAL_BASE_CAPTURE_TIME                  = 10   // Seconds of uncontested presence needed after the core is destroyed
+	NEUTRAL_BASE_CAPTURE_DECAY_RATE            = 0.5  // Meter loss per second, relative to the fill rate
+	NEUTRAL_BASE_CORE_RECOVERY_DELAY           = 30   // Seconds a destroyed core stays down before it can recover
+	NEUTRAL_BASE_CAPTURE_BROADCAST_RADIUS      = 1500 // Players within this distance receive capture progress
 
 	// Unit and detection settings
 	BARRACKS_UNIT_SPAWN_RADIUS = 100
 	UNIT_DETECTION_RADIUS      = 1000
 
+	// Unit steering settings
+	UNIT_SEPARATION_RADIUS_FACTOR = 1.1  // Neighbour radius as a multiple of both unit sizes combined
+	UNIT_SEPARATION_WEIGHT        = 0.8  // Strength of the separation push relative to the unit speed
+	UNIT_ARRIVAL_RADIUS_FACTOR    = 2.5  // Distance (in unit sizes) at which a blocked unit counts as arrived
+	UNIT_MIN_SEPARATION_MOVEMENT  = 0.05 // Pushes below this distance are ignored to avoid jitter
+
+	// Control group and formation settings
+	CONTROL_GROUP_COUNT      = 10
+	FORMATION_SPACING        = 50.0 // Space between units
+	FORMATION_RING_OFFSET    = 50.0 // Magnitude of the random offset applied to ring slots
+	FORMATION_LINE_MAX_WIDTH = 12   // Units per rank before a line starts a new rank
+
+	// Alliance settings
+	ALLIANCE_DEFAULT_MAX_SIZE  = 3
+	ALLIANCE_REQUEST_TIMEOUT   = 30 // Seconds
+	ALLIANCE_BETRAYAL_COOLDOWN = 5  // Minutes before a player who left an alliance can ally again
+
+	// High score settings
+	HIGH_SCORE_BOARD_SIZE = 10
+
+	// ID pool settings, IDs are handed out lazily so large pools cost nothing until used
+	MAX_ENTITY_IDS       = 1 << 16 // IDs are sent as 2 bytes
+	MAX_PLAYERS          = 64      // Server capacity
+	MAX_ALLIANCES        = 32
+	MAX_UNITS_PER_PLAYER = MAX_ENTITY_IDS
+	MAX_BUILDINGS        = MAX_ENTITY_IDS // Per base
+	MAX_BULLETS          = MAX_ENTITY_IDS // Per base
+	ID_REUSE_COOLDOWN    = 5              // Seconds before a returned ID is handed out again
+
+	// Map generation settings
+	MAP_DEFAULT_EXPECTED_PLAYERS = 24    // Fills the classic seven hexagon map
+	MAP_SPAWN_SPACING            = 2500  // Distance between neighbouring spawn positions
+	MAP_EDGE_MARGIN              = 1400  // Bushes and rocks are scattered this far beyond the outermost base
+	MAP_MAX_RADIUS               = 30000 // Positions are 2 byte integers
+	MAP_SCATTER_REFERENCE_RADIUS = 8000  // Map radius the bush and rock counts are given for, larger maps get more
+	MAP_BUSH_COUNT               = 30
+	MAP_BUSH_MIN_DISTANCE        = 800
+	MAP_ROCK_COUNT               = 20
+	MAP_ROCK_MIN_DISTANCE        = 1000
+	MAP_ROCK_RIDGE_SPACING       = 350 // Between the rocks of a ridge, units can slip through the gaps
+
+	// Map file validation
+	MAP_MIN_SPAWN_DISTANCE         = 2 * PLAYER_SPAWN_PROTECTION_RADIUS // Spawn protection areas must not overlap
+	MAP_MIN_SPAWN_NEUTRAL_DISTANCE = PLAYER_SPAWN_PROTECTION_RADIUS + NEUTRAL_BASE_MAX_BUILDING_RADIUS
+	MAP_MIN_NEUTRAL_DISTANCE       = 2 * NEUTRAL_BASE_MAX_BUILDING_RADIUS
+	MAP_MAX_ROCK_SIZE              = 255 // Sizes are sent as one byte
+
+	// Bush settings
+	BUSH_RADIUS        = 80  // Units whose centre is this close to a bush are hidden from enemies
+	BUSH_REVEAL_RADIUS = 300 // Enemy units this close see into the bush
+
+	// Fog of war settings, buildings and units have their own sight ranges
+	PLAYER_BASE_VISION_RADIUS  = 800                                                    // Covers the simple turrets at the border of the base and their range
+	NEUTRAL_BASE_VISION_RADIUS = 600                                                    // Around captured neutral bases
+	VISION_GRID_CELL_SIZE      = PLAYER_BASE_VISION_RADIUS + PLAYER_MAX_BUILDING_RADIUS // Longest sight range plus the margin of a base
+
+	// World event settings
+	WORLD_EVENT_DEFAULT_INTERVAL      = 90  // Seconds between the start of two events
+	WORLD_EVENT_UPDATE_INTERVAL       = 250 // Milliseconds between crate pickups and hazard damage
+	WORLD_EVENT_ANNOUNCE_DELAY        = 10  // Seconds hazards and double score are announced before they start
+	WORLD_EVENT_PLACEMENT_ATTEMPTS    = 20
+	MAX_WORLD_EVENTS                  = 64
+	WORLD_EVENT_CRATE_RADIUS          = 40
+	WORLD_EVENT_CRATE_POWER           = 500
+	WORLD_EVENT_CRATE_DURATION        = 60 // Seconds before an uncollected crate disappears
+	WORLD_EVENT_HAZARD_RADIUS         = 350
+	WORLD_EVENT_HAZARD_DAMAGE         = 20 // Per second
+	WORLD_EVENT_HAZARD_DURATION       = 30
+	WORLD_EVENT_DOUBLE_SCORE_DURATION = 45
+
+	// Play zone settings, the safe circle shrinks in phases from the map radius to the centre
+	PLAY_ZONE_DEFAULT_MIN_PLAYERS = 2
+	PLAY_ZONE_DEFAULT_START_DELAY = 120 // Seconds between the announcement and the first shrink
+	PLAY_ZONE_PHASE_COUNT         = 5   // The last phase closes the zone completely
+	PLAY_ZONE_PHASE_WAIT          = 60  // Seconds between two shrinks
+	PLAY_ZONE_SHRINK_DURATION     = 30  // Seconds a shrink takes
+	PLAY_ZONE_DAMAGE              = 25  // Per second to bases outside the zone, multiplied by the phase
+
+	// Simulation settings
+	ENTITY_UPDATE_INTERVAL  = 50  // Milliseconds, bullets move one fixed step per update
+	LAG_COMPENSATION_WINDOW = 300 // Milliseconds a client command can be validated in the past
+	POSITION_HISTORY_SIZE   = LAG_COMPENSATION_WINDOW/ENTITY_UPDATE_INTERVAL + 1
+
 	KICK_REASON_TIMEOUT   = 0
 	KICK_REASON_SCRIPTING = 1
 
@@ -0,0 +1,264 @@
+package game
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+type BufferPolicy byte
+
+const (
+	BUFFER_BLOCK       BufferPolicy = 0 // Publishing waits until the subscriber has room, nothing is lost
+	BUFFER_DROP_OLDEST BufferPolicy = 1 // A full buffer drops its oldest event to make room
+	BUFFER_COALESCE    BufferPolicy = 2 // Pending events of the same entity are replaced in place, other events queue up like BUFFER_QUEUE
+	BUFFER_QUEUE       BufferPolicy = 3 // Pending events queue up without a limit, publishing never waits and nothing is lost
+)
+
+// SubscriptionOptions configures what a subscriber receives and how it is buffered
+type SubscriptionOptions struct {
+	Topics     []EventType // Empty means all events
+	Excluded   []EventType // Never delivered, even without topics
+	BufferSize int         // Pending events of a coalescing or queueing subscriber beyond this are logged
+	Policy     BufferPolicy
+}
+
+// Subscription receives the events of an EventBus
+type Subscription struct {
+	topics   map[EventType]bool
+	excluded map[EventType]bool
+	policy   BufferPolicy
+	size     int
+
+	events    chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// Pending events of a coalescing or queueing subscriber
+	pending     *CoalescingQueue
+	notify      chan struct{}
+	highWater   int // Next pending length that is logged, doubles while the subscriber falls further behind
+	pendingLock sync.Mutex
+
+	dropped atomic.Uint64
+}
+
+type coalesceKey struct {
+	eventType EventType
+	key       interface{}
+}
+
+// EventBus fans events out to subscribers, every subscriber decides how it is buffered
+type EventBus struct {
+	subscribers map[*Subscription]struct{}
+	sync.RWMutex
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber
+func (b *EventBus) Subscribe(options SubscriptionOptions) *Subscription {
+	if options.BufferSize < 1 {
+		options.BufferSize = 1
+	}
+
+	sub := &Subscription{
+		policy:    options.Policy,
+		size:      options.BufferSize,
+		highWater: options.BufferSize,
+		events:    make(chan Event, options.BufferSize),
+		done:      make(chan struct{}),
+	}
+
+	if len(options.Topics) > 0 {
+		sub.topics = make(map[EventType]bool, len(options.Topics))
+		for _, topic := range options.Topics {
+			sub.topics[topic] = true
+		}
+	}
+	if len(options.Excluded) > 0 {
+		sub.excluded = make(map[EventType]bool, len(options.Excluded))
+		for _, topic := range options.Excluded {
+			sub.excluded[topic] = true
+		}
+	}
+
+	if sub.policy == BUFFER_COALESCE || sub.policy == BUFFER_QUEUE {
+		// Pending events wait in the queue, the channel only hands them over
+		sub.events = make(chan Event)
+		sub.pending = NewCoalescingQueue()
+		sub.notify = make(chan struct{}, 1)
+		go sub.forwardPending()
+	}
+
+	b.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its event channel
+func (b *EventBus) Unsubscribe(sub *Subscription) {
+	// Release a publish blocked on this subscriber before waiting for the lock
+	sub.closeOnce.Do(func() { close(sub.done) })
+
+	b.Lock()
+	_, ok := b.subscribers[sub]
+	delete(b.subscribers, sub)
+	b.Unlock()
+
+	// Publish never sends after the subscriber was removed, so closing here is safe
+	if ok && sub.policy != BUFFER_COALESCE && sub.policy != BUFFER_QUEUE {
+		close(sub.events)
+	}
+}
+
+// Publish delivers an event to every subscriber of its topic
+func (b *EventBus) Publish(event Event) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for sub := range b.subscribers {
+		if (sub.topics != nil && !sub.topics[event.Type()]) || sub.excluded[event.Type()] {
+			continue
+		}
+		sub.deliver(event)
+	}
+}
+
+// Events returns the channel the subscriber reads from, it is closed on unsubscribe
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns how many events were lost or replaced because the subscriber fell behind
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) deliver(event Event) {
+	switch s.policy {
+	case BUFFER_DROP_OLDEST:
+		for {
+			select {
+			case s.events <- event:
+				return
+			default:
+			}
+
+			// Make room by dropping the oldest event
+			select {
+			case <-s.events:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	case BUFFER_COALESCE, BUFFER_QUEUE:
+		s.enqueuePending(event)
+	default:
+		select {
+		case s.events <- event:
+		case <-s.done:
+		}
+	}
+}
+
+func (s *Subscription) enqueuePending(event Event) {
+	s.pendingLock.Lock()
+	if s.policy == BUFFER_QUEUE {
+		s.pending.Append(event)
+	} else if s.pending.Push(event) {
+		s.dropped.Add(1)
+	}
+
+	// Nothing is dropped to catch up, a subscriber that keeps falling behind has to show up in the log
+	if pending := s.pending.Len(); pending > s.highWater {
+		log.Printf("Event subscriber is falling behind, %d events pending", pending)
+		s.highWater *= 2
+	}
+	s.pendingLock.Unlock()
+	s.notifyPending()
+}
+
+func (s *Subscription) notifyPending() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// forwardPending hands the pending events of a coalescing or queueing subscriber over in order
+func (s *Subscription) forwardPending() {
+	defer close(s.events)
+
+	for {
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+
+		s.pendingLock.Lock()
+		batch := s.pending.Drain()
+		s.highWater = s.size
+		s.pendingLock.Unlock()
+
+		for _, event := range batch {
+			select {
+			case s.events <- event:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// CoalescingQueue keeps events in arrival order, a coalescing event replaces the queued event
+// of the same entity in place. It is not safe for concurrent use
+type CoalescingQueue struct {
+	events []Event
+	keys   map[coalesceKey]int // Index of the queued event per key
+}
+
+func NewCoalescingQueue() *CoalescingQueue {
+	return &CoalescingQueue{
+		keys: make(map[coalesceKey]int),
+	}
+}
+
+// Push queues an event and reports whether it replaced a queued event
+func (q *CoalescingQueue) Push(event Event) bool {
+	if coalescing, ok := event.(CoalescingEvent); ok {
+		key := coalesceKey{eventType: event.Type(), key: coalescing.CoalesceKey()}
+		if index, exists := q.keys[key]; exists {
+			q.events[index] = event
+			return true
+		}
+		q.keys[key] = len(q.events)
+	}
+
+	q.events = append(q.events, event)
+	return false
+}
+
+// Append queues an event without replacing a queued event of the same entity
+func (q *CoalescingQueue) Append(event Event) {
+	q.events = append(q.events, event)
+}
+
+func (q *CoalescingQueue) Len() int {
+	return len(q.events)
+}
+
+// Drain returns the queued events in order and empties the queue
+func (q *CoalescingQueue) Drain() []Event {
+	events := q.events
+	q.events = nil
+	clear(q.keys)
+	return events
+}
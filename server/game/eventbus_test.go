@@ -0,0 +1,188 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+// waitForwarded waits until the forwarder of a coalescing subscriber took every pending event
+func waitForwarded(t *testing.T, sub *Subscription) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		sub.pendingLock.Lock()
+		pending := sub.pending.Len()
+		sub.pendingLock.Unlock()
+		if pending == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pending events were not forwarded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func kick(reason byte) *KickEvent {
+	return &KickEvent{Reason: reason}
+}
+
+func TestEventBusTopics(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(SubscriptionOptions{Topics: []EventType{Kick}, BufferSize: 4})
+
+	bus.Publish(&ResourceUpdateEvent{})
+	bus.Publish(kick(1))
+	if event, ok := receive(t, sub).(*KickEvent); !ok || event.Reason != 1 {
+		t.Errorf("received %T instead of the kick", event)
+	}
+
+	bus.Unsubscribe(sub)
+	if _, open := <-sub.Events(); open {
+		t.Error("event channel still open after unsubscribing")
+	}
+	// Publishing without subscribers must not block or panic
+	bus.Publish(kick(2))
+}
+
+func TestEventBusUnsubscribeReleasesPublisher(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(SubscriptionOptions{BufferSize: 1, Policy: BUFFER_BLOCK})
+	bus.Publish(kick(1))
+
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(kick(2))
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("publishing to a full blocking subscriber did not wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	bus.Unsubscribe(sub)
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publisher still blocked after the subscriber left")
+	}
+}
+
+func TestEventBusDropOldest(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(SubscriptionOptions{BufferSize: 2, Policy: BUFFER_DROP_OLDEST})
+
+	for reason := byte(1); reason <= 3; reason++ {
+		bus.Publish(kick(reason))
+	}
+
+	for _, want := range []byte{2, 3} {
+		if event := receive(t, sub).(*KickEvent); event.Reason != want {
+			t.Errorf("received kick %d, expected %d", event.Reason, want)
+		}
+	}
+	if sub.Dropped() != 1 {
+		t.Errorf("%d events dropped, expected 1", sub.Dropped())
+	}
+}
+
+func TestEventBusCoalesce(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(SubscriptionOptions{BufferSize: 10, Policy: BUFFER_COALESCE})
+	defer bus.Unsubscribe(sub)
+
+	// The first event is held by the forwarder until it is read, the rest stays pending
+	bus.Publish(kick(0))
+	waitForwarded(t, sub)
+
+	first, second := &NeutralBase{}, &NeutralBase{}
+	bus.Publish(&NeutralBaseCaptureProgressEvent{NeutralBase: first, Progress: 10})
+	bus.Publish(kick(1))
+	bus.Publish(&NeutralBaseCaptureProgressEvent{NeutralBase: second, Progress: 50})
+	bus.Publish(&NeutralBaseCaptureProgressEvent{NeutralBase: first, Progress: 20})
+
+	receive(t, sub)
+	if event, ok := receive(t, sub).(*NeutralBaseCaptureProgressEvent); !ok || event.NeutralBase != first || event.Progress != 20 {
+		t.Errorf("the first base's progress was not replaced in place by its latest update: %+v", event)
+	}
+	if _, ok := receive(t, sub).(*KickEvent); !ok {
+		t.Error("an event without a coalesce key was not delivered in order")
+	}
+	if event := receive(t, sub).(*NeutralBaseCaptureProgressEvent); event.NeutralBase != second {
+		t.Error("the second base's progress was coalesced with the first")
+	}
+	if sub.Dropped() != 1 {
+		t.Errorf("%d events dropped, expected the 1 replaced update", sub.Dropped())
+	}
+}
+
+func TestEventBusCoalesceBeyondBufferSize(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(SubscriptionOptions{BufferSize: 2, Policy: BUFFER_COALESCE})
+	defer bus.Unsubscribe(sub)
+
+	bus.Publish(kick(0))
+	waitForwarded(t, sub)
+	for reason := byte(1); reason <= 5; reason++ {
+		bus.Publish(kick(reason))
+	}
+
+	// Events without a coalesce key are never dropped, the buffer size only marks a backlog
+	for want := byte(0); want <= 5; want++ {
+		if event := receive(t, sub).(*KickEvent); event.Reason != want {
+			t.Errorf("received kick %d, expected %d", event.Reason, want)
+		}
+	}
+	if sub.Dropped() != 0 {
+		t.Errorf("%d events dropped, expected none", sub.Dropped())
+	}
+}
+
+func TestCoalescingQueue(t *testing.T) {
+	q := NewCoalescingQueue()
+	base := &NeutralBase{}
+
+	if q.Push(&NeutralBaseCaptureProgressEvent{NeutralBase: base, Progress: 1}) {
+		t.Error("the first update of a base replaced something")
+	}
+	q.Push(kick(1))
+	if !q.Push(&NeutralBaseCaptureProgressEvent{NeutralBase: base, Progress: 2}) {
+		t.Error("the second update of a base did not replace the first")
+	}
+	if q.Push(kick(1)) {
+		t.Error("an event without a coalesce key replaced an equal one")
+	}
+	if q.Len() != 3 {
+		t.Fatalf("%d events queued, expected 3", q.Len())
+	}
+
+	events := q.Drain()
+	if event, ok := events[0].(*NeutralBaseCaptureProgressEvent); !ok || event.Progress != 2 {
+		t.Errorf("first drained event %+v, expected the latest progress in the place of the first", events[0])
+	}
+
+	// Appended events never replace anything
+	q.Append(kick(2))
+	q.Append(kick(2))
+	if q.Len() != 2 {
+		t.Errorf("%d appended events queued, expected 2", q.Len())
+	}
+
+	q.Drain()
+	if q.Len() != 0 || q.Push(&NeutralBaseCaptureProgressEvent{NeutralBase: base}) {
+		t.Error("the queue kept events or keys after it was drained")
+	}
+}
@@ -23,9 +23,29 @@ const (
 	LeaderboardUpdate
 	RemoveSpawnProtection
 	Kick
+	AllianceUpdate
+	NeutralBaseCaptureProgress
+	AchievementUnlocked
+	UnitVisibility
+	BaseVisibility
+	WorldEventStart
+	WorldEventEnd
+	PlayZoneUpdate
+	RoundWon
+	AchievementFlush
 	// Add more event types as needed
 )
 
+type ResourceUpdateEvent struct {
+	Player *Player
+	Tick   uint32 // Entity update the event was triggered in
+}
+
+type BaseHealthUpdateEvent struct {
+	Base *Base
+	Tick uint32 // Entity update the event was triggered in
+}
+
 type LeaderboardUpdateEvent struct {
 	Changes *[]LeaderboardEntry
 }
@@ -44,34 +64,80 @@ type BulletSpawnEvent struct {
 
 type BulletRemoveEvent struct {
 	Owner    Owner
+	Bullet   *Bullet
 	BulletID ID
+	Position PositionFloat // Final position, the bullet ID may be reused before the event is handled
 }
 
 type BulletPositionUpdateEvent struct {
-	Owner  Owner
-	Bullet *Bullet
+	Owner          Owner
+	Bullet         *Bullet
+	Position       PositionFloat
+	TargetPosition PositionFloat
+	Tick           uint32 // Entity update the position was reached in
 }
 
 type UnitSpawnEvent struct {
 	Player   *Player
 	Unit     *Unit
 	Barracks *Building
+	Tick     uint32 // Entity update the event was triggered in
 }
 
 type UnitPositionUpdatesEvent struct {
 	Player *Player
 	Units  []*Unit
+	Tick   uint32 // Entity update the positions were reached in
 }
 
 type UnitsTargetPointUpdateEvent struct {
 	Player      *Player
 	Units       []*Unit
 	TargetPoint PositionInt
+	Tick        uint32 // Entity update the event was triggered in
+}
+
+// UnitVisibilityEvent lists the enemy units that went into or came out of hiding for one player
+type UnitVisibilityEvent struct {
+	Player   *Player
+	Hidden   []*Unit
+	Revealed []*Unit
+	Tick     uint32 // Entity update the event was triggered in
+}
+
+// BaseVisibilityEvent lists the enemy bases that went into or came out of the fog of war for one player
+type BaseVisibilityEvent struct {
+	Player   *Player
+	Hidden   []*Player // Owners of the bases
+	Revealed []*Player
+	Tick     uint32 // Entity update the event was triggered in
+}
+
+// WorldEventStartEvent announces a world event, it takes effect at its start time
+type WorldEventStartEvent struct {
+	Event *WorldEvent
+}
+
+// WorldEventEndEvent is triggered when a world event expired or a crate was collected
+type WorldEventEndEvent struct {
+	Event       *WorldEvent
+	CollectedBy *Player // Nil unless a crate was collected
+}
+
+// PlayZoneUpdateEvent announces a phase of the play zone or its reset after a round
+type PlayZoneUpdateEvent struct {
+	Zone PlayZone
+}
+
+// RoundWonEvent ends a play zone round
+type RoundWonEvent struct {
+	Winner *Player // Nil if the last players were eliminated together
 }
 
 type UnitRemoveEvent struct {
 	Player *Player
 	UnitID ID
+	Unit   *Unit // Removed units are only announced to players who saw them
 }
 
 type BuildingRemovedEvent struct {
@@ -86,7 +152,8 @@ type BuildingPlacedEvent struct {
 
 type PlayerKilledEvent struct {
 	Player *Player
-	Killer *Player
+	Killer *Player // Nil if the play zone destroyed the base
+	Unit   *Unit   // Unit that dealt the final blow
 }
 
 type RemoveSpawnProtectionEvent struct {
@@ -95,6 +162,15 @@ type RemoveSpawnProtectionEvent struct {
 
 type NeutralBaseCapturedEvent struct {
 	NeutralBase *NeutralBase
+	Captor      *Player // The base may change hands again before subscribers handle the event
+}
+
+type NeutralBaseCaptureProgressEvent struct {
+	NeutralBase     *NeutralBase
+	Progress        byte // Percent
+	CapturingPlayer *Player
+	Contested       bool
+	Tick            uint32 // Entity update the event was triggered in
 }
 
 type KickEvent struct {
@@ -102,52 +178,99 @@ type KickEvent struct {
 	Reason byte
 }
 
-type TurretRotationUpdateEvent struct {
-	Owner          Owner
-	Turret         *Building
-	TargetPosition PositionFloat
+type AllianceUpdateEvent struct {
+	Alliance Alliance
 }
 
-// Event represents an event
-type Event struct {
-	Type    EventType
-	Payload interface{}
+type AchievementUnlockedEvent struct {
+	Player        *Player
+	AchievementID AchievementID
 }
 
-var (
-	eventListeners []chan Event
-	eventChan      = make(chan Event)
-)
+// AchievementFlushEvent is closed by the achievement listener once every earlier event was evaluated
+type AchievementFlushEvent struct {
+	Done chan struct{}
+}
 
-// StartEventDispatcher starts the event dispatcher
+type TurretRotationUpdateEvent struct {
+	Owner          Owner
+	Turret         *Building
+	TargetPosition PositionFloat
+	Tick           uint32 // Entity update the event was triggered in
+}
+
+// Event is implemented by every event struct, the type is used for topic filtering
+type Event interface {
+	Type() EventType
+}
+
+// CoalescingEvent is implemented by events that only describe the latest state of an entity.
+// A newer event with the same key replaces a pending one for subscribers using BUFFER_COALESCE
+type CoalescingEvent interface {
+	Event
+	CoalesceKey() interface{}
+}
+
+func (e *ResourceUpdateEvent) Type() EventType             { return ResourceUpdate }
+func (e *UnitSpawnEvent) Type() EventType                  { return UnitSpawn }
+func (e *UnitPositionUpdatesEvent) Type() EventType        { return UnitPositionUpdates }
+func (e *UnitsTargetPointUpdateEvent) Type() EventType     { return UnitsRotationUpdate }
+func (e *TurretRotationUpdateEvent) Type() EventType       { return TurretRotationUpdate }
+func (e *BaseHealthUpdateEvent) Type() EventType           { return BaseHealthUpdate }
+func (e *NeutralBaseCapturedEvent) Type() EventType        { return NeutralBaseCaptured }
+func (e *PlayerKilledEvent) Type() EventType               { return PlayerKilled }
+func (e *UnitRemoveEvent) Type() EventType                 { return UnitRemove }
+func (e *BuildingRemovedEvent) Type() EventType            { return BuildingRemoved }
+func (e *BuildingPlacedEvent) Type() EventType             { return BuildingPlaced }
+func (e *BulletSpawnEvent) Type() EventType                { return BulletSpawn }
+func (e *UnitBulletSpawnEvent) Type() EventType            { return UnitBulletSpawn }
+func (e *BulletRemoveEvent) Type() EventType               { return BulletRemove }
+func (e *BulletPositionUpdateEvent) Type() EventType       { return BulletPositionUpdate }
+func (e *LeaderboardUpdateEvent) Type() EventType          { return LeaderboardUpdate }
+func (e *RemoveSpawnProtectionEvent) Type() EventType      { return RemoveSpawnProtection }
+func (e *KickEvent) Type() EventType                       { return Kick }
+func (e *AllianceUpdateEvent) Type() EventType             { return AllianceUpdate }
+func (e *NeutralBaseCaptureProgressEvent) Type() EventType { return NeutralBaseCaptureProgress }
+func (e *AchievementUnlockedEvent) Type() EventType        { return AchievementUnlocked }
+func (e *UnitVisibilityEvent) Type() EventType             { return UnitVisibility }
+func (e *BaseVisibilityEvent) Type() EventType             { return BaseVisibility }
+func (e *WorldEventStartEvent) Type() EventType            { return WorldEventStart }
+func (e *WorldEventEndEvent) Type() EventType              { return WorldEventEnd }
+func (e *PlayZoneUpdateEvent) Type() EventType             { return PlayZoneUpdate }
+func (e *RoundWonEvent) Type() EventType                   { return RoundWon }
+func (e *AchievementFlushEvent) Type() EventType           { return AchievementFlush }
+
+func (e *ResourceUpdateEvent) CoalesceKey() interface{}             { return e.Player }
+func (e *BaseHealthUpdateEvent) CoalesceKey() interface{}           { return e.Base }
+func (e *TurretRotationUpdateEvent) CoalesceKey() interface{}       { return e.Turret }
+func (e *BulletPositionUpdateEvent) CoalesceKey() interface{}       { return e.Bullet }
+func (e *NeutralBaseCaptureProgressEvent) CoalesceKey() interface{} { return e.NeutralBase }
+
+// Bus delivers all game events to its subscribers
+var Bus = NewEventBus()
+
+// Triggers hand events to the dispatcher so the simulation never waits on subscribers directly
+var eventChan = make(chan Event)
+
+// StartEventDispatcher publishes triggered events on the bus
 func StartEventDispatcher() {
 	for event := range eventChan {
-		dispatchEvent(event)
-	}
-}
-
-// AddListener adds a listener for events
-func AddListener(listener chan Event) {
-	eventListeners = append(eventListeners, listener)
-}
-
-// dispatchEvent dispatches an event to all listeners
-func dispatchEvent(event Event) {
-	for _, listener := range eventListeners {
-		listener <- event
+		Bus.Publish(event)
 	}
 }
 
 func TriggerResourceUpdateEvent(player *Player) {
-	eventChan <- Event{Type: ResourceUpdate, Payload: player}
+	eventChan <- &ResourceUpdateEvent{Player: player, Tick: CurrentTick()}
 }
 
 func TriggerUnitSpawnEvent(unit *Unit, barracks *Building) {
 	event := &UnitSpawnEvent{
+		Player:   unit.Player,
 		Unit:     unit,
 		Barracks: barracks,
+		Tick:     CurrentTick(),
 	}
-	eventChan <- Event{Type: UnitSpawn, Payload: event}
+	eventChan <- event
 }
 
 func TriggerUnitPositionUpdatesEvent(player *Player, units []*Unit) {
@@ -155,24 +278,27 @@ func TriggerUnitPositionUpdatesEvent(player *Player, units []*Unit) {
 	event := &UnitPositionUpdatesEvent{
 		Player: player,
 		Units:  units,
+		Tick:   CurrentTick(),
 	}
 	// Send the batch event
-	eventChan <- Event{Type: UnitPositionUpdates, Payload: event}
+	eventChan <- event
 }
 func TriggerUnitsRotationUpdateEvent(player *Player, units []*Unit) {
 	event := &UnitsTargetPointUpdateEvent{
 		Player: player,
 		Units:  units,
+		Tick:   CurrentTick(),
 	}
-	eventChan <- Event{Type: UnitsRotationUpdate, Payload: event}
+	eventChan <- event
 }
 
-func TriggerUnitRemoveEvent(player *Player, unitID ID) {
+func TriggerUnitRemoveEvent(unit *Unit) {
 	event := &UnitRemoveEvent{
-		Player: player,
-		UnitID: unitID,
+		Player: unit.Player,
+		UnitID: unit.ID,
+		Unit:   unit,
 	}
-	eventChan <- Event{Type: UnitRemove, Payload: event}
+	eventChan <- event
 }
 
 func TriggerBuildingRemovedEvent(base *Base, building *Building) {
@@ -180,7 +306,7 @@ func TriggerBuildingRemovedEvent(base *Base, building *Building) {
 		Base:     base,
 		Building: building,
 	}
-	eventChan <- Event{Type: BuildingRemoved, Payload: event}
+	eventChan <- event
 }
 
 func TriggerBuildingPlacedEvent(base *Base, building *Building) {
@@ -188,20 +314,21 @@ func TriggerBuildingPlacedEvent(base *Base, building *Building) {
 		Base:     base,
 		Building: building,
 	}
-	eventChan <- Event{Type: BuildingPlaced, Payload: event}
+	eventChan <- event
 }
 
 func TriggerBaseHealthUpdateEvent(base *Base) {
-	eventChan <- Event{Type: BaseHealthUpdate, Payload: base}
+	eventChan <- &BaseHealthUpdateEvent{Base: base, Tick: CurrentTick()}
 }
 
-func TriggerPlayerKilledEvent(player *Player, killer *Player) {
+func TriggerPlayerKilledEvent(player *Player, killer *Player, unit *Unit) {
 
 	event := &PlayerKilledEvent{
 		Player: player,
 		Killer: killer,
+		Unit:   unit,
 	}
-	eventChan <- Event{Type: PlayerKilled, Payload: event}
+	eventChan <- event
 }
 
 func TriggerUnitBulletSpawnEvent(player *Player, bullet *Bullet, unit *Unit) {
@@ -210,7 +337,7 @@ func TriggerUnitBulletSpawnEvent(player *Player, bullet *Bullet, unit *Unit) {
 		Bullet: bullet,
 		Unit:   unit,
 	}
-	eventChan <- Event{Type: UnitBulletSpawn, Payload: event}
+	eventChan <- event
 }
 
 func TriggerBulletSpawnEvent(owner Owner, bullet *Bullet, turret *Building) {
@@ -219,23 +346,29 @@ func TriggerBulletSpawnEvent(owner Owner, bullet *Bullet, turret *Building) {
 		Bullet: bullet,
 		Turret: turret,
 	}
-	eventChan <- Event{Type: BulletSpawn, Payload: event}
+	eventChan <- event
 }
 
-func TriggerBulletRemoveEvent(owner Owner, bulletID ID) {
+func TriggerBulletRemoveEvent(owner Owner, bullet *Bullet) {
 	event := &BulletRemoveEvent{
 		Owner:    owner,
-		BulletID: bulletID,
+		Bullet:   bullet,
+		BulletID: bullet.ID,
+		Position: bullet.GetPosition(),
 	}
-	eventChan <- Event{Type: BulletRemove, Payload: event}
+	eventChan <- event
 }
 
-func TriggerBulletPositionUpdateEvent(owner Owner, bullet *Bullet) {
+func TriggerBulletPositionUpdateEvent(owner Owner, bullet *Bullet, tick uint32) {
+	position, targetPosition := bullet.GetTrajectory()
 	event := &BulletPositionUpdateEvent{
-		Owner:  owner,
-		Bullet: bullet,
+		Owner:          owner,
+		Bullet:         bullet,
+		Position:       position,
+		TargetPosition: targetPosition,
+		Tick:           tick,
 	}
-	eventChan <- Event{Type: BulletPositionUpdate, Payload: event}
+	eventChan <- event
 }
 
 func TriggerTurretRotationUpdateEvent(owner Owner, turret *Building, targetPosition PositionFloat) {
@@ -243,29 +376,42 @@ func TriggerTurretRotationUpdateEvent(owner Owner, turret *Building, targetPosit
 		Owner:          owner,
 		Turret:         turret,
 		TargetPosition: targetPosition,
+		Tick:           CurrentTick(),
 	}
-	eventChan <- Event{Type: TurretRotationUpdate, Payload: event}
+	eventChan <- event
 }
 
 func TriggerLeaderboardUpdateEvent(changes *[]LeaderboardEntry) {
 	event := &LeaderboardUpdateEvent{
 		Changes: changes,
 	}
-	eventChan <- Event{Type: LeaderboardUpdate, Payload: event}
+	eventChan <- event
 }
 
 func TriggerRemoveSpawnProtectionEvent(player *Player) {
 	event := &RemoveSpawnProtectionEvent{
 		Player: player,
 	}
-	eventChan <- Event{Type: RemoveSpawnProtection, Payload: event}
+	eventChan <- event
 }
 
-func TriggerNeutralBaseCaptured(neutral *NeutralBase) {
+func TriggerNeutralBaseCaptured(neutral *NeutralBase, captor *Player) {
 	event := &NeutralBaseCapturedEvent{
 		NeutralBase: neutral,
+		Captor:      captor,
+	}
+	eventChan <- event
+}
+
+func TriggerNeutralBaseCaptureProgressEvent(neutral *NeutralBase, progress byte, capturingPlayer *Player, contested bool) {
+	event := &NeutralBaseCaptureProgressEvent{
+		NeutralBase:     neutral,
+		Progress:        progress,
+		CapturingPlayer: capturingPlayer,
+		Contested:       contested,
+		Tick:            CurrentTick(),
 	}
-	eventChan <- Event{Type: NeutralBaseCaptured, Payload: event}
+	eventChan <- event
 }
 
 func TriggerKickEvent(player *Player, reason byte) {
@@ -273,5 +419,59 @@ func TriggerKickEvent(player *Player, reason byte) {
 		Player: player,
 		Reason: reason,
 	}
-	eventChan <- Event{Type: Kick, Payload: event}
+	eventChan <- event
+}
+
+func TriggerAllianceUpdateEvent(alliance Alliance) {
+	event := &AllianceUpdateEvent{
+		Alliance: alliance,
+	}
+	eventChan <- event
+}
+
+func TriggerAchievementUnlockedEvent(player *Player, achievementID AchievementID) {
+	event := &AchievementUnlockedEvent{
+		Player:        player,
+		AchievementID: achievementID,
+	}
+	eventChan <- event
+}
+
+func TriggerUnitVisibilityEvent(player *Player, hidden []*Unit, revealed []*Unit) {
+	event := &UnitVisibilityEvent{
+		Player:   player,
+		Hidden:   hidden,
+		Revealed: revealed,
+		Tick:     CurrentTick(),
+	}
+	eventChan <- event
+}
+
+func TriggerBaseVisibilityEvent(player *Player, hidden []*Player, revealed []*Player) {
+	event := &BaseVisibilityEvent{
+		Player:   player,
+		Hidden:   hidden,
+		Revealed: revealed,
+		Tick:     CurrentTick(),
+	}
+	eventChan <- event
+}
+
+func TriggerWorldEventStartEvent(event *WorldEvent) {
+	eventChan <- &WorldEventStartEvent{Event: event}
+}
+
+func TriggerWorldEventEndEvent(event *WorldEvent, collectedBy *Player) {
+	eventChan <- &WorldEventEndEvent{
+		Event:       event,
+		CollectedBy: collectedBy,
+	}
+}
+
+func TriggerPlayZoneUpdateEvent(zone PlayZone) {
+	eventChan <- &PlayZoneUpdateEvent{Zone: zone}
+}
+
+func TriggerRoundWonEvent(winner *Player) {
+	eventChan <- &RoundWonEvent{Winner: winner}
 }
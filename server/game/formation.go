@@ -0,0 +1,205 @@
+package game
+
+import (
+	"math"
+	"math/rand"
+)
+
+// IsValidFormation checks if the given formation is known to the server
+func IsValidFormation(formation Formation) bool {
+	return formation <= FORMATION_BOX
+}
+
+// ArrangeFormation sets the target positions of the given units so they form
+// the requested formation around the target position
+func ArrangeFormation(formation Formation, targetPosition PositionInt, units []*Unit) {
+	if len(units) == 0 {
+		return
+	}
+
+	if len(units) == 1 {
+		units[0].SetTargetPosition(IntToFloat(targetPosition))
+		return
+	}
+
+	if formation == FORMATION_RING {
+		arrangeRingFormation(targetPosition, units)
+		return
+	}
+
+	// Orient the formation along the direction the group is moving
+	directionX, directionY := getFormationDirection(targetPosition, units)
+
+	var slots []PositionFloat
+	switch formation {
+	case FORMATION_LINE:
+		slots = getLineFormationSlots(targetPosition, directionX, directionY, len(units))
+	case FORMATION_WEDGE:
+		slots = getWedgeFormationSlots(targetPosition, directionX, directionY, len(units))
+	case FORMATION_BOX:
+		slots = getBoxFormationSlots(targetPosition, directionX, directionY, len(units))
+	default:
+		arrangeRingFormation(targetPosition, units)
+		return
+	}
+
+	assignFormationSlots(units, slots)
+}
+
+// arrangeRingFormation places units in growing rings around the target position
+func arrangeRingFormation(targetPosition PositionInt, units []*Unit) {
+	radius := FORMATION_SPACING // Start radius
+	totalUnits := 0             // Count total units placed
+
+	// Store the index of the nearest unit
+	var nearestUnitIndex int
+	nearestDistance := float32(math.MaxFloat32)
+
+	for {
+		circumference := 2.0 * math.Pi * radius
+		unitsInLayer := int(circumference / FORMATION_SPACING)
+
+		// Exit if no units can fit or we placed all units
+		if unitsInLayer <= 0 || totalUnits >= len(units) {
+			break
+		}
+
+		// Place units for the current layer
+		for i := 0; i < unitsInLayer && totalUnits < len(units); i++ {
+			angle := float64(i) * (2.0 * math.Pi / float64(unitsInLayer))
+			targetX := float32(targetPosition.X) + float32(radius)*float32(math.Cos(angle))
+			targetY := float32(targetPosition.Y) + float32(radius)*float32(math.Sin(angle))
+
+			// Add random offset
+			targetX += (rand.Float32() - 0.5) * FORMATION_RING_OFFSET
+			targetY += (rand.Float32() - 0.5) * FORMATION_RING_OFFSET
+
+			// Set the target position for the unit
+			units[totalUnits].SetTargetPosition(PositionFloat{X: targetX, Y: targetY})
+
+			// Check if this unit is the nearest to the targetPosition
+			distance := units[totalUnits].Position.DistanceTo(IntToFloat(targetPosition))
+			if distance < nearestDistance {
+				nearestDistance = distance
+				nearestUnitIndex = totalUnits
+			}
+
+			totalUnits++
+		}
+		radius += FORMATION_SPACING // Increase the radius for the next layer
+	}
+
+	// Set the nearest unit's target position to the exact targetPosition
+	units[nearestUnitIndex].SetTargetPosition(IntToFloat(targetPosition))
+}
+
+// getFormationDirection returns the normalized direction from the center of the units to the target
+func getFormationDirection(targetPosition PositionInt, units []*Unit) (float64, float64) {
+	var centerX, centerY float64
+	for _, unit := range units {
+		centerX += float64(unit.Position.X)
+		centerY += float64(unit.Position.Y)
+	}
+	centerX /= float64(len(units))
+	centerY /= float64(len(units))
+
+	directionX := float64(targetPosition.X) - centerX
+	directionY := float64(targetPosition.Y) - centerY
+	length := math.Hypot(directionX, directionY)
+	if length < 1 {
+		// The group is already on the target, keep a fixed orientation
+		return 1, 0
+	}
+
+	return directionX / length, directionY / length
+}
+
+// formationSlot converts a slot given in formation space (lateral, depth) into a world position.
+// Positive depth is behind the target as seen from the moving group
+func formationSlot(targetPosition PositionInt, directionX, directionY, lateral, depth float64) PositionFloat {
+	// The right vector is perpendicular to the movement direction
+	rightX, rightY := -directionY, directionX
+
+	return PositionFloat{
+		X: float32(float64(targetPosition.X) + rightX*lateral - directionX*depth),
+		Y: float32(float64(targetPosition.Y) + rightY*lateral - directionY*depth),
+	}
+}
+
+// getLineFormationSlots lines units up side by side, starting a new rank behind once a rank is full
+func getLineFormationSlots(targetPosition PositionInt, directionX, directionY float64, count int) []PositionFloat {
+	slots := make([]PositionFloat, 0, count)
+
+	for rank := 0; len(slots) < count; rank++ {
+		unitsInRank := min(count-len(slots), FORMATION_LINE_MAX_WIDTH)
+		for i := 0; i < unitsInRank; i++ {
+			lateral := (float64(i) - float64(unitsInRank-1)/2) * FORMATION_SPACING
+			depth := float64(rank) * FORMATION_SPACING
+			slots = append(slots, formationSlot(targetPosition, directionX, directionY, lateral, depth))
+		}
+	}
+
+	return slots
+}
+
+// getWedgeFormationSlots places one unit on the target and the others on two trailing arms
+func getWedgeFormationSlots(targetPosition PositionInt, directionX, directionY float64, count int) []PositionFloat {
+	slots := make([]PositionFloat, 0, count)
+	slots = append(slots, formationSlot(targetPosition, directionX, directionY, 0, 0))
+
+	for row := 1; len(slots) < count; row++ {
+		offset := float64(row) * FORMATION_SPACING
+		slots = append(slots, formationSlot(targetPosition, directionX, directionY, -offset, offset))
+		if len(slots) < count {
+			slots = append(slots, formationSlot(targetPosition, directionX, directionY, offset, offset))
+		}
+	}
+
+	return slots
+}
+
+// getBoxFormationSlots fills a square grid centered on the target position
+func getBoxFormationSlots(targetPosition PositionInt, directionX, directionY float64, count int) []PositionFloat {
+	slots := make([]PositionFloat, 0, count)
+	side := int(math.Ceil(math.Sqrt(float64(count))))
+	center := float64(side-1) / 2
+
+	for row := 0; row < side && len(slots) < count; row++ {
+		for column := 0; column < side && len(slots) < count; column++ {
+			lateral := (float64(column) - center) * FORMATION_SPACING
+			depth := (float64(row) - center) * FORMATION_SPACING
+			slots = append(slots, formationSlot(targetPosition, directionX, directionY, lateral, depth))
+		}
+	}
+
+	return slots
+}
+
+// assignFormationSlots gives every unit the closest free slot so units don't cross each other's paths
+func assignFormationSlots(units []*Unit, slots []PositionFloat) {
+	taken := make([]bool, len(slots))
+
+	for _, unit := range units {
+		bestSlot := -1
+		bestDistance := float32(math.MaxFloat32)
+
+		for i, slot := range slots {
+			if taken[i] {
+				continue
+			}
+
+			distance := unit.Position.DistanceTo(slot)
+			if distance < bestDistance {
+				bestDistance = distance
+				bestSlot = i
+			}
+		}
+
+		if bestSlot == -1 {
+			return
+		}
+
+		taken[bestSlot] = true
+		unit.SetTargetPosition(slots[bestSlot])
+	}
+}
@@ -14,6 +14,7 @@ type GameState struct {
 	NeutralBases       []*NeutralBase
 	Bushes             []PositionInt
 	Rocks              []Rock
+	Map                MapInfo // Seed and layout the map was generated from
 	AvailablePositions map[PositionInt]bool
 	Leaderboard        *Leaderboard
 	sync.RWMutex
@@ -27,8 +28,18 @@ var (
 	availablePlayerIDs *AvailableIDs
 )
 
-func init() {
+// Start sets up the game and starts the simulation. Packages that only use maps, like the map preview,
+// import game without starting it
+func Start() {
 	go StartEventDispatcher()
+	startAchievementListener()
+
+	availablePlayerIDs = InitAvailableIDs(MAX_PLAYERS)
+	InitializeGameMap()
+	State.Leaderboard = &Leaderboard{}
+
+	InitializeNonSkinColors()
+	loadSkins("data/skins.json")
 
 	// Start the updates
 	go startResourceUpdateLoop()
@@ -38,15 +49,14 @@ func init() {
 	go startTargetingLoop()
 	go startEntityUpdateLoop()
 	go startProtectionCheckLoop()
-}
+	go startCaptureLoop()
 
-func Start() {
-	availablePlayerIDs = InitAvailableIDs(64)
-	InitializeGameMap()
-	State.Leaderboard = &Leaderboard{}
-
-	InitializeNonSkinColors()
-	loadSkins("data/skins.json")
+	if WorldEventConfig.Enabled {
+		go startWorldEventLoop()
+	}
+	if PlayZoneConfig.Enabled {
+		go startPlayZoneLoop()
+	}
 }
 
 func startRegenerationLoop() {
@@ -103,6 +113,149 @@ func startProtectionCheckLoop() {
 	}
 }
 
+func startCaptureLoop() {
+	duration := 500 * time.Millisecond
+	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		State.RLock()
+		players := make([]*Player, 0, len(State.Players))
+		for _, player := range State.Players {
+			if !player.IsMarkedForRemoval() {
+				players = append(players, player)
+			}
+		}
+		neutrals := make([]*NeutralBase, 0, len(State.NeutralBases))
+		neutrals = append(neutrals, State.NeutralBases...)
+		State.RUnlock()
+
+		for _, neutral := range neutrals {
+			updateNeutralBaseCapture(neutral, players, duration)
+		}
+	}
+}
+
+// updateNeutralBaseCapture advances the capture meter of a neutral base. The meter fills while
+// a single side occupies a base with a destroyed core, pauses while contested and decays otherwise
+func updateNeutralBaseCapture(neutral *NeutralBase, players []*Player, duration time.Duration) {
+	present := getPlayersInsideNeutralBase(neutral, players)
+	coreDestroyed := !neutral.Base.Health.IsAlive()
+	seconds := duration.Seconds()
+
+	// Allies count as one side
+	var attacker *Player
+	contested := false
+	for _, player := range present {
+		if attacker == nil {
+			attacker = player
+		} else if !isSameSide(attacker, player) {
+			contested = true
+		}
+	}
+
+	previousProgress, previousCapturer, previousContested := neutral.GetCaptureProgress()
+
+	neutral.Lock()
+	owner := neutral.CapturedBy
+	neutral.CaptureContested = contested
+	isCapturing := false
+	recovered := false
+	if coreDestroyed {
+		neutral.CoreDownTime += seconds
+	} else {
+		neutral.CoreDownTime = 0
+	}
+
+	switch {
+	case contested:
+		// Paused until only one side is left
+
+	case attacker != nil && coreDestroyed && !isSameSide(attacker, owner):
+		if neutral.CapturingPlayer != nil && !isSameSide(neutral.CapturingPlayer, attacker) {
+			// Progress of another side has to be drained first
+			neutral.CaptureProgress -= seconds
+			if neutral.CaptureProgress <= 0 {
+				neutral.CaptureProgress = 0
+				neutral.CapturingPlayer = attacker
+			}
+		} else {
+			if neutral.CapturingPlayer == nil {
+				neutral.CapturingPlayer = attacker
+			}
+			neutral.CaptureProgress += seconds
+			isCapturing = true
+		}
+
+	default:
+		neutral.CaptureProgress -= seconds * NEUTRAL_BASE_CAPTURE_DECAY_RATE
+		if neutral.CaptureProgress <= 0 {
+			neutral.CaptureProgress = 0
+			neutral.CapturingPlayer = nil
+
+			// The core recovers once nobody is left to capture it, but gives attackers time to move in first
+			recovered = coreDestroyed && neutral.CoreDownTime >= NEUTRAL_BASE_CORE_RECOVERY_DELAY
+			if recovered {
+				neutral.CoreDownTime = 0
+			}
+		}
+	}
+
+	capturingPlayer := neutral.CapturingPlayer
+	completed := capturingPlayer != nil && neutral.CaptureProgress >= NEUTRAL_BASE_CAPTURE_TIME
+	neutral.Unlock()
+
+	if isCapturing {
+		attacker.IncrementScore(uint32(NEUTRAL_BASE_CAPTURE_SCORE * seconds))
+	}
+
+	if recovered {
+		neutral.Base.Health.Reset()
+		TriggerBaseHealthUpdateEvent(neutral.Base)
+	}
+
+	if completed {
+		handleNeutralBaseCaptured(capturingPlayer, neutral)
+	}
+
+	progress, capturer, contested := neutral.GetCaptureProgress()
+	if progress != previousProgress || capturer != previousCapturer || contested != previousContested {
+		TriggerNeutralBaseCaptureProgressEvent(neutral, progress, capturer, contested)
+	}
+}
+
+// getPlayersInsideNeutralBase returns all players with at least one unit inside the capture radius
+func getPlayersInsideNeutralBase(neutral *NeutralBase, players []*Player) []*Player {
+	basePosition := IntToFloat(neutral.Base.Position)
+	present := make([]*Player, 0)
+
+	for _, player := range players {
+		player.RLock()
+		units := make([]*Unit, 0, len(player.Units))
+		for _, unit := range player.Units {
+			units = append(units, unit)
+		}
+		player.RUnlock()
+
+		for _, unit := range units {
+			if unit.IsMarkedForRemoval() {
+				continue
+			}
+
+			if unit.IsWithinRadius(basePosition, NEUTRAL_BASE_CAPTURE_RADIUS+float32(unit.Size)) {
+				present = append(present, player)
+				break
+			}
+		}
+	}
+	return present
+}
+
+// isSameSide checks if both players are the same player or allied
+func isSameSide(player *Player, otherPlayer *Player) bool {
+	return player == otherPlayer || AreAllied(player, otherPlayer)
+}
+
 func startResourceUpdateLoop() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -126,6 +279,8 @@ func startResourceUpdateLoop() {
 			// Increment the player's score safely
 			player.IncrementScore(uint32(scoreIncrement)) // Cast back to uint32
 
+			player.recordPeaks()
+
 			// Trigger resource update event
 			TriggerResourceUpdateEvent(player)
 		}
@@ -188,6 +343,7 @@ func startUnitSpawnLoop() {
 					spawning.Frequency.Reset()
 
 					player.AddUnitBulletSpawning(unit)
+					player.recordPeaks()
 
 					TriggerUnitSpawnEvent(unit, spawning.Barracks)
 				}
@@ -385,6 +541,11 @@ func findClosestUnitInRange(spawning *BulletSpawning, players []*Player, exclude
 			continue
 		}
 
+		// Skip allies
+		if AreAllied(excludePlayer, otherPlayer) {
+			continue
+		}
+
 		// Lock the player to access units
 		otherPlayer.RLock()
 		otherPlayerUnits := make([]*Unit, 0, len(otherPlayer.Units))
@@ -398,6 +559,11 @@ func findClosestUnitInRange(spawning *BulletSpawning, players []*Player, exclude
 				continue
 			}
 
+			// Units hidden in a bush can only be targeted once they are revealed
+			if !excludePlayer.CanSee(unit) {
+				continue
+			}
+
 			// ? GetPosition doesnt use a LOCK
 			turretPosition := spawning.Shooter.GetPosition()
 			unitPosition := unit.GetPosition()
@@ -421,6 +587,11 @@ func findClosestBuildingInRange(spawning *BulletSpawning, player *Player, player
 			continue
 		}
 
+		// Skip allies
+		if AreAllied(player, otherPlayer) {
+			continue
+		}
+
 		// Lock the player to access units
 		otherPlayer.Base.RLock()
 		otherPlayerBuildings := make([]*Building, 0, len(otherPlayer.Base.Buildings))
@@ -455,8 +626,8 @@ func findClosestBuildingInRangeNeutralBase(spawning *BulletSpawning, player *Pla
 	for _, neutral := range neutral {
 
 		// Check if the player has captured the neutral base
-		if neutral.CapturedBy == player {
-			continue // Skip if the player has captured this neutral base
+		if neutral.CapturedBy == player || AreAllied(neutral.CapturedBy, player) {
+			continue // Skip if the player or an ally has captured this neutral base
 		}
 
 		// Lock the neutral base to access buildings
@@ -497,11 +668,13 @@ func hasCaptured(base *NeutralBase, capturedBases []*NeutralBase) bool {
 }
 
 func startEntityUpdateLoop() {
-	duration := 50 * time.Millisecond
+	duration := ENTITY_UPDATE_INTERVAL * time.Millisecond
 	ticker := time.NewTicker(duration)
 	defer ticker.Stop()
 
 	for range ticker.C {
+		tick := advanceEntityTick()
+
 		State.RLock()
 		players := make([]*Player, 0, len(State.Players))
 		for _, player := range State.Players {
@@ -511,24 +684,28 @@ func startEntityUpdateLoop() {
 		}
 		neutrals := make([]*NeutralBase, 0, len(State.NeutralBases))
 		neutrals = append(neutrals, State.NeutralBases...)
+		bushes := State.Bushes
 		State.RUnlock()
 
-		updateEntities(players, neutrals, duration)
+		updateEntities(players, neutrals, tick, duration)
 		checkCollisions(players, neutrals)
+		updateVisibility(players, bushes)
 	}
 }
 
-func updateEntities(players []*Player, neutrals []*NeutralBase, duration time.Duration) {
+func updateEntities(players []*Player, neutrals []*NeutralBase, tick uint32, duration time.Duration) {
 	for _, player := range players {
-		updateBullets(player.Base, duration)
-		updateUnits(player, duration, players)
+		updateBullets(player.Base, tick, duration)
+		updateUnits(player, tick, duration, players)
 	}
 	for _, neutral := range neutrals {
-		updateBullets(neutral.Base, duration)
+		updateBullets(neutral.Base, tick, duration)
 	}
 }
 
-func updateBullets(base *Base, duration time.Duration) {
+// updateBullets moves every bullet one step. Clients simulate the same steps from the spawn message,
+// so positions are only sent when a bullet left its predictable trajectory
+func updateBullets(base *Base, tick uint32, duration time.Duration) {
 	base.RLock()
 	bullets := make([]*Bullet, 0, len(base.Bullets))
 	for _, bullet := range base.Bullets {
@@ -541,6 +718,11 @@ func updateBullets(base *Base, duration time.Duration) {
 			continue
 		}
 
+		// Bullets added while this update is running start moving with the next one
+		if bullet.StartTick > tick {
+			continue
+		}
+
 		// Skip position update if bullet is a trapper and has already reached target
 		if bullet.Behavior == TrapperBullet && bullet.ReachedTargetPosition {
 			// Handle stay duration countdown for trapper bullets
@@ -549,7 +731,7 @@ func updateBullets(base *Base, duration time.Duration) {
 			}
 			// Remove the trapper bullet once its stay duration has expired
 			if bullet.StayDuration <= 0 {
-				TriggerBulletRemoveEvent(base.Owner, bullet.ID)
+				TriggerBulletRemoveEvent(base.Owner, bullet)
 				bullet.MarkForRemoval()
 				base.RemoveBullet(bullet.ID)
 			}
@@ -560,7 +742,7 @@ func updateBullets(base *Base, duration time.Duration) {
 		if !updated {
 			// Handle normal bullet behavior
 			if bullet.Behavior != TrapperBullet {
-				TriggerBulletRemoveEvent(base.Owner, bullet.ID)
+				TriggerBulletRemoveEvent(base.Owner, bullet)
 				bullet.MarkForRemoval()
 				base.RemoveBullet(bullet.ID)
 				continue
@@ -570,12 +752,13 @@ func updateBullets(base *Base, duration time.Duration) {
 			}
 		}
 
-		// Trigger position update event only if bullet still moves or is not a trapper bullet
-		TriggerBulletPositionUpdateEvent(base.Owner, bullet)
+		if bullet.takeDiverged() {
+			TriggerBulletPositionUpdateEvent(base.Owner, bullet, tick)
+		}
 	}
 }
 
-func updateUnits(player *Player, duration time.Duration, players []*Player) {
+func updateUnits(player *Player, tick uint32, duration time.Duration, players []*Player) {
 	// Lock the player to access units
 	player.RLock()
 	units := make([]*Unit, 0, len(player.Units))
@@ -595,6 +778,9 @@ func updateUnits(player *Player, duration time.Duration, players []*Player) {
 		if unit.UpdatePosition(duration, units) {
 			updatedUnits = append(updatedUnits, unit)
 		}
+
+		// Kept for validating commands of lagging clients
+		unit.recordPosition(tick)
 	}
 
 	// Trigger a single update event for all updated units
@@ -651,8 +837,8 @@ func checkRockCollisions(units []*Unit) {
 // ! TODO: Optimize this shit
 func checkBulletCollisions(player *Player, players []*Player, neutrals []*NeutralBase, units []*Unit, buildings []*Building) {
 	for _, otherPlayer := range players {
-		// Skip self or players marked for removal
-		if otherPlayer.ID == player.ID || otherPlayer.IsMarkedForRemoval() {
+		// Skip self, allies or players marked for removal
+		if otherPlayer.ID == player.ID || otherPlayer.IsMarkedForRemoval() || AreAllied(player, otherPlayer) {
 			continue
 		}
 
@@ -681,7 +867,7 @@ func checkBulletCollisions(player *Player, players []*Player, neutrals []*Neutra
 
 					isAlive := bullet.TakeDamage(unitHealth)
 					if !isAlive { // Bullet is destroyed
-						TriggerBulletRemoveEvent(otherPlayer.Base.Owner, bullet.ID)
+						TriggerBulletRemoveEvent(otherPlayer.Base.Owner, bullet)
 						bullet.MarkForRemoval()
 						otherPlayer.Base.RemoveBullet(bullet.ID)
 					}
@@ -695,6 +881,7 @@ func checkBulletCollisions(player *Player, players []*Player, neutrals []*Neutra
 						damage *= uint16(bullet.DamageMultiplier) // 200% against other units
 					}
 
+					recordDamage(otherPlayer, player, unitHealth, damage)
 					isAlive = unit.TakeDamage(damage)
 					if !isAlive { // Unit is destroyed
 						unit.MarkForRemoval()
@@ -727,15 +914,16 @@ func checkBulletCollisions(player *Player, players []*Player, neutrals []*Neutra
 
 					isAlive := bullet.TakeDamage(buildingHealth)
 					if !isAlive { // Bullet is destroyed
-						TriggerBulletRemoveEvent(otherPlayer.Base.Owner, bullet.ID)
+						TriggerBulletRemoveEvent(otherPlayer.Base.Owner, bullet)
 						bullet.MarkForRemoval()
 						otherPlayer.Base.RemoveBullet(bullet.ID)
 					}
 
+					recordDamage(otherPlayer, player, buildingHealth, bulletHealth)
 					isAlive = building.TakeDamage(bulletHealth)
 					if !isAlive { // Unit is destroyed
 						building.MarkForRemoval()
-						handleBuildingDestroyed(building, player.Base)
+						handleBuildingDestroyed(building, player.Base, otherPlayer)
 						break // Building destroyed no need for more bullet checks for that building
 					}
 				}
@@ -754,7 +942,7 @@ func checkBulletCollisions(player *Player, players []*Player, neutrals []*Neutra
 				}
 
 				if isBulletCollidingWithRock(bullet, &rock) {
-					TriggerBulletRemoveEvent(otherPlayer.Base.Owner, bullet.ID)
+					TriggerBulletRemoveEvent(otherPlayer.Base.Owner, bullet)
 					bullet.MarkForRemoval()
 					otherPlayer.Base.RemoveBullet(bullet.ID)
 				}
@@ -764,7 +952,7 @@ func checkBulletCollisions(player *Player, players []*Player, neutrals []*Neutra
 
 	for _, neutral := range neutrals {
 
-		if hasCaptured(neutral, player.CapturedNeutralBases) {
+		if hasCaptured(neutral, player.CapturedNeutralBases) || AreAllied(neutral.CapturedBy, player) {
 			continue
 		}
 
@@ -793,16 +981,17 @@ func checkBulletCollisions(player *Player, players []*Player, neutrals []*Neutra
 
 					isAlive := bullet.TakeDamage(unitHealth)
 					if !isAlive { // Bullet is destroyed
-						TriggerBulletRemoveEvent(neutral.Base.Owner, bullet.ID)
+						TriggerBulletRemoveEvent(neutral.Base.Owner, bullet)
 						bullet.MarkForRemoval()
 						neutral.Base.RemoveBullet(bullet.ID)
 					}
 
 					damage := bulletHealth
-					if bullet.Behavior == AntiTankBullet && unit.Type == TANK && unit.Type == SIEGE_TANK {
+					if bullet.Behavior == AntiTankBullet && (unit.Type == TANK || unit.Type == SIEGE_TANK) {
 						damage *= uint16(bullet.DamageMultiplier) // 150% damage to tanks
 					}
 
+					recordDamage(neutral.CapturedBy, player, unitHealth, damage)
 					isAlive = unit.TakeDamage(damage)
 					if !isAlive { // Unit is destroyed
 						unit.MarkForRemoval()
@@ -821,7 +1010,7 @@ func checkBulletCollisions(player *Player, players []*Player, neutrals []*Neutra
 	}
 	player.Base.RUnlock()
 	for _, neutral := range neutrals {
-		if hasCaptured(neutral, player.CapturedNeutralBases) {
+		if hasCaptured(neutral, player.CapturedNeutralBases) || AreAllied(neutral.CapturedBy, player) {
 			continue
 		}
 		neutral.Base.RLock()
@@ -856,15 +1045,16 @@ func checkBulletCollisions(player *Player, players []*Player, neutrals []*Neutra
 
 					isAlive := bullet.TakeDamage(buildingHealth)
 					if !isAlive { // Bullet is destroyed
-						TriggerBulletRemoveEvent(player.Base.Owner, bullet.ID)
+						TriggerBulletRemoveEvent(player.Base.Owner, bullet)
 						bullet.MarkForRemoval()
 						player.Base.RemoveBullet(bullet.ID)
 					}
 
+					recordDamage(player, neutral.CapturedBy, buildingHealth, bulletHealth)
 					isAlive = building.TakeDamage(bulletHealth)
 					if !isAlive { // Unit is destroyed
 						building.MarkForRemoval()
-						handleBuildingDestroyed(building, neutral.Base)
+						handleBuildingDestroyed(building, neutral.Base, player)
 						break // Building destroyed no need for more bullet checks for that building
 					}
 				}
@@ -896,6 +1086,11 @@ func checkBaseCollisions(player *Player, players []*Player, units []*Unit) {
 			continue
 		}
 
+		// Allied bases can be passed safely
+		if AreAllied(player, otherPlayer) {
+			continue
+		}
+
 		// Lock the player to access buildings
 		otherPlayer.Base.RLock()
 		otherBuildings := make([]*Building, 0, len(otherPlayer.Base.Buildings))
@@ -933,6 +1128,8 @@ func checkBaseCollisions(player *Player, players []*Player, units []*Unit) {
 				unitHealth := unit.Health.Current
 				unitIsAlive := unit.TakeDamage(otherPlayerHealth)
 				otherPlayerIsAlive := otherPlayer.Base.TakeDamage(unitHealth)
+				recordDamage(otherPlayer, player, unitHealth, otherPlayerHealth)
+				recordDamage(player, otherPlayer, otherPlayerHealth, unitHealth)
 
 				if !otherPlayerIsAlive {
 					// Calculate the score and power increment
@@ -946,7 +1143,7 @@ func checkBaseCollisions(player *Player, players []*Player, units []*Unit) {
 
 					// Mark the other player for removal and trigger the kill event
 					otherPlayer.MarkForRemoval()
-					TriggerPlayerKilledEvent(otherPlayer, player)
+					TriggerPlayerKilledEvent(otherPlayer, player, unit)
 				} else {
 					// Update the health
 					TriggerBaseHealthUpdateEvent(otherPlayer.Base)
@@ -970,7 +1167,7 @@ func checkBaseCollisions(player *Player, players []*Player, units []*Unit) {
 					if !buildingAlive {
 						player.IncrementScore(uint32(building.Health.Max))
 						building.MarkForRemoval()
-						handleBuildingDestroyed(building, otherPlayer.Base)
+						handleBuildingDestroyed(building, otherPlayer.Base, player)
 					}
 
 					if !unitAlive {
@@ -988,7 +1185,7 @@ func checkNeutralBaseCollisions(player *Player, neutrals []*NeutralBase, units [
 	for _, neutral := range neutrals {
 		basePosition := neutral.Base.Position
 
-		if neutral.CapturedBy == player {
+		if neutral.CapturedBy == player || AreAllied(neutral.CapturedBy, player) {
 			continue
 		}
 
@@ -1012,21 +1209,18 @@ func checkNeutralBaseCollisions(player *Player, neutrals []*NeutralBase, units [
 				continue
 			}
 
-			// Check if unit is colliding with the core
+			// Check if unit is colliding with the core, a destroyed core is left to the capture meter
 			neutralBaseHealth := neutral.Base.Health.Current
-			isNearCore := unit.IsWithinRadius(IntToFloat(basePosition), (float32(neutralBaseHealth)/NEUTRAL_BASE_INITIAL_HEALTH)*NEUTRAL_BASE_MAX_CORE_RADIUS+unitSize)
+			isNearCore := neutralBaseHealth > 0 && unit.IsWithinRadius(IntToFloat(basePosition), (float32(neutralBaseHealth)/float32(neutral.Base.Health.Max))*NEUTRAL_BASE_MAX_CORE_RADIUS+unitSize)
 			if isNearCore {
 				unitHealth := unit.Health.Current
 				unitIsAlive := unit.TakeDamage(neutralBaseHealth)
-				neutralBaseIsAlive := neutral.Base.TakeDamage(unitHealth)
+				neutral.Base.TakeDamage(unitHealth)
+				recordDamage(neutral.CapturedBy, player, unitHealth, neutralBaseHealth)
+				recordDamage(player, neutral.CapturedBy, neutralBaseHealth, unitHealth)
 
-				if !neutralBaseIsAlive {
-					handleNeutralBaseCaptured(player, neutral)
-					break
-				} else {
-					// Update the health
-					TriggerBaseHealthUpdateEvent(neutral.Base)
-				}
+				// Update the health
+				TriggerBaseHealthUpdateEvent(neutral.Base)
 
 				if !unitIsAlive {
 					unit.MarkForRemoval()
@@ -1046,7 +1240,7 @@ func checkNeutralBaseCollisions(player *Player, neutrals []*NeutralBase, units [
 					if !buildingAlive {
 						player.IncrementScore(uint32(building.Health.Max))
 						building.MarkForRemoval()
-						handleBuildingDestroyed(building, neutral.Base)
+						handleBuildingDestroyed(building, neutral.Base, player)
 					}
 
 					if !unitAlive {
@@ -1087,7 +1281,7 @@ func applyExplosionDamage(unit *Unit) {
 		}
 
 		// Skip friendly damage
-		if player.ID == unit.Player.ID {
+		if player.ID == unit.Player.ID || AreAllied(player, unit.Player) {
 			continue
 		}
 
@@ -1109,6 +1303,7 @@ func applyExplosionDamage(unit *Unit) {
 				continue
 			}
 			if unit.IsWithinRadius(otherUnit.Position, explosionRadius*offset) {
+				recordDamage(unit.Player, player, otherUnit.Health.Current, damage)
 				isAlive := otherUnit.TakeDamage(damage)
 				if !isAlive {
 					otherUnit.MarkForRemoval()
@@ -1127,11 +1322,12 @@ func applyExplosionDamage(unit *Unit) {
 					continue
 				}
 				if unit.IsWithinRadius(otherBuilding.Position, explosionRadius*offset) {
+					recordDamage(unit.Player, player, otherBuilding.Health.Current, damage)
 					isAlive := otherBuilding.TakeDamage(damage)
 					if !isAlive {
 						otherBuilding.MarkForRemoval()
 						unit.Player.IncrementScore(uint32(otherBuilding.Health.Max))
-						handleBuildingDestroyed(otherBuilding, player.Base)
+						handleBuildingDestroyed(otherBuilding, player.Base, unit.Player)
 					}
 				}
 			}
@@ -1140,6 +1336,7 @@ func applyExplosionDamage(unit *Unit) {
 			otherPlayerHealth := player.Base.Health.Get()
 			isNearCore := unit.IsWithinRadius(IntToFloat(basePosition), (float32(otherPlayerHealth)/PLAYER_INITIAL_HEALTH)*PLAYER_MAX_CORE_RADIUS+explosionRadius)
 			if isNearCore {
+				recordDamage(unit.Player, player, otherPlayerHealth, damage)
 				isAlive := player.Base.TakeDamage(damage)
 				TriggerBaseHealthUpdateEvent(player.Base)
 				if !isAlive {
@@ -1155,7 +1352,7 @@ func applyExplosionDamage(unit *Unit) {
 					unit.Player.IncrementScore(scoreIncrement)
 					unit.Player.Resources.Power.Increment(uint16(powerIncrement))
 
-					TriggerPlayerKilledEvent(player, unit.Player)
+					TriggerPlayerKilledEvent(player, unit.Player, unit)
 				}
 			}
 		}
@@ -1164,8 +1361,8 @@ func applyExplosionDamage(unit *Unit) {
 
 func checkUnitCollisions(player *Player, players []*Player, units []*Unit) {
 	for _, otherPlayer := range players {
-		// Skip self or players marked for removal
-		if otherPlayer.ID == player.ID || otherPlayer.IsMarkedForRemoval() {
+		// Skip self, allies or players marked for removal
+		if otherPlayer.ID == player.ID || otherPlayer.IsMarkedForRemoval() || AreAllied(player, otherPlayer) {
 			continue
 		}
 		// Lock the player to access units
@@ -1297,12 +1494,20 @@ func isUnitCollidingWithUnit(unit1, unit2 *Unit) bool {
 }
 
 func handleNeutralBaseCaptured(player *Player, neutral *NeutralBase) {
-	if neutral.CapturedBy != nil {
-		neutral.CapturedBy.RemoveCapturedNeutralBase(neutral)
+	// The player left while the meter filled up
+	if player.IsMarkedForRemoval() {
+		neutral.ResetCaptureBy(player)
+		return
+	}
+
+	if previous := neutral.Captured(player); previous != nil {
+		previous.RemoveCapturedNeutralBase(neutral)
+		previous.recordNeutralBaseLost()
 	}
-	neutral.Captured(player)
 	player.AddCapturedNeutralBase(neutral)
-	TriggerNeutralBaseCaptured(neutral)
+	player.recordNeutralBaseCaptured()
+	player.IncrementScore(neutral.GetArchetype().CaptureScore)
+	TriggerNeutralBaseCaptured(neutral, player)
 }
 
 func handleUnitBuildingCollision(unit *Unit, building *Building) (bool, bool) {
@@ -1312,6 +1517,10 @@ func handleUnitBuildingCollision(unit *Unit, building *Building) (bool, bool) {
 	isUnitAlive := unit.TakeDamage(buildingHealth)
 	isBuildingAlive := building.TakeDamage(unitHealth)
 
+	buildingOwner := getOwningPlayer(building.Owner)
+	recordDamage(buildingOwner, unit.Player, unitHealth, buildingHealth)
+	recordDamage(unit.Player, buildingOwner, buildingHealth, unitHealth)
+
 	return isUnitAlive, isBuildingAlive
 }
 
@@ -1331,6 +1540,8 @@ func handleUnitCollision(unit1, unit2 *Unit) (bool, bool) {
 	// Apply damage
 	isAliveUnit1 := unit1.TakeDamage(unit2Health)
 	isAliveUnit2 := unit2.TakeDamage(unit1Health)
+	recordDamage(unit2.Player, unit1.Player, unit1Health, unit2Health)
+	recordDamage(unit1.Player, unit2.Player, unit2Health, unit1Health)
 
 	// Return the alive status in the original order
 	if originalUnit1 > originalUnit2 {
@@ -1346,24 +1557,28 @@ func handleUnitDestroyed(unit *Unit) {
 		unit.Player.RemoveUnitBulletSpawning(unit)
 	}
 
-	unitID := unit.ID
 	ok := unit.Player.RemoveUnit(unit.ID)
 	if ok {
+		unit.Player.recordUnitLost(unit.Type)
 		requiredPopulation, ok := GetUnitRequiredPopulation(unit.Type)
 		if !ok {
 			log.Println("Could not find the required population for unit (handleUnitDestroyed)")
 			return
 		}
 		unit.Player.Population.DecrementUsed(requiredPopulation)
-		TriggerUnitRemoveEvent(unit.Player, unitID)
+		TriggerUnitRemoveEvent(unit)
 	} else {
 		//! Is already removed
 	}
 }
 
-func handleBuildingDestroyed(building *Building, base *Base) {
+// handleBuildingDestroyed removes a destroyed building, destroyedBy may be nil
+func handleBuildingDestroyed(building *Building, base *Base, destroyedBy *Player) {
 	ok := base.RemoveBuilding(building.ID)
 	if ok {
+		if destroyedBy != nil {
+			destroyedBy.recordBuildingDestroyed(building.Type)
+		}
 		TriggerBuildingRemovedEvent(base, building)
 	}
 }
@@ -1379,6 +1594,11 @@ func AddPlayer(conn *websocket.Conn, permission Permission, name []byte, color [
 		}
 	}
 
+	if IsJoiningClosed() {
+		log.Println("Play zone is shrinking, the room is closed for new players")
+		return nil, false
+	}
+
 	initialPower := uint16(PLAYER_INITIAL_POWER)
 	maxPower := uint16(PLAYER_MAX_POWER)
 	if permission == PERMISSION_ADMIN {
@@ -1396,7 +1616,8 @@ func AddPlayer(conn *websocket.Conn, permission Permission, name []byte, color [
 		Kills:             0,
 		Camera:            NewCamera(),
 		Units:             make(map[ID]*Unit),
-		AvailableUnitIDs:  InitAvailableIDs(128),
+		AvailableUnitIDs:  InitAvailableIDs(MAX_UNITS_PER_PLAYER),
+		AllianceRequests:  make(map[ID]time.Time),
 		Population:        Population{Capacity: PLAYER_INITIAL_POPULATION, Used: 0},
 		UnitSpawningLimit: Capacity{Current: 0, Max: 5},
 		Resources: Resources{
@@ -1431,8 +1652,8 @@ func AddPlayer(conn *websocket.Conn, permission Permission, name []byte, color [
 			BARRACKS:      {0, 9999},
 			GENERATOR:     {0, 9999},
 			HOUSE:         {0, 64}},
-		AvailableBuildingIDs: InitAvailableIDs(256),
-		AvailableBulletIDs:   InitAvailableIDs(256),
+		AvailableBuildingIDs: InitAvailableIDs(MAX_BUILDINGS),
+		AvailableBulletIDs:   InitAvailableIDs(MAX_BULLETS),
 	}
 
 	// Truncate the player name if it's longer than 12 bytes
@@ -1465,6 +1686,16 @@ func AddPlayer(conn *websocket.Conn, permission Permission, name []byte, color [
 }
 
 func RemovePlayer(conn *websocket.Conn) (ID, uint32, uint32, time.Duration, bool) {
+	var leftAlliance Alliance
+	var wasAllied bool
+
+	// Deferred before the state unlock so the alliance update is sent once the state is unlocked
+	defer func() {
+		if wasAllied {
+			TriggerAllianceUpdateEvent(leftAlliance)
+		}
+	}()
+
 	State.Lock()
 	defer State.Unlock()
 
@@ -1517,8 +1748,18 @@ func RemovePlayer(conn *websocket.Conn) (ID, uint32, uint32, time.Duration, bool
 	}
 	player.Base.Unlock()
 
-	for _, base := range player.CapturedNeutralBases {
-		base.Captured(nil)
+	player.RLock()
+	capturedNeutralBases := append([]*NeutralBase(nil), player.CapturedNeutralBases...)
+	player.RUnlock()
+	for _, base := range capturedNeutralBases {
+		base.Release(player)
+	}
+
+	leftAlliance, wasAllied = removePlayerFromAlliances(player)
+
+	// Drop the capture progress of the leaving player
+	for _, neutral := range State.NeutralBases {
+		neutral.ResetCaptureBy(player)
 	}
 
 	// Return player ID to available pool
@@ -1542,3 +1783,14 @@ func GetPlayerByConn(conn *websocket.Conn) (*Player, bool) {
 
 	return nil, false
 }
+
+func GetPlayerByID(id ID) (*Player, bool) {
+	State.RLock()
+	defer State.RUnlock()
+	player, ok := State.Players[id]
+	if !ok || player.IsMarkedForRemoval() {
+		return nil, false
+	}
+
+	return player, true
+}
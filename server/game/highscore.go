@@ -0,0 +1,190 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type HighScoreEntry struct {
+	Key        string    `json:"key"` // Discord ID when logged in, otherwise the player name
+	Name       string    `json:"name"`
+	LoggedIn   bool      `json:"loggedIn"`
+	Score      uint32    `json:"score"`
+	Kills      uint32    `json:"kills"`
+	Playtime   int       `json:"playtime"` // Seconds
+	AchievedAt time.Time `json:"achievedAt"`
+}
+
+type HighScoreBoard struct {
+	PeriodStart time.Time        `json:"periodStart"` // Start of the day or week, zero for the all-time board
+	Entries     []HighScoreEntry `json:"entries"`
+}
+
+// HighScores keeps the all-time, daily and weekly records of this server
+type HighScores struct {
+	AllTime HighScoreBoard `json:"allTime"`
+	Daily   HighScoreBoard `json:"daily"`
+	Weekly  HighScoreBoard `json:"weekly"`
+
+	path string
+	sync.RWMutex
+}
+
+var highScores = &HighScores{}
+
+// LoadHighScores reads the records from the given file, new records are saved to it
+func LoadHighScores(path string) {
+	highScores.Lock()
+	defer highScores.Unlock()
+
+	highScores.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		log.Println("Error reading high scores file:", err)
+		return
+	}
+
+	if err := json.Unmarshal(data, highScores); err != nil {
+		log.Println("Error parsing high scores file:", err)
+	}
+}
+
+// RecordHighScore enters the final score of a run into all boards.
+// Only the best run per key is kept on each board
+func RecordHighScore(key string, name string, loggedIn bool, score uint32, kills uint32, playtime time.Duration) bool {
+	if key == "" || score == 0 {
+		return false
+	}
+
+	highScores.Lock()
+	defer highScores.Unlock()
+
+	now := time.Now().UTC()
+	highScores.rotate(now)
+
+	entry := HighScoreEntry{
+		Key:        key,
+		Name:       name,
+		LoggedIn:   loggedIn,
+		Score:      score,
+		Kills:      kills,
+		Playtime:   int(playtime.Seconds()),
+		AchievedAt: now,
+	}
+
+	changed := false
+	for _, board := range highScores.boards() {
+		if board.submit(entry) {
+			changed = true
+		}
+	}
+
+	if changed {
+		highScores.save()
+	}
+	return changed
+}
+
+// GetHighScores returns a copy of the boards of all periods
+func GetHighScores() map[HighScorePeriod]HighScoreBoard {
+	highScores.Lock()
+	defer highScores.Unlock()
+
+	highScores.rotate(time.Now().UTC())
+
+	snapshot := make(map[HighScorePeriod]HighScoreBoard, 3)
+	for period, board := range highScores.boards() {
+		entries := make([]HighScoreEntry, len(board.Entries))
+		copy(entries, board.Entries)
+		snapshot[period] = HighScoreBoard{PeriodStart: board.PeriodStart, Entries: entries}
+	}
+	return snapshot
+}
+
+// HighScoreName converts a fixed size player name into a string
+func HighScoreName(name [12]byte) string {
+	return strings.TrimRight(string(name[:]), "\x00")
+}
+
+func (h *HighScores) boards() map[HighScorePeriod]*HighScoreBoard {
+	return map[HighScorePeriod]*HighScoreBoard{
+		HIGH_SCORE_ALL_TIME: &h.AllTime,
+		HIGH_SCORE_DAILY:    &h.Daily,
+		HIGH_SCORE_WEEKLY:   &h.Weekly,
+	}
+}
+
+// rotate clears the daily and weekly boards once their period ended, the caller must hold the lock
+func (h *HighScores) rotate(now time.Time) {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !h.Daily.PeriodStart.Equal(dayStart) {
+		h.Daily = HighScoreBoard{PeriodStart: dayStart}
+	}
+
+	// Weeks start on Monday
+	weekStart := dayStart.AddDate(0, 0, -((int(dayStart.Weekday()) + 6) % 7))
+	if !h.Weekly.PeriodStart.Equal(weekStart) {
+		h.Weekly = HighScoreBoard{PeriodStart: weekStart}
+	}
+}
+
+// submit replaces the entry of the same key if the score is higher and keeps the board sorted and trimmed
+func (b *HighScoreBoard) submit(entry HighScoreEntry) bool {
+	for i, existing := range b.Entries {
+		if existing.Key != entry.Key || existing.LoggedIn != entry.LoggedIn {
+			continue
+		}
+		if entry.Score <= existing.Score {
+			return false
+		}
+		b.Entries = append(b.Entries[:i], b.Entries[i+1:]...)
+		break
+	}
+
+	if len(b.Entries) >= HIGH_SCORE_BOARD_SIZE && entry.Score <= b.Entries[len(b.Entries)-1].Score {
+		return false
+	}
+
+	b.Entries = append(b.Entries, entry)
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		return b.Entries[i].Score > b.Entries[j].Score
+	})
+	if len(b.Entries) > HIGH_SCORE_BOARD_SIZE {
+		b.Entries = b.Entries[:HIGH_SCORE_BOARD_SIZE]
+	}
+	return true
+}
+
+// save writes the records to a temporary file first so a crash never leaves a half written file.
+// The caller must hold the lock
+func (h *HighScores) save() {
+	if h.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		log.Println("Error encoding high scores:", err)
+		return
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(h.path), "."+filepath.Base(h.path)+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		log.Println("Error writing high scores file:", err)
+		return
+	}
+	if err := os.Rename(tmpPath, h.path); err != nil {
+		log.Println("Error writing high scores file:", err)
+	}
+}
@@ -1,41 +1,82 @@
 package game
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
-type ID byte
+// ID identifies players, alliances, neutral bases, buildings, units and bullets. IDs are unique per pool
+type ID uint16
 
-// AvailableIDs manages a slice of available IDs
+// NO_ID is never handed out, messages send it where an entity is absent
+const NO_ID ID = MAX_ENTITY_IDS - 1
+
+// Generation counts how often an ID was reused, together with the ID it names one entity
+type Generation byte
+
+// AvailableIDs hands out the IDs of one pool. Fresh IDs are handed out first, returned IDs are only
+// reused once the pool ran out of fresh ones, oldest first and not before ID_REUSE_COOLDOWN passed.
+// Every return starts a new generation of the ID, so late commands naming the old entity can be told apart
 type AvailableIDs struct {
-	IDs []ID
+	IDs         []returnedID // Returned IDs waiting for reuse, oldest first
+	queued      map[ID]bool  // IDs in IDs, an ID returned twice is only queued once
+	generations map[ID]Generation
+	next        int // Lowest ID that was never handed out
+	limit       int
 	sync.Mutex
 }
 
+type returnedID struct {
+	id         ID
+	returnedAt time.Time
+}
+
 // Initializes an AvailableIDs struct with a specified number of IDs
 func InitAvailableIDs(numIDs int) *AvailableIDs {
-	ids := make([]ID, numIDs)
-	for i := 0; i < numIDs; i++ {
-		ids[i] = ID(i)
+	return &AvailableIDs{
+		queued:      make(map[ID]bool),
+		generations: make(map[ID]Generation),
+		limit:       min(numIDs, int(NO_ID)),
 	}
-	return &AvailableIDs{IDs: ids}
 }
 
 // getNextAvailableID returns the next available ID
 func (a *AvailableIDs) getNextAvailableID() (ID, bool) {
+	id, _, ok := a.getNextAvailableIDWithGeneration()
+	return id, ok
+}
+
+// getNextAvailableIDWithGeneration returns the next available ID and its current generation
+func (a *AvailableIDs) getNextAvailableIDWithGeneration() (ID, Generation, bool) {
 	a.Lock()
 	defer a.Unlock()
 
-	if len(a.IDs) == 0 {
-		return 0, false // No available IDs
+	if a.next < a.limit {
+		id := ID(a.next)
+		a.next++
+		return id, 0, true
+	}
+
+	// Returned IDs are queued in the order they came back, so the first one is the longest cooled down
+	if len(a.IDs) == 0 || time.Since(a.IDs[0].returnedAt) < ID_REUSE_COOLDOWN*time.Second {
+		return 0, 0, false // No available IDs
 	}
-	id := a.IDs[0]
+	id := a.IDs[0].id
 	a.IDs = a.IDs[1:] // Remove the first element
-	return id, true
+	delete(a.queued, id)
+	return id, a.generations[id], true
 }
 
-// ReturnID returns an ID to the available pool
+// ReturnID returns an ID to the available pool and starts its next generation.
+// Returning an ID that is already waiting for reuse does nothing, so it is never handed out twice
 func (a *AvailableIDs) returnID(id ID) {
 	a.Lock()
 	defer a.Unlock()
 
-	a.IDs = append(a.IDs, id)
+	if a.queued[id] {
+		return
+	}
+	a.queued[id] = true
+	a.generations[id]++
+	a.IDs = append(a.IDs, returnedID{id: id, returnedAt: time.Now()})
 }
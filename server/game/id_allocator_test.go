@@ -0,0 +1,93 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// backdate makes the returned IDs look like they came back the given time ago
+func (a *AvailableIDs) backdate(ago time.Duration) {
+	for i := range a.IDs {
+		a.IDs[i].returnedAt = time.Now().Add(-ago)
+	}
+}
+
+func TestAvailableIDsCooldown(t *testing.T) {
+	a := InitAvailableIDs(3)
+	for want := ID(0); want < 3; want++ {
+		if id, ok := a.getNextAvailableID(); !ok || id != want {
+			t.Fatalf("fresh ID %d (%v) handed out, expected %d", id, ok, want)
+		}
+	}
+	if _, ok := a.getNextAvailableID(); ok {
+		t.Fatal("an ID was handed out from an exhausted pool")
+	}
+
+	a.returnID(2)
+	a.returnID(0)
+	if id, ok := a.getNextAvailableID(); ok {
+		t.Fatalf("ID %d was reused right after it was returned", id)
+	}
+	a.backdate(ID_REUSE_COOLDOWN*time.Second - time.Second)
+	if id, ok := a.getNextAvailableID(); ok {
+		t.Fatalf("ID %d was reused before its cooldown ended", id)
+	}
+
+	// Returned IDs come back in the order they were returned, in their next generation
+	a.backdate(ID_REUSE_COOLDOWN * time.Second)
+	for _, want := range []ID{2, 0} {
+		id, generation, ok := a.getNextAvailableIDWithGeneration()
+		if !ok || id != want || generation != 1 {
+			t.Errorf("reused ID %d generation %d (%v), expected ID %d generation 1", id, generation, ok, want)
+		}
+	}
+}
+
+func TestAvailableIDsPrefersFreshIDs(t *testing.T) {
+	a := InitAvailableIDs(3)
+	a.getNextAvailableID()
+	a.returnID(0)
+	a.backdate(time.Hour)
+
+	if id, _ := a.getNextAvailableID(); id != 1 {
+		t.Errorf("ID %d handed out while fresh IDs are left, expected 1", id)
+	}
+
+	if a := InitAvailableIDs(MAX_ENTITY_IDS + 1); a.limit != int(NO_ID) {
+		t.Errorf("pool limited to %d IDs, expected the ID space without NO_ID of %d", a.limit, NO_ID)
+	}
+}
+
+func TestAvailableIDsHandsOutReturnedIDOnce(t *testing.T) {
+	a := InitAvailableIDs(2)
+	a.getNextAvailableID()
+	a.getNextAvailableID()
+
+	a.returnID(1)
+	a.returnID(1)
+	a.backdate(ID_REUSE_COOLDOWN * time.Second)
+
+	if id, generation, ok := a.getNextAvailableIDWithGeneration(); !ok || id != 1 || generation != 1 {
+		t.Fatalf("reused ID %d generation %d (%v), expected ID 1 generation 1", id, generation, ok)
+	}
+	if id, ok := a.getNextAvailableID(); ok {
+		t.Fatalf("ID %d handed out again after a double return", id)
+	}
+}
+
+func TestAvailableIDsGenerationWraps(t *testing.T) {
+	a := InitAvailableIDs(1)
+	id, _ := a.getNextAvailableID()
+
+	for returns := 1; returns <= 257; returns++ {
+		a.returnID(id)
+		a.backdate(ID_REUSE_COOLDOWN * time.Second)
+		_, generation, ok := a.getNextAvailableIDWithGeneration()
+		if !ok {
+			t.Fatalf("ID not reused after return %d", returns)
+		}
+		if want := Generation(returns % 256); generation != want {
+			t.Fatalf("generation %d after %d returns, expected %d", generation, returns, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package game
+
+import "time"
+
+// positionSample is the position of an entity after an entity update
+type positionSample struct {
+	tick     uint32
+	position PositionFloat
+}
+
+// positionHistory keeps the positions of the last entity updates in a ring buffer
+type positionHistory struct {
+	samples [POSITION_HISTORY_SIZE]positionSample
+	count   int
+	next    int
+}
+
+func (h *positionHistory) record(tick uint32, position PositionFloat) {
+	h.samples[h.next] = positionSample{tick: tick, position: position}
+	h.next = (h.next + 1) % len(h.samples)
+	h.count = min(h.count+1, len(h.samples))
+}
+
+// at returns the newest position recorded at or before the tick, or the oldest one if the tick is older
+func (h *positionHistory) at(tick uint32) (PositionFloat, bool) {
+	if h.count == 0 {
+		return PositionFloat{}, false
+	}
+
+	var oldest positionSample
+	for i := 1; i <= h.count; i++ {
+		sample := h.samples[(h.next-i+len(h.samples))%len(h.samples)]
+		if sample.tick <= tick {
+			return sample.position, true
+		}
+		oldest = sample
+	}
+	return oldest.position, true
+}
+
+func (u *Unit) recordPosition(tick uint32) {
+	u.Lock()
+	defer u.Unlock()
+	u.history.record(tick, u.Position)
+}
+
+// PositionAt returns where the unit was after the given entity update.
+// Units spawned since are taken at their first known position
+func (u *Unit) PositionAt(tick uint32) PositionFloat {
+	u.RLock()
+	defer u.RUnlock()
+	if position, ok := u.history.at(tick); ok {
+		return position
+	}
+	return u.Position
+}
+
+// RewindTick limits the tick a client command refers to, it can be rewound by at most maxRewind
+// and never more than the lag compensation window
+func RewindTick(tick uint32, maxRewind time.Duration) uint32 {
+	return rewindTick(tick, CurrentTick(), maxRewind)
+}
+
+func rewindTick(tick, current uint32, maxRewind time.Duration) uint32 {
+	if tick > current {
+		return current
+	}
+
+	maxRewind = min(maxRewind, LAG_COMPENSATION_WINDOW*time.Millisecond)
+	maxTicks := uint32(maxRewind / (ENTITY_UPDATE_INTERVAL * time.Millisecond))
+	if current-tick > maxTicks {
+		return current - maxTicks
+	}
+	return tick
+}
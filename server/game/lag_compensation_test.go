@@ -0,0 +1,71 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPositionHistory(t *testing.T) {
+	var h positionHistory
+	if _, ok := h.at(10); ok {
+		t.Fatal("empty history returned a position")
+	}
+
+	for tick := uint32(10); tick <= 30; tick += 10 {
+		h.record(tick, PositionFloat{X: float32(tick), Y: 1})
+	}
+
+	expect := func(tick uint32, x float32) {
+		t.Helper()
+		position, ok := h.at(tick)
+		if !ok || position.X != x {
+			t.Errorf("position at tick %d is %v (%v), expected X %v", tick, position, ok, x)
+		}
+	}
+	expect(20, 20)
+	expect(25, 20)
+	expect(40, 30)
+	// Ticks older than the history fall back to the oldest sample
+	expect(5, 10)
+
+	// Fill the ring past its size so the first samples are overwritten
+	last := uint32(30)
+	for i := 0; i < POSITION_HISTORY_SIZE; i++ {
+		last += 10
+		h.record(last, PositionFloat{X: float32(last)})
+	}
+	oldest := last - (POSITION_HISTORY_SIZE-1)*10
+	expect(last, float32(last))
+	expect(oldest, float32(oldest))
+	expect(10, float32(oldest))
+	expect(oldest+5, float32(oldest))
+}
+
+func TestRewindTick(t *testing.T) {
+	interval := ENTITY_UPDATE_INTERVAL * time.Millisecond
+	window := LAG_COMPENSATION_WINDOW * time.Millisecond
+	windowTicks := uint32(window / interval)
+
+	tests := []struct {
+		tick      uint32
+		maxRewind time.Duration
+		want      uint32
+	}{
+		{100, window, 100},
+		{105, window, 100},
+		{98, window, 98},
+		{100 - windowTicks, window, 100 - windowTicks},
+		{99 - windowTicks, window, 100 - windowTicks},
+		{0, window, 100 - windowTicks},
+		{0, time.Minute, 100 - windowTicks},
+		{97, 2 * interval, 98},
+		{98, interval + interval/2, 99},
+		{99, 0, 100},
+	}
+
+	for _, tt := range tests {
+		if got := rewindTick(tt.tick, 100, tt.maxRewind); got != tt.want {
+			t.Errorf("rewinding to tick %d by at most %v from tick 100 gave %d, expected %d", tt.tick, tt.maxRewind, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package game
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain only starts the event dispatcher, the simulation loops would change the state the tests set up
+func TestMain(m *testing.M) {
+	State.Leaderboard = &Leaderboard{} // Scoring players updates it
+	go StartEventDispatcher()
+	os.Exit(m.Run())
+}
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"log"
 	"math"
 	"math/rand"
 )
@@ -31,7 +32,7 @@ func abs(x int16) int16 {
 	return x
 }
 
-func generateBushes(centers []PositionInt, neutralBases []PositionInt, radius float64, numBushes int, minDistance int16) []PositionInt {
+func generateBushes(rng *rand.Rand, centers []PositionInt, neutralBases []PositionInt, radius float64, numBushes int, minDistance int16) []PositionInt {
 	bushes := make([]PositionInt, 0, numBushes)
 
 	// Function to check if a bush position is too close to any center, neutral base, or other bushes
@@ -56,8 +57,8 @@ func generateBushes(centers []PositionInt, neutralBases []PositionInt, radius fl
 
 	for len(bushes) < numBushes {
 		// Generate random angle and radius
-		angle := rand.Float64() * 2 * math.Pi // Random angle
-		distance := rand.Float64() * radius   // Random distance from the center
+		angle := rng.Float64() * 2 * math.Pi // Random angle
+		distance := rng.Float64() * radius   // Random distance from the center
 
 		// Calculate bush position based on angle and distance
 		bushX := int16(distance * math.Cos(angle))
@@ -75,10 +76,11 @@ func generateBushes(centers []PositionInt, neutralBases []PositionInt, radius fl
 
 type Rock struct {
 	Polygon Polygon
+	Shape   PolygonType
 	Size    int
 }
 
-func generateRocks(centers []PositionInt, neutralBases []PositionInt, radius float64, numRocks int, minDistance int16, polygonType PolygonType) []Rock {
+func generateRocks(rng *rand.Rand, centers []PositionInt, neutralBases []PositionInt, radius float64, numRocks int, minDistance int16, polygonType PolygonType) []Rock {
 	rocks := make([]Rock, 0, numRocks) // Store Rock structs
 
 	// Function to check if a rock position is too close to any neutral or player base
@@ -95,8 +97,8 @@ func generateRocks(centers []PositionInt, neutralBases []PositionInt, radius flo
 	// Generate large rocks first
 	for len(rocks) < numRocks/2 { // Generate half as large rocks
 		// Generate random angle and radius for large rocks
-		angle := rand.Float64() * 2 * math.Pi
-		distance := rand.Float64() * radius
+		angle := rng.Float64() * 2 * math.Pi
+		distance := rng.Float64() * radius
 
 		// Calculate position for the large rock
 		rockX := int16(distance * math.Cos(angle))
@@ -106,44 +108,46 @@ func generateRocks(centers []PositionInt, neutralBases []PositionInt, radius flo
 		// Check if the large rock is far enough from neutral/player bases
 		if isFarEnough(rockPos) {
 			// Generate a large size rock between 60 and 80
-			size := rand.Intn(40) + 60 // Generates a number between 60 and 80
+			size := rng.Intn(40) + 60 // Generates a number between 60 and 80
 
 			// Generate polygon for large rock
 			polygon := GeneratePolygon(polygonType, size, 0)
-			polygon.SetRotation(rand.Float64() * 2 * math.Pi)
+			polygon.SetRotation(rng.Float64() * 2 * math.Pi)
 			polygon.Center = PositionFloat{X: float32(rockX), Y: float32(rockY)}
 
 			// Create large rock
 			rock := Rock{
 				Polygon: polygon,
+				Shape:   polygonType,
 				Size:    size,
 			}
 			rocks = append(rocks, rock)
 
 			// Generate smaller rocks around this large rock
 			// Make sure small rocks are randomly scattered close to the large rock
-			numSmallRocks := rand.Intn(2) + 2
+			numSmallRocks := rng.Intn(2) + 2
 			for i := 0; i < numSmallRocks; i++ {
 				// Randomly generate smaller rocks close to the large rock
-				angle := rand.Float64() * 2 * math.Pi
+				angle := rng.Float64() * 2 * math.Pi
 				// Vary distance randomly between 0.5 and 1.5 times the size of the large rock
-				distance := rand.Float64()*1.0 + 2 // Random distance factor
+				distance := rng.Float64()*1.0 + 2 // Random distance factor
 
 				// Calculate the position of the small rock around the large rock with random offset
 				smallRockX := int16(float64(rockX) + distance*float64(size)*math.Cos(angle))
 				smallRockY := int16(float64(rockY) + distance*float64(size)*math.Sin(angle))
 
 				// Generate a small size rock between 40 and 60
-				smallSize := rand.Intn(40) + 20 // Generates a number between 40 and 60
+				smallSize := rng.Intn(40) + 20 // Generates a number between 40 and 60
 
 				// Generate polygon for small rock
 				smallPolygon := GeneratePolygon(polygonType, smallSize, 0)
-				smallPolygon.SetRotation(rand.Float64() * 2 * math.Pi)
+				smallPolygon.SetRotation(rng.Float64() * 2 * math.Pi)
 				smallPolygon.Center = PositionFloat{X: float32(smallRockX), Y: float32(smallRockY)}
 
 				// Create small rock
 				smallRock := Rock{
 					Polygon: smallPolygon,
+					Shape:   polygonType,
 					Size:    smallSize,
 				}
 				rocks = append(rocks, smallRock)
@@ -154,8 +158,8 @@ func generateRocks(centers []PositionInt, neutralBases []PositionInt, radius flo
 	// Generate remaining small rocks
 	for len(rocks) < numRocks {
 		// Generate random angle and radius for smaller rocks
-		angle := rand.Float64() * 2 * math.Pi
-		distance := rand.Float64() * radius
+		angle := rng.Float64() * 2 * math.Pi
+		distance := rng.Float64() * radius
 
 		// Calculate position for the small rock
 		rockX := int16(distance * math.Cos(angle))
@@ -165,16 +169,17 @@ func generateRocks(centers []PositionInt, neutralBases []PositionInt, radius flo
 		// Check if the small rock is far enough from neutral/player bases
 		if isFarEnough(rockPos) {
 			// Generate a small size rock between 40 and 60
-			size := rand.Intn(40) + 60
+			size := rng.Intn(40) + 60
 
 			// Generate polygon for small rock
 			polygon := GeneratePolygon(polygonType, size, 0)
-			polygon.SetRotation(rand.Float64() * 2 * math.Pi)
+			polygon.SetRotation(rng.Float64() * 2 * math.Pi)
 			polygon.Center = PositionFloat{X: float32(rockX), Y: float32(rockY)}
 
 			// Create small rock
 			rock := Rock{
 				Polygon: polygon,
+				Shape:   polygonType,
 				Size:    size,
 			}
 			rocks = append(rocks, rock)
@@ -184,73 +189,35 @@ func generateRocks(centers []PositionInt, neutralBases []PositionInt, radius flo
 	return rocks
 }
 
-func generateHexagonGameMap() (playerPositions, neutralPositions []PositionInt) {
-	hexagonSize := 2500.0
-	center := PositionInt{X: 0, Y: 0}
-
-	// Generate player positions for the center hexagon
-	playerPositions = append(playerPositions, generateHexagon(center, hexagonSize)...)
-
-	// Keep track of generated positions to avoid duplication
-	generatedPositions := make(map[PositionInt]bool)
-	generatedPositions[center] = true // Mark center as generated
-
-	// Generate positions for the surrounding hexagons
-	offsets := []struct{ x, y float64 }{
-		{0, hexagonSize * math.Sqrt(3)},
-		{hexagonSize * 1.5, hexagonSize * math.Sqrt(3) / 2},
-		{-hexagonSize * 1.5, hexagonSize * math.Sqrt(3) / 2},
-		{-hexagonSize * 1.5, -hexagonSize * math.Sqrt(3) / 2},
-		{hexagonSize * 1.5, -hexagonSize * math.Sqrt(3) / 2},
-		{0, -hexagonSize * math.Sqrt(3)},
+// getNeutralBaseType picks the archetype of a neutral base, the centre of the map always holds the citadel
+func getNeutralBaseType(position PositionInt, index int) NeutralBaseType {
+	if position == (PositionInt{X: 0, Y: 0}) {
+		return CITADEL
 	}
 
-	for _, offset := range offsets {
-		hexagonCenter := PositionInt{X: int16(math.Round(offset.x)), Y: int16(math.Round(offset.y))}
-		hexagonPositions := generateHexagon(hexagonCenter, hexagonSize)
-
-		// Add player positions for the surrounded hexagon
-		playerPositions = append(playerPositions, hexagonPositions...)
-
-		// Add neutral position (center of hexagon) as neutral base
-		neutralPositions = append(neutralPositions, hexagonCenter)
-
-		// Mark the generated positions as used
-		for _, pos := range hexagonPositions {
-			generatedPositions[pos] = true
-		}
-	}
-
-	neutralPositions = append(neutralPositions, PositionInt{X: 0, Y: 0})
-
-	// Remove duplicates and positions too close to each other
-	uniquePlayerPositions := make([]PositionInt, 0, len(playerPositions))
-	seen := make(map[PositionInt]bool)
-
-	for _, pos := range playerPositions {
-		if !seen[pos] {
-			// Check if the position is too close to any existing position
-			isUnique := true
-			for _, uniquePos := range uniquePlayerPositions {
-				if isTooClose(pos, uniquePos, 1, 1) {
-					isUnique = false
-					break
-				}
-			}
-			if isUnique {
-				seen[pos] = true
-				uniquePlayerPositions = append(uniquePlayerPositions, pos)
-			}
-		}
-	}
-
-	return uniquePlayerPositions, neutralPositions
+	outerTypes := []NeutralBaseType{POWER_OUTPOST, BARRACKS_OUTPOST, FORTRESS}
+	return outerTypes[index%len(outerTypes)]
 }
 
-// Helper function to initialize the game state with predefined positions
+// Helper function to initialize the game state with the map file or a map generated from MapConfig
 func InitializeGameMap() {
+	var gameMap GameMap
+	if MapConfig.File != "" {
+		var err error
+		gameMap, err = LoadMapFile(MapConfig.File)
+		if err != nil {
+			log.Fatal("Error loading map file:", err)
+		}
+		log.Printf("Loaded map %q from %s: %d spawn positions, %d neutral bases, radius %d",
+			gameMap.Info.Name, MapConfig.File, len(gameMap.PlayerPositions), len(gameMap.NeutralBases), gameMap.Info.Radius)
+	} else {
+		gameMap = GenerateMap(MapConfig)
+		log.Printf("Generated %s map for %d players with seed %d: %d spawn positions, %d neutral bases, radius %d",
+			gameMap.Info.Layout, gameMap.Info.ExpectedPlayers, gameMap.Info.Seed, len(gameMap.PlayerPositions), len(gameMap.NeutralBases), gameMap.Info.Radius)
+	}
+	playerPositions, neutralBases := gameMap.PlayerPositions, gameMap.NeutralBases
+	State.Map = gameMap.Info
 
-	playerPositions, neutralPositions := generateHexagonGameMap()
 	// Initialize the map with all positions as available
 	State.AvailablePositions = make(map[PositionInt]bool)
 	for _, pos := range playerPositions {
@@ -258,33 +225,38 @@ func InitializeGameMap() {
 	}
 
 	// Populate NeutralBases with NeutralBase instances
-	State.NeutralBases = make([]*NeutralBase, len(neutralPositions))
-	for i, pos := range neutralPositions {
+	State.NeutralBases = make([]*NeutralBase, len(neutralBases))
+	for i, mapBase := range neutralBases {
+		pos := mapBase.Position
+
 		// Initialize the neutral base
 		neutralBase := &NeutralBase{
-			ID: ID(i), // IDs are assigned based on position index
+			ID:     ID(i), // IDs are assigned based on position index
+			Type:   mapBase.Type,
+			Layout: mapBase.Buildings,
 		}
+		health := neutralBase.GetArchetype().Health
 
 		neutralBase.Base = &Base{
 			Owner:                neutralBase,
 			Position:             pos,
-			Health:               Health{Current: NEUTRAL_BASE_INITIAL_HEALTH, Max: NEUTRAL_BASE_INITIAL_HEALTH},
+			Health:               Health{Current: health, Max: health},
 			Buildings:            make(map[ID]*Building),
 			Bullets:              make(map[ID]*Bullet),
-			AvailableBuildingIDs: InitAvailableIDs(256),
-			AvailableBulletIDs:   InitAvailableIDs(256),
+			AvailableBuildingIDs: InitAvailableIDs(MAX_BUILDINGS),
+			AvailableBulletIDs:   InitAvailableIDs(MAX_BULLETS),
 		}
 
 		// Add the neutral base to the GameState
 		State.NeutralBases[i] = neutralBase
 	}
 
-	// Populate each neutral base with walls
+	// Populate each neutral base with its building layout
 	for _, base := range State.NeutralBases {
 		PopulateNeutralBase(base)
 	}
 
-	// Generate bushes away from player and neutral base positions
-	State.Bushes = generateBushes(playerPositions, neutralPositions, 8000, 30, 800)
-	State.Rocks = generateRocks(playerPositions, neutralPositions, 8000, 20, 1000, ShapeHexagon)
+	// Bushes and rocks were generated away from player and neutral base positions
+	State.Bushes = gameMap.Bushes
+	State.Rocks = gameMap.Rocks
 }
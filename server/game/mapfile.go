@@ -0,0 +1,299 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MapFile is the JSON format of fixed maps in data/maps. Positions are map coordinates, building positions are
+// relative to their neutral base
+type MapFile struct {
+	Name         string               `json:"name"`
+	Spawns       []MapFilePosition    `json:"spawns"`
+	NeutralBases []MapFileNeutralBase `json:"neutralBases"`
+	Bushes       []MapFilePosition    `json:"bushes"`
+	Rocks        []MapFileRock        `json:"rocks"`
+}
+
+type MapFilePosition struct {
+	X int16 `json:"x"`
+	Y int16 `json:"y"`
+}
+
+type MapFileNeutralBase struct {
+	X         int16             `json:"x"`
+	Y         int16             `json:"y"`
+	Type      string            `json:"type"`      // Archetype, picked like on generated maps if empty
+	Buildings []MapFileBuilding `json:"buildings"` // Empty uses the layout of the archetype
+}
+
+type MapFileBuilding struct {
+	Type    string          `json:"type"`
+	Variant BuildingVariant `json:"variant"`
+	X       float32         `json:"x"`
+	Y       float32         `json:"y"`
+}
+
+type MapFileRock struct {
+	X        int16   `json:"x"`
+	Y        int16   `json:"y"`
+	Shape    string  `json:"shape"` // Hexagon if empty
+	Size     int     `json:"size"`
+	Rotation float64 `json:"rotation"` // Degrees
+}
+
+var neutralBaseTypeNames = map[string]NeutralBaseType{
+	"power_outpost":    POWER_OUTPOST,
+	"barracks_outpost": BARRACKS_OUTPOST,
+	"fortress":         FORTRESS,
+	"citadel":          CITADEL,
+}
+
+var buildingTypeNames = map[string]BuildingType{
+	"wall":          WALL,
+	"simple_turret": SIMPLE_TURRET,
+	"sniper_turret": SNIPER_TURRET,
+}
+
+var rockShapeNames = map[string]PolygonType{
+	"circle":    ShapeCircle,
+	"hexagon":   ShapeHexagon,
+	"pentagon":  ShapePentagon,
+	"rectangle": ShapeRectangle,
+	"triangle":  ShapeTriangle,
+}
+
+// LoadMapFile reads a map file and checks that it is playable, every problem found is reported
+func LoadMapFile(filePath string) (GameMap, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return GameMap{}, err
+	}
+
+	var file MapFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return GameMap{}, fmt.Errorf("%s: %w", filePath, err)
+	}
+	if file.Name == "" {
+		file.Name = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	}
+
+	gameMap, err := file.toGameMap()
+	if err != nil {
+		return GameMap{}, fmt.Errorf("%s: %w", filePath, err)
+	}
+	if err := ValidateMap(gameMap); err != nil {
+		return GameMap{}, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return gameMap, nil
+}
+
+// toGameMap resolves the names used in the file
+func (f MapFile) toGameMap() (GameMap, error) {
+	var errs []error
+	gameMap := GameMap{
+		Info: MapInfo{
+			Layout:          MAP_LAYOUT_FILE,
+			ExpectedPlayers: len(f.Spawns),
+			Name:            f.Name,
+		},
+	}
+
+	for _, spawn := range f.Spawns {
+		gameMap.PlayerPositions = append(gameMap.PlayerPositions, PositionInt{X: spawn.X, Y: spawn.Y})
+	}
+
+	for i, base := range f.NeutralBases {
+		neutral := MapNeutralBase{Position: PositionInt{X: base.X, Y: base.Y}}
+		if base.Type == "" {
+			neutral.Type = getNeutralBaseType(neutral.Position, i)
+		} else if neutralType, ok := neutralBaseTypeNames[base.Type]; ok {
+			neutral.Type = neutralType
+		} else {
+			errs = append(errs, fmt.Errorf("neutral base %d: unknown type %q", i, base.Type))
+		}
+
+		for j, building := range base.Buildings {
+			buildingType, ok := buildingTypeNames[building.Type]
+			if !ok {
+				errs = append(errs, fmt.Errorf("neutral base %d building %d: unknown type %q", i, j, building.Type))
+				continue
+			}
+			if _, ok := GetBuildingCost(buildingType, building.Variant); !ok {
+				errs = append(errs, fmt.Errorf("neutral base %d building %d: %s has no variant %d", i, j, building.Type, building.Variant))
+				continue
+			}
+			// Preset buildings are not credited to the captor, so they must not generate power
+			if _, ok := GetResourceGeneration(buildingType, building.Variant); ok {
+				errs = append(errs, fmt.Errorf("neutral base %d building %d: %s variant %d generates power", i, j, building.Type, building.Variant))
+				continue
+			}
+			neutral.Buildings = append(neutral.Buildings, NeutralBuilding{
+				Type:    buildingType,
+				Variant: building.Variant,
+				Offset:  PositionFloat{X: building.X, Y: building.Y},
+			})
+		}
+		gameMap.NeutralBases = append(gameMap.NeutralBases, neutral)
+	}
+
+	for _, bush := range f.Bushes {
+		gameMap.Bushes = append(gameMap.Bushes, PositionInt{X: bush.X, Y: bush.Y})
+	}
+
+	for i, rock := range f.Rocks {
+		shape := ShapeHexagon
+		if rock.Shape != "" {
+			var ok bool
+			if shape, ok = rockShapeNames[rock.Shape]; !ok {
+				errs = append(errs, fmt.Errorf("rock %d: unknown shape %q", i, rock.Shape))
+				continue
+			}
+		}
+		if rock.Size < 1 || rock.Size > MAP_MAX_ROCK_SIZE {
+			errs = append(errs, fmt.Errorf("rock %d: size %d is not between 1 and %d", i, rock.Size, MAP_MAX_ROCK_SIZE))
+			continue
+		}
+
+		polygon := GeneratePolygon(shape, rock.Size, 0)
+		polygon.SetRotation(rock.Rotation * math.Pi / 180)
+		polygon.Center = IntToFloat(PositionInt{X: rock.X, Y: rock.Y})
+		gameMap.Rocks = append(gameMap.Rocks, Rock{Polygon: polygon, Shape: shape, Size: rock.Size})
+	}
+
+	// The map reaches a margin beyond the farthest thing placed on it
+	radius := float32(0)
+	for _, positions := range [][]PositionInt{gameMap.PlayerPositions, gameMap.Bushes} {
+		for _, pos := range positions {
+			radius = max(radius, pos.DistanceTo(PositionInt{}))
+		}
+	}
+	for _, neutral := range gameMap.NeutralBases {
+		radius = max(radius, neutral.Position.DistanceTo(PositionInt{}))
+	}
+	for _, rock := range gameMap.Rocks {
+		radius = max(radius, rock.Polygon.Center.DistanceTo(PositionFloat{}))
+	}
+	gameMap.Info.Radius = int16(min(radius+MAP_EDGE_MARGIN, MAP_MAX_RADIUS))
+
+	return gameMap, errors.Join(errs...)
+}
+
+// ValidateMap checks that bases keep their distance, pre-built buildings fit into their base and rocks
+// and bushes stay clear of every base and of each other
+func ValidateMap(gameMap GameMap) error {
+	var errs []error
+	if len(gameMap.PlayerPositions) == 0 {
+		errs = append(errs, errors.New("no spawn positions"))
+	}
+	if len(gameMap.PlayerPositions) > MAX_PLAYERS {
+		errs = append(errs, fmt.Errorf("%d spawn positions, at most %d players can join", len(gameMap.PlayerPositions), MAX_PLAYERS))
+	}
+
+	isOutside := func(pos PositionFloat) bool {
+		return pos.DistanceTo(PositionFloat{}) > MAP_MAX_RADIUS
+	}
+
+	for i, spawn := range gameMap.PlayerPositions {
+		if isOutside(IntToFloat(spawn)) {
+			errs = append(errs, fmt.Errorf("spawn %d is farther than %d from the centre", i, MAP_MAX_RADIUS))
+		}
+		for j := i + 1; j < len(gameMap.PlayerPositions); j++ {
+			if distance := spawn.DistanceTo(gameMap.PlayerPositions[j]); distance < MAP_MIN_SPAWN_DISTANCE {
+				errs = append(errs, fmt.Errorf("spawns %d and %d are %.0f apart, at least %d are needed", i, j, distance, MAP_MIN_SPAWN_DISTANCE))
+			}
+		}
+		for j, neutral := range gameMap.NeutralBases {
+			if distance := spawn.DistanceTo(neutral.Position); distance < MAP_MIN_SPAWN_NEUTRAL_DISTANCE {
+				errs = append(errs, fmt.Errorf("spawn %d and neutral base %d are %.0f apart, at least %d are needed", i, j, distance, MAP_MIN_SPAWN_NEUTRAL_DISTANCE))
+			}
+		}
+	}
+
+	for i, neutral := range gameMap.NeutralBases {
+		if isOutside(IntToFloat(neutral.Position)) {
+			errs = append(errs, fmt.Errorf("neutral base %d is farther than %d from the centre", i, MAP_MAX_RADIUS))
+		}
+		for j := i + 1; j < len(gameMap.NeutralBases); j++ {
+			if distance := neutral.Position.DistanceTo(gameMap.NeutralBases[j].Position); distance < MAP_MIN_NEUTRAL_DISTANCE {
+				errs = append(errs, fmt.Errorf("neutral bases %d and %d are %.0f apart, at least %d are needed", i, j, distance, MAP_MIN_NEUTRAL_DISTANCE))
+			}
+		}
+		errs = append(errs, validateNeutralBuildings(i, neutral)...)
+	}
+
+	for i, bush := range gameMap.Bushes {
+		if isOutside(IntToFloat(bush)) {
+			errs = append(errs, fmt.Errorf("bush %d is farther than %d from the centre", i, MAP_MAX_RADIUS))
+		}
+		for j, spawn := range gameMap.PlayerPositions {
+			if bush.DistanceTo(spawn) < PLAYER_SPAWN_PROTECTION_RADIUS+BUSH_RADIUS {
+				errs = append(errs, fmt.Errorf("bush %d overlaps the spawn protection of spawn %d", i, j))
+			}
+		}
+		for j, rock := range gameMap.Rocks {
+			if IntToFloat(bush).DistanceTo(rock.Polygon.Center) < float32(BUSH_RADIUS+rock.Size) {
+				errs = append(errs, fmt.Errorf("bush %d overlaps rock %d", i, j))
+			}
+		}
+	}
+
+	for i, rock := range gameMap.Rocks {
+		center := rock.Polygon.Center
+		if isOutside(center) {
+			errs = append(errs, fmt.Errorf("rock %d is farther than %d from the centre", i, MAP_MAX_RADIUS))
+		}
+		for j, spawn := range gameMap.PlayerPositions {
+			if center.DistanceTo(IntToFloat(spawn)) < float32(PLAYER_SPAWN_PROTECTION_RADIUS+rock.Size) {
+				errs = append(errs, fmt.Errorf("rock %d overlaps the spawn protection of spawn %d", i, j))
+			}
+		}
+		for j, neutral := range gameMap.NeutralBases {
+			if center.DistanceTo(IntToFloat(neutral.Position)) < float32(NEUTRAL_BASE_MAX_BUILDING_RADIUS+rock.Size) {
+				errs = append(errs, fmt.Errorf("rock %d overlaps neutral base %d", i, j))
+			}
+		}
+		for j := i + 1; j < len(gameMap.Rocks); j++ {
+			if DoPolygonsIntersect(rock.Polygon, gameMap.Rocks[j].Polygon) {
+				errs = append(errs, fmt.Errorf("rocks %d and %d overlap", i, j))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateNeutralBuildings checks that the pre-built buildings of a base lie between its core and its border
+// without overlapping each other. They are rotated the same way addNeutralBuilding rotates them
+func validateNeutralBuildings(index int, neutral MapNeutralBase) []error {
+	var errs []error
+	if len(neutral.Buildings) > MAX_BUILDINGS {
+		errs = append(errs, fmt.Errorf("neutral base %d has %d buildings, at most %d fit", index, len(neutral.Buildings), MAX_BUILDINGS))
+	}
+
+	polygons := make([]Polygon, len(neutral.Buildings))
+	for i, building := range neutral.Buildings {
+		distance := building.Offset.DistanceTo(PositionFloat{})
+		minDistance := float32(NEUTRAL_BASE_MIN_BUILDING_RADIUS + GetBuildingSize(building.Type))
+		if distance < minDistance || distance > NEUTRAL_BASE_MAX_BUILDING_RADIUS {
+			errs = append(errs, fmt.Errorf("neutral base %d building %d is %.0f from the base, it has to be between %.0f and %d",
+				index, i, distance, minDistance, NEUTRAL_BASE_MAX_BUILDING_RADIUS))
+		}
+
+		polygons[i], _ = GetBuildingPolygon(building.Type)
+		polygons[i].SetCenter(building.Offset)
+		polygons[i].SetRotation(math.Atan2(float64(building.Offset.Y), float64(building.Offset.X)))
+
+		for j := 0; j < i; j++ {
+			if DoPolygonsIntersect(polygons[i], polygons[j]) {
+				errs = append(errs, fmt.Errorf("neutral base %d buildings %d and %d overlap", index, j, i))
+			}
+		}
+	}
+	return errs
+}
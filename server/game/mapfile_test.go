@@ -0,0 +1,92 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// playableMap is a small map file that passes validation
+func playableMap() MapFile {
+	return MapFile{
+		Name:   "test",
+		Spawns: []MapFilePosition{{X: -2000, Y: 0}, {X: 2000, Y: 0}},
+		NeutralBases: []MapFileNeutralBase{{
+			X: 0, Y: 2000, Type: "fortress",
+			Buildings: []MapFileBuilding{{Type: "wall", X: 150, Y: 0}, {Type: "simple_turret", X: -150, Y: 0}},
+		}},
+		Bushes: []MapFilePosition{{X: 0, Y: -2000}},
+		Rocks:  []MapFileRock{{X: 0, Y: -3000, Size: 50}},
+	}
+}
+
+func TestValidateMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(f *MapFile)
+		problem string // Part of the expected error, empty if the map is playable
+	}{
+		{"playable", func(f *MapFile) {}, ""},
+		{"no spawns", func(f *MapFile) { f.Spawns = nil }, "no spawn positions"},
+		{"spawns too close", func(f *MapFile) { f.Spawns[1].X = -1500 }, "spawns 0 and 1"},
+		{"spawn next to a neutral base", func(f *MapFile) { f.Spawns[0] = MapFilePosition{X: 0, Y: 1500} }, "spawn 0 and neutral base 0"},
+		{"neutral bases too close", func(f *MapFile) {
+			f.NeutralBases = append(f.NeutralBases, MapFileNeutralBase{X: 300, Y: 2000})
+		}, "neutral bases 0 and 1"},
+		{"building inside the core", func(f *MapFile) { f.NeutralBases[0].Buildings[0].X = 40 }, "building 0 is"},
+		{"building beyond the border", func(f *MapFile) { f.NeutralBases[0].Buildings[0].X = 400 }, "building 0 is"},
+		{"overlapping buildings", func(f *MapFile) { f.NeutralBases[0].Buildings[1] = f.NeutralBases[0].Buildings[0] }, "buildings 0 and 1 overlap"},
+		{"bush outside the map", func(f *MapFile) { f.Bushes[0].X = 31000 }, "bush 0 is farther"},
+		{"rock on a spawn", func(f *MapFile) { f.Rocks[0].X, f.Rocks[0].Y = 2000, 300 }, "rock 0 overlaps the spawn protection of spawn 1"},
+		{"rock on a neutral base", func(f *MapFile) { f.Rocks[0].Y = 1800 }, "rock 0 overlaps neutral base 0"},
+		{"overlapping rocks", func(f *MapFile) { f.Rocks = append(f.Rocks, MapFileRock{X: 30, Y: -3000, Size: 50}) }, "rocks 0 and 1 overlap"},
+		{"bush on a rock", func(f *MapFile) { f.Bushes[0].Y = -2900 }, "bush 0 overlaps rock 0"},
+		{"bush on a spawn", func(f *MapFile) { f.Bushes[0] = MapFilePosition{X: 2000, Y: 400} }, "bush 0 overlaps the spawn protection of spawn 1"},
+	}
+
+	for _, tt := range tests {
+		file := playableMap()
+		tt.change(&file)
+		gameMap, err := file.toGameMap()
+		if err == nil {
+			err = ValidateMap(gameMap)
+		}
+
+		switch {
+		case tt.problem == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.problem != "" && err == nil:
+			t.Errorf("%s: map passed validation", tt.name)
+		case tt.problem != "" && !strings.Contains(err.Error(), tt.problem):
+			t.Errorf("%s: error %q does not mention %q", tt.name, err, tt.problem)
+		}
+	}
+}
+
+func TestMapFileNames(t *testing.T) {
+	file := playableMap()
+	file.NeutralBases[0].Type = "castle"
+	file.NeutralBases[0].Buildings[0].Type = "moat"
+	file.Rocks[0].Shape = "star"
+	file.Rocks = append(file.Rocks, MapFileRock{Shape: "circle", Size: MAP_MAX_ROCK_SIZE + 1})
+
+	_, err := file.toGameMap()
+	if err == nil {
+		t.Fatal("unknown names were accepted")
+	}
+	for _, problem := range []string{`unknown type "castle"`, `unknown type "moat"`, `unknown shape "star"`, "rock 1: size"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("error does not mention %s: %v", problem, err)
+		}
+	}
+
+	gameMap, err := playableMap().toGameMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gameMap.Info.Layout != MAP_LAYOUT_FILE || gameMap.Info.ExpectedPlayers != 2 || gameMap.Rocks[0].Shape != ShapeHexagon {
+		t.Errorf("map info %+v, rock shape %d", gameMap.Info, gameMap.Rocks[0].Shape)
+	}
+	if want := int16(3000 + MAP_EDGE_MARGIN); gameMap.Info.Radius != want {
+		t.Errorf("map radius %d, expected %d beyond the farthest rock", gameMap.Info.Radius, want)
+	}
+}
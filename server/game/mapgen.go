@@ -0,0 +1,428 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// MapSettings selects the generated map, it is read once when the game starts
+type MapSettings struct {
+	Seed            int64 // Zero picks a random seed
+	Layout          MapLayout
+	ExpectedPlayers int    // The map provides at least this many spawn positions
+	File            string // Map file loaded instead of generating a map, see LoadMapFile
+}
+
+var MapConfig = MapSettings{
+	Layout:          MAP_LAYOUT_HEX_RING,
+	ExpectedPlayers: MAP_DEFAULT_EXPECTED_PLAYERS,
+}
+
+// MapInfo describes a generated map, the same seed, layout and player count generate the same map again.
+// Maps loaded from a file have the MAP_LAYOUT_FILE layout and a name instead of a seed
+type MapInfo struct {
+	Seed            int64
+	Layout          MapLayout
+	ExpectedPlayers int
+	Radius          int16 // Bushes and rocks are scattered within this distance of the centre
+	Name            string
+}
+
+// GameMap holds everything placed on the map before the first player joins
+type GameMap struct {
+	Info            MapInfo
+	PlayerPositions []PositionInt
+	NeutralBases    []MapNeutralBase
+	Bushes          []PositionInt
+	Rocks           []Rock
+}
+
+// MapNeutralBase places a neutral base on the map
+type MapNeutralBase struct {
+	Position  PositionInt
+	Type      NeutralBaseType
+	Buildings []NeutralBuilding // Empty uses the layout of the archetype
+}
+
+// layoutGenerator places the spawn positions and neutral bases of a layout, layouts may add rocks of their own
+type layoutGenerator func(rng *rand.Rand, expectedPlayers int) mapLayoutResult
+
+type mapLayoutResult struct {
+	playerPositions  []PositionInt
+	neutralPositions []PositionInt
+	rocks            []Rock
+}
+
+var mapLayouts = [MAP_LAYOUT_COUNT]struct {
+	name     string
+	generate layoutGenerator
+}{
+	MAP_LAYOUT_HEX_RING: {"hex", generateHexRingLayout},
+	MAP_LAYOUT_ISLANDS:  {"islands", generateIslandsLayout},
+	MAP_LAYOUT_LANES:    {"lanes", generateLanesLayout},
+	MAP_LAYOUT_POISSON:  {"poisson", generatePoissonLayout},
+}
+
+func (l MapLayout) String() string {
+	if l == MAP_LAYOUT_FILE {
+		return "file"
+	}
+	if int(l) < len(mapLayouts) {
+		return mapLayouts[l].name
+	}
+	return fmt.Sprintf("layout %d", l)
+}
+
+// ParseMapLayout returns the layout with the given name
+func ParseMapLayout(name string) (MapLayout, bool) {
+	for layout, entry := range mapLayouts {
+		if strings.EqualFold(name, entry.name) {
+			return MapLayout(layout), true
+		}
+	}
+	return 0, false
+}
+
+// GenerateMap builds a map from the settings, every random choice is drawn from the seed
+func GenerateMap(settings MapSettings) GameMap {
+	if settings.Seed == 0 {
+		settings.Seed = time.Now().UnixNano()
+	}
+	if int(settings.Layout) >= len(mapLayouts) {
+		settings.Layout = MAP_LAYOUT_HEX_RING
+	}
+	settings.ExpectedPlayers = min(max(settings.ExpectedPlayers, 1), MAX_PLAYERS)
+
+	rng := rand.New(rand.NewSource(settings.Seed))
+	layout := mapLayouts[settings.Layout].generate(rng, settings.ExpectedPlayers)
+
+	// The map reaches a margin beyond its outermost base
+	radius := 0.0
+	for _, positions := range [][]PositionInt{layout.playerPositions, layout.neutralPositions} {
+		for _, pos := range positions {
+			radius = max(radius, float64(pos.DistanceTo(PositionInt{})))
+		}
+	}
+	radius = min(radius+MAP_EDGE_MARGIN, MAP_MAX_RADIUS)
+
+	// Larger maps get more bushes and rocks, so their density stays the same
+	scale := (radius / MAP_SCATTER_REFERENCE_RADIUS) * (radius / MAP_SCATTER_REFERENCE_RADIUS)
+	numBushes := int(math.Round(MAP_BUSH_COUNT * scale))
+	numRocks := int(math.Round(MAP_ROCK_COUNT * scale))
+
+	bushes := generateBushes(rng, layout.playerPositions, layout.neutralPositions, radius, numBushes, MAP_BUSH_MIN_DISTANCE)
+	rocks := append(layout.rocks, generateRocks(rng, layout.playerPositions, layout.neutralPositions, radius, numRocks, MAP_ROCK_MIN_DISTANCE, ShapeHexagon)...)
+
+	neutralBases := make([]MapNeutralBase, len(layout.neutralPositions))
+	for i, pos := range layout.neutralPositions {
+		neutralBases[i] = MapNeutralBase{Position: pos, Type: getNeutralBaseType(pos, i)}
+	}
+
+	return GameMap{
+		Info: MapInfo{
+			Seed:            settings.Seed,
+			Layout:          settings.Layout,
+			ExpectedPlayers: settings.ExpectedPlayers,
+			Radius:          int16(radius),
+		},
+		PlayerPositions: layout.playerPositions,
+		NeutralBases:    neutralBases,
+		Bushes:          bushes,
+		Rocks:           rocks,
+	}
+}
+
+// generateHexRingLayout places spawns on the corners of a honeycomb with a neutral base in the centre of every hexagon.
+// One ring of hexagons around the central one is the classic map, more players add more rings
+func generateHexRingLayout(_ *rand.Rand, expectedPlayers int) mapLayoutResult {
+	hexagonSize := float64(MAP_SPAWN_SPACING)
+
+	// A honeycomb with n rings around the central hexagon has 6(n+1)² corners
+	rings := 1
+	for 6*(rings+1)*(rings+1) < expectedPlayers {
+		rings++
+	}
+
+	var result mapLayoutResult
+	var playerPositions []PositionInt
+
+	// Axial coordinates of flat topped hexagons, the central hexagon comes last
+	for q := -rings; q <= rings; q++ {
+		for r := max(-rings, -q-rings); r <= min(rings, -q+rings); r++ {
+			if q == 0 && r == 0 {
+				continue
+			}
+			x := hexagonSize * 1.5 * float64(q)
+			y := hexagonSize * math.Sqrt(3) * (float64(r) + float64(q)/2)
+			hexagonCenter := PositionInt{X: int16(math.Round(x)), Y: int16(math.Round(y))}
+
+			playerPositions = append(playerPositions, generateHexagon(hexagonCenter, hexagonSize)...)
+			result.neutralPositions = append(result.neutralPositions, hexagonCenter)
+		}
+	}
+	playerPositions = append(playerPositions, generateHexagon(PositionInt{}, hexagonSize)...)
+	result.neutralPositions = append(result.neutralPositions, PositionInt{})
+
+	// Neighbouring hexagons share corners, rounding may put them a unit apart
+	for _, pos := range playerPositions {
+		isUnique := true
+		for _, uniquePos := range result.playerPositions {
+			if isTooClose(pos, uniquePos, 1, 1) {
+				isUnique = false
+				break
+			}
+		}
+		if isUnique {
+			result.playerPositions = append(result.playerPositions, pos)
+		}
+	}
+
+	return result
+}
+
+// generateIslandsLayout places small groups of spawns around a neutral base each. The islands sit on a circle
+// around the citadel, separated by open ground and a rock ridge
+func generateIslandsLayout(rng *rand.Rand, expectedPlayers int) mapLayoutResult {
+	const spawnsPerIsland = 4
+	islandRadius := MAP_SPAWN_SPACING * 0.6 // Distance of the spawns to the neutral base of their island
+	gap := MAP_SPAWN_SPACING * 1.5          // Open ground between neighbouring islands
+
+	islands := max(3, (expectedPlayers+spawnsPerIsland-1)/spawnsPerIsland)
+	ringRadius := max(MAP_SPAWN_SPACING*2, (2*islandRadius+gap)/(2*math.Sin(math.Pi/float64(islands))))
+
+	result := mapLayoutResult{neutralPositions: []PositionInt{{}}}
+	offset := rng.Float64() * 2 * math.Pi
+	step := 2 * math.Pi / float64(islands)
+
+	for i := 0; i < islands; i++ {
+		center := polarPosition(PositionFloat{}, ringRadius, offset+step*float64(i))
+		result.neutralPositions = append(result.neutralPositions, FloatToInt(center))
+
+		rotation := rng.Float64() * 2 * math.Pi
+		for j := 0; j < spawnsPerIsland; j++ {
+			spawn := polarPosition(center, islandRadius, rotation+2*math.Pi*float64(j)/spawnsPerIsland)
+			result.playerPositions = append(result.playerPositions, FloatToInt(spawn))
+		}
+	}
+
+	// A ridge runs across the open ground between every two neighbouring islands
+	for i := 0; i < islands; i++ {
+		angle := offset + step*(float64(i)+0.5)
+		from := polarPosition(PositionFloat{}, ringRadius-islandRadius, angle)
+		to := polarPosition(PositionFloat{}, ringRadius+islandRadius, angle)
+		result.rocks = append(result.rocks, generateRockRidge(rng, from, to, 0, result.neutralPositions)...)
+	}
+
+	return result
+}
+
+// generateLanesLayout places spawns along both sides of parallel lanes, with a neutral base in the middle and at both
+// ends of every lane. Rock ridges with a few openings separate the lanes
+func generateLanesLayout(rng *rand.Rand, expectedPlayers int) mapLayoutResult {
+	const spawnsPerLane = 8
+	spacing := float64(MAP_SPAWN_SPACING)
+
+	lanes := min(max((expectedPlayers+spawnsPerLane-1)/spawnsPerLane, 2), 5)
+	perLane := (expectedPlayers + lanes - 1) / lanes
+	perHalf := (perLane + 1) / 2 // Spawns on each side of the middle base
+	halfLength := spacing + float64(perHalf-1)*spacing/2 + spacing
+
+	var result mapLayoutResult
+	laneY := func(lane int) float64 {
+		return (float64(lane) - float64(lanes-1)/2) * 2 * spacing
+	}
+
+	for lane := 0; lane < lanes; lane++ {
+		y := laneY(lane)
+		for _, x := range []float64{0, -halfLength, halfLength} {
+			result.neutralPositions = append(result.neutralPositions, FloatToInt(PositionFloat{X: float32(x), Y: float32(y)}))
+		}
+
+		// Spawns zigzag away from the middle, alternating between both halves of the lane
+		for i := 0; i < perLane; i++ {
+			side := 1.0
+			if i%2 == 1 {
+				side = -1.0
+			}
+			step := i / 2
+			offset := spacing / 2
+			if step%2 == 1 {
+				offset = -offset
+			}
+			x := side * (spacing + float64(step)*spacing/2)
+			result.playerPositions = append(result.playerPositions, FloatToInt(PositionFloat{X: float32(x), Y: float32(y + offset)}))
+		}
+	}
+
+	// With an even number of lanes the citadel sits between the two middle lanes
+	if lanes%2 == 0 {
+		result.neutralPositions = append(result.neutralPositions, PositionInt{})
+	}
+
+	for lane := 0; lane < lanes-1; lane++ {
+		y := float32((laneY(lane) + laneY(lane+1)) / 2)
+		from := PositionFloat{X: float32(-halfLength - spacing), Y: y}
+		to := PositionFloat{X: float32(halfLength + spacing), Y: y}
+		result.rocks = append(result.rocks, generateRockRidge(rng, from, to, 2, result.neutralPositions)...)
+	}
+
+	return result
+}
+
+// generatePoissonLayout spreads bases randomly with at least the spawn spacing between any two. The citadel is
+// the first sample, the other neutral bases are picked as far from each other as possible
+func generatePoissonLayout(rng *rand.Rand, expectedPlayers int) mapLayoutResult {
+	spacing := float64(MAP_SPAWN_SPACING)
+	neutralCount := max(3, expectedPlayers/4)
+	sites := expectedPlayers + neutralCount
+
+	// Poisson-disc sampling fills about 0.65 samples per spacing², the radius grows if a seed falls short
+	radius := math.Sqrt(float64(sites) * spacing * spacing / (0.65 * math.Pi))
+	var samples []PositionFloat
+	for attempt := 0; attempt < 5; attempt++ {
+		samples = poissonDiscSamples(rng, radius, spacing)
+		if len(samples) >= sites {
+			break
+		}
+		radius *= 1.1
+	}
+
+	isNeutral := make([]bool, len(samples))
+	isNeutral[0] = true
+	neutrals := []PositionFloat{samples[0]}
+	for len(neutrals) < min(neutralCount, len(samples)) {
+		farthest, farthestDistance := -1, float32(-1)
+		for i, sample := range samples {
+			if isNeutral[i] {
+				continue
+			}
+			distance := float32(math.MaxFloat32)
+			for _, neutral := range neutrals {
+				distance = min(distance, sample.DistanceTo(neutral))
+			}
+			if distance > farthestDistance {
+				farthest, farthestDistance = i, distance
+			}
+		}
+		isNeutral[farthest] = true
+		neutrals = append(neutrals, samples[farthest])
+	}
+
+	var result mapLayoutResult
+	for i, sample := range samples {
+		if isNeutral[i] {
+			result.neutralPositions = append(result.neutralPositions, FloatToInt(sample))
+		} else {
+			result.playerPositions = append(result.playerPositions, FloatToInt(sample))
+		}
+	}
+	return result
+}
+
+// poissonDiscSamples returns points within the radius that are at least spacing apart, using Bridson's algorithm.
+// The first point is the centre
+func poissonDiscSamples(rng *rand.Rand, radius float64, spacing float64) []PositionFloat {
+	const candidates = 30 // Attempts around an active sample before it is retired
+
+	cellSize := spacing / math.Sqrt2 // A cell holds at most one sample
+	cellOf := func(p PositionFloat) [2]int {
+		return [2]int{int(math.Floor(float64(p.X) / cellSize)), int(math.Floor(float64(p.Y) / cellSize))}
+	}
+
+	samples := []PositionFloat{{}}
+	grid := map[[2]int]int{cellOf(samples[0]): 0}
+	active := []int{0}
+
+	isFarEnough := func(candidate PositionFloat) bool {
+		cell := cellOf(candidate)
+		for dx := -2; dx <= 2; dx++ {
+			for dy := -2; dy <= 2; dy++ {
+				if index, ok := grid[[2]int{cell[0] + dx, cell[1] + dy}]; ok && float64(samples[index].DistanceTo(candidate)) < spacing {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	for len(active) > 0 {
+		i := rng.Intn(len(active))
+		origin := samples[active[i]]
+
+		found := false
+		for attempt := 0; attempt < candidates; attempt++ {
+			candidate := polarPosition(origin, spacing*(1+rng.Float64()), rng.Float64()*2*math.Pi)
+			if float64(candidate.DistanceTo(PositionFloat{})) > radius || !isFarEnough(candidate) {
+				continue
+			}
+
+			grid[cellOf(candidate)] = len(samples)
+			active = append(active, len(samples))
+			samples = append(samples, candidate)
+			found = true
+			break
+		}
+
+		if !found {
+			active[i] = active[len(active)-1]
+			active = active[:len(active)-1]
+		}
+	}
+
+	return samples
+}
+
+// generateRockRidge lines rocks up between two points. Openings one spawn spacing wide are left at random points
+// and rocks too close to a base are left out
+func generateRockRidge(rng *rand.Rand, from, to PositionFloat, openings int, bases []PositionInt) []Rock {
+	length := float64(from.DistanceTo(to))
+	openingsAt := make([]float64, openings)
+	for i := range openingsAt {
+		openingsAt[i] = rng.Float64() * length
+	}
+
+	var rocks []Rock
+	for along := 0.0; along <= length; along += MAP_ROCK_RIDGE_SPACING {
+		inOpening := false
+		for _, opening := range openingsAt {
+			if math.Abs(along-opening) < MAP_SPAWN_SPACING/2 {
+				inOpening = true
+				break
+			}
+		}
+		if inOpening {
+			continue
+		}
+
+		t := float32(along / length)
+		position := PositionFloat{X: from.X + (to.X-from.X)*t, Y: from.Y + (to.Y-from.Y)*t}
+
+		nearBase := false
+		for _, base := range bases {
+			if position.DistanceTo(IntToFloat(base)) < MAP_ROCK_MIN_DISTANCE {
+				nearBase = true
+				break
+			}
+		}
+		if nearBase {
+			continue
+		}
+
+		size := rng.Intn(20) + 60
+		polygon := GeneratePolygon(ShapeHexagon, size, 0)
+		polygon.SetRotation(rng.Float64() * 2 * math.Pi)
+		polygon.Center = position
+		rocks = append(rocks, Rock{Polygon: polygon, Shape: ShapeHexagon, Size: size})
+	}
+	return rocks
+}
+
+func polarPosition(center PositionFloat, distance float64, angle float64) PositionFloat {
+	return PositionFloat{
+		X: center.X + float32(distance*math.Cos(angle)),
+		Y: center.Y + float32(distance*math.Sin(angle)),
+	}
+}
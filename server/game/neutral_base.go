@@ -9,7 +9,15 @@ import (
 type NeutralBase struct {
 	CapturedBy *Player
 	ID         ID
+	Type       NeutralBaseType
 	Base       *Base
+	Layout     []NeutralBuilding // Pre-built buildings of a map file, replaces the layout of the archetype
+
+	// Capture meter
+	CaptureProgress  float64 // Seconds of uncontested presence collected by the capturing player
+	CapturingPlayer  *Player
+	CaptureContested bool
+	CoreDownTime     float64 // Seconds since the core was destroyed
 	sync.RWMutex
 }
 
@@ -17,16 +25,65 @@ func (n *NeutralBase) GetBase() *Base {
 	return n.Base
 }
 
-func (n *NeutralBase) Captured(player *Player) {
+// Captured hands the base to player and returns the previous owner
+func (n *NeutralBase) Captured(player *Player) *Player {
+	n.Lock()
+	previous := n.CapturedBy
+	n.CapturedBy = player
+	n.resetCapture()
+	n.Unlock()
+
 	n.Base.Health.Reset()
-	if player != nil {
-		n.Clear()
-		PopulateNeutralBase(n)
-	}
+	n.Clear()
+	PopulateNeutralBase(n)
+	return previous
+}
 
+// Release returns the base to the neutral side if player still holds it. A player leaving
+// the game races with the capture loop, which may already have handed the base to someone else
+func (n *NeutralBase) Release(player *Player) bool {
 	n.Lock()
-	n.CapturedBy = player
+	if n.CapturedBy != player {
+		n.Unlock()
+		return false
+	}
+	n.CapturedBy = nil
+	n.resetCapture()
 	n.Unlock()
+
+	n.Base.Health.Reset()
+	return true
+}
+
+// resetCapture clears the capture meter, the caller holds the LOCK
+func (n *NeutralBase) resetCapture() {
+	n.CaptureProgress = 0
+	n.CapturingPlayer = nil
+	n.CaptureContested = false
+	n.CoreDownTime = 0
+}
+
+// GetCaptureProgress returns the capture meter in percent, the capturing player and if the base is contested
+func (n *NeutralBase) GetCaptureProgress() (byte, *Player, bool) {
+	n.RLock()
+	defer n.RUnlock()
+
+	progress := math.Min(n.CaptureProgress/NEUTRAL_BASE_CAPTURE_TIME, 1)
+	return byte(progress * 100), n.CapturingPlayer, n.CaptureContested
+}
+
+// ResetCaptureBy clears the capture meter if the given player is capturing the base
+func (n *NeutralBase) ResetCaptureBy(player *Player) bool {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.CapturingPlayer != player {
+		return false
+	}
+
+	n.CaptureProgress = 0
+	n.CapturingPlayer = nil
+	return true
 }
 
 func (n *NeutralBase) Clear() {
@@ -50,54 +107,168 @@ func (n *NeutralBase) GetID() ID {
 	return n.ID
 }
 
+// NeutralBuildingRing places a number of identical buildings evenly on a circle around the base
+type NeutralBuildingRing struct {
+	Type        BuildingType
+	Variant     BuildingVariant
+	Count       int
+	Radius      float64
+	AngleOffset float64 // Rotation of the whole ring in radians
+}
+
+// NeutralBuilding places a single building, the offset is relative to the base position
+type NeutralBuilding struct {
+	Type    BuildingType
+	Variant BuildingVariant
+	Offset  PositionFloat
+}
+
+type NeutralBaseArchetype struct {
+	Health         uint16
+	Population     uint16
+	Generating     Generating
+	UnitSpawnSlots uint16 // Additional barracks that can be active at the same time
+	CaptureScore   uint32 // One-time score for capturing the base
+	Layout         []NeutralBuildingRing
+}
+
+var neutralBaseArchetypes = map[NeutralBaseType]NeutralBaseArchetype{
+	POWER_OUTPOST: {
+		Health:       NEUTRAL_BASE_INITIAL_HEALTH,
+		Population:   NEUTRAL_BASE_POPULATION,
+		Generating:   Generating{Power: 3},
+		CaptureScore: 2000,
+		Layout: []NeutralBuildingRing{
+			{Type: WALL, Variant: SPIKE, Count: 22, Radius: NEUTRAL_BASE_MAX_BUILDING_RADIUS},
+		},
+	},
+	BARRACKS_OUTPOST: {
+		Health:         NEUTRAL_BASE_INITIAL_HEALTH,
+		Population:     NEUTRAL_BASE_POPULATION + 16,
+		UnitSpawnSlots: 2,
+		CaptureScore:   2000,
+		Layout: []NeutralBuildingRing{
+			{Type: WALL, Variant: SPIKE, Count: 22, Radius: NEUTRAL_BASE_MAX_BUILDING_RADIUS},
+			{Type: SIMPLE_TURRET, Variant: BASIC_BUILDING, Count: 3, Radius: 150},
+		},
+	},
+	FORTRESS: {
+		Health:       NEUTRAL_BASE_INITIAL_HEALTH * 2,
+		Population:   NEUTRAL_BASE_POPULATION,
+		CaptureScore: 5000,
+		Layout: []NeutralBuildingRing{
+			{Type: WALL, Variant: SPIKE, Count: 22, Radius: NEUTRAL_BASE_MAX_BUILDING_RADIUS},
+			{Type: SIMPLE_TURRET, Variant: RAPID_TURRET, Count: 6, Radius: 170},
+			{Type: SNIPER_TURRET, Variant: BASIC_BUILDING, Count: 2, Radius: 115, AngleOffset: math.Pi / 2},
+		},
+	},
+	CITADEL: {
+		Health:         NEUTRAL_BASE_INITIAL_HEALTH * 3,
+		Population:     NEUTRAL_BASE_POPULATION * 2,
+		Generating:     Generating{Power: 3},
+		UnitSpawnSlots: 1,
+		CaptureScore:   10000,
+		Layout: []NeutralBuildingRing{
+			{Type: WALL, Variant: BOULDER, Count: 24, Radius: NEUTRAL_BASE_MAX_BUILDING_RADIUS},
+			{Type: SIMPLE_TURRET, Variant: GATLING_TURRET, Count: 6, Radius: 185, AngleOffset: math.Pi / 6},
+			{Type: SNIPER_TURRET, Variant: SEMI_AUTOMATIC_SNIPER, Count: 4, Radius: 115},
+		},
+	},
+}
+
+func GetNeutralBaseArchetype(neutralBaseType NeutralBaseType) (NeutralBaseArchetype, bool) {
+	archetype, ok := neutralBaseArchetypes[neutralBaseType]
+	return archetype, ok
+}
+
+// GetArchetype returns the archetype of the neutral base, falling back to a power outpost
+func (n *NeutralBase) GetArchetype() NeutralBaseArchetype {
+	archetype, ok := GetNeutralBaseArchetype(n.Type)
+	if !ok {
+		return neutralBaseArchetypes[POWER_OUTPOST]
+	}
+	return archetype
+}
+
+// Buildings places the rings of the layout, building by building
+func (a NeutralBaseArchetype) Buildings() []NeutralBuilding {
+	const fullCircleAngle = 2 * math.Pi
+
+	var buildings []NeutralBuilding
+	for _, ring := range a.Layout {
+		angleIncrement := fullCircleAngle / float64(ring.Count)
+		for i := 0; i < ring.Count; i++ {
+			angle := float64(i)*angleIncrement + ring.AngleOffset
+
+			buildings = append(buildings, NeutralBuilding{
+				Type:    ring.Type,
+				Variant: ring.Variant,
+				Offset: PositionFloat{
+					X: float32(math.Round(ring.Radius * math.Cos(angle))),
+					Y: float32(math.Round(ring.Radius * math.Sin(angle))),
+				},
+			})
+		}
+	}
+	return buildings
+}
+
 func PopulateNeutralBase(neutral *NeutralBase) {
-	const (
-		spikeWallRadius = NEUTRAL_BASE_MAX_BUILDING_RADIUS
-		numWalls        = 22
-		fullCircleAngle = 2 * math.Pi
-	)
-
-	// Place walls
-	angleIncrementWalls := fullCircleAngle / float64(numWalls)
-	for i := 0; i < numWalls; i++ {
-		angle := float64(i) * angleIncrementWalls
-		radius := float64(spikeWallRadius)
-
-		buildingX := float32(neutral.Base.Position.X) + float32(math.Round(radius*math.Cos(angle)))
-		buildingY := float32(neutral.Base.Position.Y) + float32(math.Round(radius*math.Sin(angle)))
+	layout := neutral.Layout
+	if len(layout) == 0 {
+		layout = neutral.GetArchetype().Buildings()
+	}
+
+	for _, building := range layout {
+		buildingX := float32(neutral.Base.Position.X) + building.Offset.X
+		buildingY := float32(neutral.Base.Position.Y) + building.Offset.Y
 		position := PositionFloat{X: buildingX, Y: buildingY}
 
-		buildingID, ok := neutral.Base.AvailableBuildingIDs.getNextAvailableID()
-		if !ok {
-			log.Println("No available building IDs for neutral base")
+		if !addNeutralBuilding(neutral, building.Type, building.Variant, position) {
 			return
 		}
-		buildingType := WALL
-		buildingVariant := SPIKE
-		polygon, ok := GetBuildingPolygon(buildingType)
-		if !ok {
-			log.Println("Polygon not found! %PopulateNeutralBase")
-			return
-		}
-		polygon.SetCenter(position)
-
-		// Calculate distance between base position and desired building position
-		dx := float64(position.X - float32(neutral.Base.Position.X))
-		dy := float64(position.Y - float32(neutral.Base.Position.Y))
-
-		rotationAngle := math.Atan2(dy, dx)
-		polygon.SetRotation(rotationAngle)
-
-		building := &Building{
-			ID:       buildingID,
-			Type:     buildingType,
-			Variant:  buildingVariant,
-			Position: position,
-			Polygon:  polygon,
-			Health:   GetInitialHealth(buildingType, buildingVariant),
-		}
-		neutral.Lock()
-		neutral.Base.Buildings[buildingID] = building
-		neutral.Unlock()
 	}
 }
+
+func addNeutralBuilding(neutral *NeutralBase, buildingType BuildingType, buildingVariant BuildingVariant, position PositionFloat) bool {
+	buildingID, generation, ok := neutral.Base.AvailableBuildingIDs.getNextAvailableIDWithGeneration()
+	if !ok {
+		log.Println("No available building IDs for neutral base")
+		return false
+	}
+
+	polygon, ok := GetBuildingPolygon(buildingType)
+	if !ok {
+		log.Println("Polygon not found! %PopulateNeutralBase")
+		return false
+	}
+	polygon.SetCenter(position)
+
+	// Calculate distance between base position and desired building position
+	dx := float64(position.X - float32(neutral.Base.Position.X))
+	dy := float64(position.Y - float32(neutral.Base.Position.Y))
+
+	rotationAngle := math.Atan2(dy, dx)
+	polygon.SetRotation(rotationAngle)
+
+	building := &Building{
+		Owner:      neutral,
+		ID:         buildingID,
+		Generation: generation,
+		Type:       buildingType,
+		Variant:    buildingVariant,
+		Position:   position,
+		Polygon:    polygon,
+		Health:     GetInitialHealth(buildingType, buildingVariant),
+		Preset:     true,
+	}
+	neutral.Base.Lock()
+	neutral.Base.Buildings[buildingID] = building
+	neutral.Base.Unlock()
+
+	if buildingType == SIMPLE_TURRET || buildingType == SNIPER_TURRET {
+		neutral.Base.AddBulletSpawning(building)
+	}
+
+	return true
+}
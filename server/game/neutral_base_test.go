@@ -0,0 +1,43 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDestroyedCoreRecovery(t *testing.T) {
+	neutral := &NeutralBase{Base: &Base{Health: Health{Max: NEUTRAL_BASE_INITIAL_HEALTH}}}
+	attacker := &Player{}
+	attacker.Units = map[ID]*Unit{1: {Player: attacker, ID: 1}}
+
+	// Nobody moved in yet, the core stays down for the recovery delay
+	for second := 1; second < NEUTRAL_BASE_CORE_RECOVERY_DELAY; second++ {
+		updateNeutralBaseCapture(neutral, nil, time.Second)
+	}
+	if neutral.Base.Health.IsAlive() {
+		t.Fatal("a destroyed core recovered before attackers could move in")
+	}
+
+	// A capture attempt keeps it down until the meter has decayed
+	updateNeutralBaseCapture(neutral, []*Player{attacker}, 2*time.Second)
+	updateNeutralBaseCapture(neutral, nil, time.Second)
+	if neutral.Base.Health.IsAlive() {
+		t.Fatal("the core recovered while the capture meter was still filled")
+	}
+	updateNeutralBaseCapture(neutral, nil, 3*time.Second)
+	if !neutral.Base.Health.IsAlive() || neutral.CoreDownTime != 0 {
+		t.Error("the core did not recover after the meter decayed")
+	}
+}
+
+func TestReleaseAfterRecapture(t *testing.T) {
+	leaving, captor := &Player{}, &Player{}
+	neutral := &NeutralBase{CapturedBy: captor, Base: &Base{}}
+
+	if neutral.Release(leaving) || neutral.CapturedBy != captor {
+		t.Error("a leaving player released a base someone else captured in the meantime")
+	}
+	if !neutral.Release(captor) || neutral.CapturedBy != nil {
+		t.Error("the owner could not release the base")
+	}
+}
@@ -0,0 +1,182 @@
+package game
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// PlayZoneSettings enables the shrinking play zone for the room, it is read once when the game starts
+type PlayZoneSettings struct {
+	Enabled    bool
+	MinPlayers int           // The first shrink is announced once this many players joined
+	StartDelay time.Duration // Between the announcement and the first shrink
+}
+
+var PlayZoneConfig = PlayZoneSettings{
+	MinPlayers: PLAY_ZONE_DEFAULT_MIN_PLAYERS,
+	StartDelay: PLAY_ZONE_DEFAULT_START_DELAY * time.Second,
+}
+
+// PlayZone is the safe circle around the centre of the map. Every phase shrinks it from Radius to TargetRadius
+// between ShrinkStartsAt and ShrinkEndsAt, bases outside take damage once the first shrink started
+type PlayZone struct {
+	Announced      bool // False while the room waits for players
+	Phase          int
+	Radius         float32
+	TargetRadius   float32
+	ShrinkStartsAt time.Time
+	ShrinkEndsAt   time.Time
+}
+
+var (
+	playZone      PlayZone
+	playZoneMutex sync.RWMutex
+)
+
+// RadiusAt returns the radius of the zone at the given time
+func (z PlayZone) RadiusAt(now time.Time) float32 {
+	if now.Before(z.ShrinkStartsAt) {
+		return z.Radius
+	}
+	if !now.Before(z.ShrinkEndsAt) {
+		return z.TargetRadius
+	}
+	progress := float32(now.Sub(z.ShrinkStartsAt)) / float32(z.ShrinkEndsAt.Sub(z.ShrinkStartsAt))
+	return z.Radius + (z.TargetRadius-z.Radius)*progress
+}
+
+// IsShrinking reports if the first shrink started, the zone only grows back after a winner was found
+func (z PlayZone) IsShrinking(now time.Time) bool {
+	return z.Announced && (z.Phase > 0 || !now.Before(z.ShrinkStartsAt))
+}
+
+// GetPlayZone returns the current zone and if the play zone mode is enabled
+func GetPlayZone() (PlayZone, bool) {
+	playZoneMutex.RLock()
+	defer playZoneMutex.RUnlock()
+	return playZone, PlayZoneConfig.Enabled
+}
+
+// IsJoiningClosed reports if players can no longer join, the play zone mode closes the room while it shrinks
+func IsJoiningClosed() bool {
+	zone, enabled := GetPlayZone()
+	return enabled && zone.IsShrinking(time.Now())
+}
+
+func startPlayZoneLoop() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		State.RLock()
+		players := make([]*Player, 0, len(State.Players))
+		for _, player := range State.Players {
+			if !player.IsMarkedForRemoval() {
+				players = append(players, player)
+			}
+		}
+		neutrals := make([]*NeutralBase, 0, len(State.NeutralBases))
+		neutrals = append(neutrals, State.NeutralBases...)
+		mapRadius := float32(State.Map.Radius)
+		State.RUnlock()
+
+		updatePlayZone(now, players, neutrals, mapRadius)
+	}
+}
+
+// updatePlayZone announces the phases of the zone, damages the bases outside and ends the round
+// once a single player is left
+func updatePlayZone(now time.Time, players []*Player, neutrals []*NeutralBase, mapRadius float32) {
+	playZoneMutex.Lock()
+	if !playZone.Announced {
+		if len(players) < PlayZoneConfig.MinPlayers {
+			playZoneMutex.Unlock()
+			return
+		}
+		playZone = PlayZone{
+			Announced:      true,
+			Radius:         mapRadius,
+			TargetRadius:   playZoneTargetRadius(0, mapRadius),
+			ShrinkStartsAt: now.Add(PlayZoneConfig.StartDelay),
+			ShrinkEndsAt:   now.Add(PlayZoneConfig.StartDelay + PLAY_ZONE_SHRINK_DURATION*time.Second),
+		}
+		zone := playZone
+		playZoneMutex.Unlock()
+
+		log.Printf("Play zone announced, shrinking starts in %s\n", PlayZoneConfig.StartDelay)
+		TriggerPlayZoneUpdateEvent(zone)
+		return
+	}
+
+	advanced := false
+	if !now.Before(playZone.ShrinkEndsAt) && playZone.Phase < PLAY_ZONE_PHASE_COUNT-1 {
+		phase := playZone.Phase + 1
+		playZone = PlayZone{
+			Announced:      true,
+			Phase:          phase,
+			Radius:         playZone.TargetRadius,
+			TargetRadius:   playZoneTargetRadius(phase, mapRadius),
+			ShrinkStartsAt: now.Add(PLAY_ZONE_PHASE_WAIT * time.Second),
+			ShrinkEndsAt:   now.Add((PLAY_ZONE_PHASE_WAIT + PLAY_ZONE_SHRINK_DURATION) * time.Second),
+		}
+		advanced = true
+	}
+	zone := playZone
+	playZoneMutex.Unlock()
+
+	if advanced {
+		TriggerPlayZoneUpdateEvent(zone)
+	}
+
+	if !zone.IsShrinking(now) {
+		return
+	}
+
+	// The last player standing wins the round, the zone then waits for players again
+	if len(players) <= 1 {
+		var winner *Player
+		if len(players) == 1 {
+			winner = players[0]
+		}
+		playZoneMutex.Lock()
+		playZone = PlayZone{}
+		zone = playZone
+		playZoneMutex.Unlock()
+
+		TriggerRoundWonEvent(winner)
+		TriggerPlayZoneUpdateEvent(zone)
+		return
+	}
+
+	radius := zone.RadiusAt(now)
+	damage := uint16(PLAY_ZONE_DAMAGE * (zone.Phase + 1))
+
+	for _, player := range players {
+		if player.HasProtection() || player.Base.Position.DistanceTo(PositionInt{}) <= radius {
+			continue
+		}
+
+		recordDamage(nil, player, player.Base.Health.Get(), damage)
+		isAlive := player.Base.TakeDamage(damage)
+		TriggerBaseHealthUpdateEvent(player.Base)
+		if !isAlive {
+			player.MarkForRemoval()
+			TriggerPlayerKilledEvent(player, nil, nil)
+		}
+	}
+
+	// Destroyed neutral cores are left to the capture meter
+	for _, neutral := range neutrals {
+		if !neutral.Base.Health.IsAlive() || neutral.Base.Position.DistanceTo(PositionInt{}) <= radius {
+			continue
+		}
+		neutral.Base.TakeDamage(damage)
+		TriggerBaseHealthUpdateEvent(neutral.Base)
+	}
+}
+
+// playZoneTargetRadius returns the radius the zone shrinks to in a phase
+func playZoneTargetRadius(phase int, mapRadius float32) float32 {
+	return mapRadius * float32(PLAY_ZONE_PHASE_COUNT-1-phase) / PLAY_ZONE_PHASE_COUNT
+}
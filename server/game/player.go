@@ -13,7 +13,7 @@ type MovementPackage struct {
 	Timestamp      time.Time     // Timestamp of the movement
 	TargetPosition PositionInt   // The target position for the movement
 	UnitPositions  []PositionInt // The positions of the units being moved
-	UnitIds        []byte
+	UnitIds        []ID
 }
 
 type Player struct {
@@ -27,8 +27,14 @@ type Player struct {
 	SkinID       ID
 
 	// Statistics
-	StartTime time.Time // For playtime
-	Kills     uint32
+	StartTime  time.Time // For playtime
+	Kills      uint32
+	Stats      SessionStats // Guarded by statsMutex
+	statsMutex sync.Mutex
+
+	// Achievements earned in this life (guarded by statsMutex)
+	Achievements        []AchievementID
+	AchievementProgress map[AchievementID]uint32
 	/* XP is calculated based on the score at the end of the run */
 
 	// Game State
@@ -49,6 +55,15 @@ type Player struct {
 	UnitSpawningLimit  Capacity
 	HasCommander       bool
 
+	// Control groups
+	ControlGroups [CONTROL_GROUP_COUNT][]ID
+	Formation     Formation
+
+	// Alliance (guarded by the alliance lock)
+	Alliance                *Alliance
+	AllianceRequests        map[ID]time.Time // Pending requests by requesting player ID
+	AllianceCooldownEndTime time.Time
+
 	// Script prevention
 	LastBuildingAction  time.Time // Timestamp of the last building upgraded/placed
 	BuildingActionCount uint32    // Counter to track the number of building actions
@@ -63,6 +78,12 @@ type Player struct {
 	// Camera
 	Camera Camera
 
+	// Enemy units and bases first checked between two visibility updates and found hidden, the next
+	// update reveals them if they came into sight (guarded by visionMutex)
+	lateHiddenUnits map[*Unit]bool
+	lateHiddenBases map[*Player]bool // Keyed by the owner of the base
+	visionMutex     sync.Mutex
+
 	// Flags & Conditions
 	RemoveFlag bool // Flag to mark unit for removal
 
@@ -170,25 +191,31 @@ func (p *Player) GetProtectionEndTime() time.Time {
 }
 
 func (p *Player) AddCapturedNeutralBase(neutralBase *NeutralBase) {
-	p.Population.IncrementCapacity(NEUTRAL_BASE_POPULATION)
+	archetype := neutralBase.GetArchetype()
+	p.Population.IncrementCapacity(archetype.Population)
+	p.UnitSpawningLimit.IncrementMax(archetype.UnitSpawnSlots)
+
 	p.Lock()
 	defer p.Unlock()
+	p.Generating.Power += archetype.Generating.Power
 	p.CapturedNeutralBases = append(p.CapturedNeutralBases, neutralBase)
 }
 
 func (p *Player) RemoveCapturedNeutralBase(neutralBase *NeutralBase) {
-	p.Population.DecrementCapacity(NEUTRAL_BASE_POPULATION)
+	archetype := neutralBase.GetArchetype()
+	p.Population.DecrementCapacity(archetype.Population)
+	p.UnitSpawningLimit.DecrementMax(archetype.UnitSpawnSlots)
 
 	p.Lock()
 	defer p.Unlock()
 
+	if p.Generating.Power >= archetype.Generating.Power {
+		p.Generating.Power -= archetype.Generating.Power
+	}
+
+	// Buildings the player placed in the base are accounted for when the base is cleared for the next captor
 	for i, base := range p.CapturedNeutralBases {
 		if base == neutralBase {
-
-			for _, building := range neutralBase.Base.Buildings {
-				p.Base.decrementBuildingLimit(building.Type)
-			}
-
 			// Remove the base by slicing the array
 			p.CapturedNeutralBases = append(p.CapturedNeutralBases[:i], p.CapturedNeutralBases[i+1:]...)
 			break // Exit after removing the base
@@ -218,6 +245,7 @@ func (p *Player) RemoveProtection() {
 }
 
 func (p *Player) IncrementScore(value uint32) {
+	value *= ScoreMultiplier()
 	p.Lock()
 	p.Score += uint32(value)
 	p.Unlock()
@@ -274,6 +302,7 @@ func (p *Player) AddCommander() (*Unit, bool) {
 	p.HasCommander = true
 	p.Unlock()
 
+	p.recordUnitSpawned(COMMANDER)
 	p.AddUnitBulletSpawning(unit)
 
 	return unit, true
@@ -326,6 +355,8 @@ func (p *Player) AddUnit(unitType UnitType, unitVariant UnitVariant, barracks *B
 	p.Units[unitID] = unit
 	p.Unlock()
 
+	p.recordUnitSpawned(unitType)
+
 	return unit, true
 }
 
@@ -344,6 +375,7 @@ func (p *Player) RemoveUnit(unitID ID) bool {
 		p.HasCommander = false
 	}
 	delete(p.Units, unitID)
+	p.removeUnitFromControlGroups(unitID)
 	p.Unlock()
 
 	p.AvailableUnitIDs.returnID(unitID)
@@ -351,6 +383,69 @@ func (p *Player) RemoveUnit(unitID ID) bool {
 	return true // Unit was successfully removed
 }
 
+// SetControlGroup replaces the units of a control group, ignoring units the player doesn't own
+func (p *Player) SetControlGroup(group byte, unitIDs []ID) ([]ID, bool) {
+	if int(group) >= CONTROL_GROUP_COUNT {
+		return nil, false
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	groupUnits := make([]ID, 0, len(unitIDs))
+	for _, unitID := range unitIDs {
+		if _, ok := p.Units[unitID]; ok {
+			groupUnits = append(groupUnits, unitID)
+		}
+	}
+
+	p.ControlGroups[group] = groupUnits
+	return groupUnits, true
+}
+
+// GetControlGroup returns a copy of the unit IDs stored in a control group
+func (p *Player) GetControlGroup(group byte) ([]ID, bool) {
+	if int(group) >= CONTROL_GROUP_COUNT {
+		return nil, false
+	}
+
+	p.RLock()
+	defer p.RUnlock()
+
+	groupUnits := make([]ID, len(p.ControlGroups[group]))
+	copy(groupUnits, p.ControlGroups[group])
+	return groupUnits, true
+}
+
+// removeUnitFromControlGroups drops a unit from every control group, the caller must hold the lock
+func (p *Player) removeUnitFromControlGroups(unitID ID) {
+	for group, groupUnits := range p.ControlGroups {
+		for i, id := range groupUnits {
+			if id == unitID {
+				p.ControlGroups[group] = append(groupUnits[:i], groupUnits[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (p *Player) SetFormation(formation Formation) bool {
+	if !IsValidFormation(formation) {
+		return false
+	}
+
+	p.Lock()
+	p.Formation = formation
+	p.Unlock()
+	return true
+}
+
+func (p *Player) GetFormation() Formation {
+	p.RLock()
+	defer p.RUnlock()
+	return p.Formation
+}
+
 func (p *Player) AddUnitSpawning(barracks *Building, setActive bool) bool {
 	// Get unit spawning data based on barracks variant
 	unitSpawning, ok := GetUnitSpawning(barracks.Variant)
@@ -482,6 +577,22 @@ func (p *Player) RemoveUnitBulletSpawning(unit *Unit) {
 	p.UnitBulletSpawning = updatedBulletSpawning
 }
 
+// IsNear checks if the player's base or any of their units is within the radius of the position
+func (p *Player) IsNear(position PositionFloat, radius float32) bool {
+	if p.Base.GetPosition().DistanceTo(FloatToInt(position)) <= radius {
+		return true
+	}
+
+	p.RLock()
+	defer p.RUnlock()
+	for _, unit := range p.Units {
+		if unit.IsWithinRadius(position, radius) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Player) GetScore() uint32 {
 	return p.Score
 }
@@ -55,3 +55,9 @@ func (p *Population) DecrementUsed(amount uint16) bool {
 	}
 	return false
 }
+
+func (p *Population) GetUsed() uint16 {
+	p.RLock()
+	defer p.RUnlock()
+	return p.Used
+}
@@ -5,16 +5,21 @@ import "sync"
 type Resource struct {
 	Current  uint16
 	Capacity uint16
+	revision uint32 // Changes whenever Current changes
 	sync.RWMutex
 }
 
 func (r *Resource) Increment(amount uint16) {
 	r.Lock()
 	defer r.Unlock()
+	previous := r.Current
 	r.Current += amount
 	if r.Current > r.Capacity {
 		r.Current = r.Capacity
 	}
+	if r.Current != previous {
+		r.revision++
+	}
 }
 
 func (r *Resource) Decrement(amount uint16) bool {
@@ -22,11 +27,28 @@ func (r *Resource) Decrement(amount uint16) bool {
 	defer r.Unlock()
 	if r.Current >= amount {
 		r.Current -= amount
+		if amount > 0 {
+			r.revision++
+		}
 		return true
 	}
 	return false
 }
 
+func (r *Resource) Get() uint16 {
+	r.RLock()
+	defer r.RUnlock()
+	return r.Current
+}
+
+// Revision returns a counter that changes with every change of the current amount.
+// Unlike the amount itself it also detects spending and regaining the same amount in between
+func (r *Resource) Revision() uint32 {
+	r.RLock()
+	defer r.RUnlock()
+	return r.revision
+}
+
 type Resources struct {
 	Power Resource
 }
@@ -48,6 +48,7 @@ type SkinData struct {
 	BaseColorHex  string `json:"base_color"` // Original Hex String
 	RequiredLevel int    `json:"required_level,omitempty"`
 	Cost          int    `json:"cost,omitempty"`
+	Achievement   string `json:"achievement,omitempty"` // Unlocked by earning the achievement with this key
 }
 
 // SkinCategory contains all skins grouped by category
@@ -113,6 +114,19 @@ func loadSkins(filePath string) {
 	log.Println("Skins loaded into memory successfully with parsed colors")
 }
 
+// GetAchievementSkinIDs returns the IDs of all skins unlocked by the given achievement
+func GetAchievementSkinIDs(achievementKey string) []int {
+	var skinIDs []int
+	for _, category := range [][]SkinData{AllSkins.Default, AllSkins.Veteran, AllSkins.Premium} {
+		for _, skin := range category {
+			if skin.Achievement != "" && skin.Achievement == achievementKey {
+				skinIDs = append(skinIDs, int(skin.ID))
+			}
+		}
+	}
+	return skinIDs
+}
+
 func GetDefaultSkinByName(name string) (SkinData, bool) {
 	nameLower := strings.ToLower(name) // Convert the input name to lowercase
 
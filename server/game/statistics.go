@@ -0,0 +1,140 @@
+package game
+
+// SessionStats counts what a player did during one life
+type SessionStats struct {
+	BuildingsPlaced      [BUILDING_TYPE_COUNT]uint32 `json:"buildingsPlaced"`
+	BuildingsUpgraded    [BUILDING_TYPE_COUNT]uint32 `json:"buildingsUpgraded"`
+	BuildingsDestroyed   [BUILDING_TYPE_COUNT]uint32 `json:"buildingsDestroyed"` // Enemy buildings destroyed by the player
+	UnitsSpawned         [UNIT_TYPE_COUNT]uint32     `json:"unitsSpawned"`
+	UnitsLost            [UNIT_TYPE_COUNT]uint32     `json:"unitsLost"`
+	DamageDealt          uint32                      `json:"damageDealt"`
+	DamageTaken          uint32                      `json:"damageTaken"`
+	NeutralBasesCaptured uint32                      `json:"neutralBasesCaptured"`
+	NeutralBasesLost     uint32                      `json:"neutralBasesLost"`
+	PeakPower            uint16                      `json:"peakPower"`
+	PeakPopulation       uint16                      `json:"peakPopulation"`
+}
+
+// Add accumulates the counters of another session, peaks keep the highest value
+func (s *SessionStats) Add(other SessionStats) {
+	for i := range s.BuildingsPlaced {
+		s.BuildingsPlaced[i] += other.BuildingsPlaced[i]
+		s.BuildingsUpgraded[i] += other.BuildingsUpgraded[i]
+		s.BuildingsDestroyed[i] += other.BuildingsDestroyed[i]
+	}
+	for i := range s.UnitsSpawned {
+		s.UnitsSpawned[i] += other.UnitsSpawned[i]
+		s.UnitsLost[i] += other.UnitsLost[i]
+	}
+	s.DamageDealt += other.DamageDealt
+	s.DamageTaken += other.DamageTaken
+	s.NeutralBasesCaptured += other.NeutralBasesCaptured
+	s.NeutralBasesLost += other.NeutralBasesLost
+	s.PeakPower = max(s.PeakPower, other.PeakPower)
+	s.PeakPopulation = max(s.PeakPopulation, other.PeakPopulation)
+}
+
+// GetSessionStats returns a snapshot of the player's session statistics
+func (p *Player) GetSessionStats() SessionStats {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	return p.Stats
+}
+
+func (p *Player) recordBuildingPlaced(buildingType BuildingType) {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	if int(buildingType) < BUILDING_TYPE_COUNT {
+		p.Stats.BuildingsPlaced[buildingType]++
+	}
+}
+
+func (p *Player) recordBuildingUpgraded(buildingType BuildingType) {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	if int(buildingType) < BUILDING_TYPE_COUNT {
+		p.Stats.BuildingsUpgraded[buildingType]++
+	}
+}
+
+func (p *Player) recordBuildingDestroyed(buildingType BuildingType) {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	if int(buildingType) < BUILDING_TYPE_COUNT {
+		p.Stats.BuildingsDestroyed[buildingType]++
+	}
+}
+
+func (p *Player) recordUnitSpawned(unitType UnitType) {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	if int(unitType) < UNIT_TYPE_COUNT {
+		p.Stats.UnitsSpawned[unitType]++
+	}
+}
+
+func (p *Player) recordUnitLost(unitType UnitType) {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	if int(unitType) < UNIT_TYPE_COUNT {
+		p.Stats.UnitsLost[unitType]++
+	}
+}
+
+func (p *Player) recordNeutralBaseCaptured() {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	p.Stats.NeutralBasesCaptured++
+}
+
+func (p *Player) recordNeutralBaseLost() {
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	p.Stats.NeutralBasesLost++
+}
+
+// recordPeaks samples the current power and population
+func (p *Player) recordPeaks() {
+	power := p.Resources.Power.Get()
+	population := p.Population.GetUsed()
+
+	p.statsMutex.Lock()
+	defer p.statsMutex.Unlock()
+	p.Stats.PeakPower = max(p.Stats.PeakPower, power)
+	p.Stats.PeakPopulation = max(p.Stats.PeakPopulation, population)
+}
+
+// recordDamage credits damage to the attacker and the victim, either of them may be nil.
+// Only the damage that could actually be absorbed by the remaining health is counted
+func recordDamage(attacker *Player, victim *Player, health uint16, damage uint16) {
+	if attacker == victim {
+		return
+	}
+
+	amount := uint32(min(health, damage))
+	if amount == 0 {
+		return
+	}
+
+	if attacker != nil {
+		attacker.statsMutex.Lock()
+		attacker.Stats.DamageDealt += amount
+		attacker.statsMutex.Unlock()
+	}
+	if victim != nil {
+		victim.statsMutex.Lock()
+		victim.Stats.DamageTaken += amount
+		victim.statsMutex.Unlock()
+	}
+}
+
+// getOwningPlayer returns the player controlling a base owner, a neutral base is controlled by its captor
+func getOwningPlayer(owner Owner) *Player {
+	switch o := owner.(type) {
+	case *Player:
+		return o
+	case *NeutralBase:
+		return o.CapturedBy
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package game
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var serverStartTime = time.Now()
+
+var (
+	entityTick     atomic.Uint32 // Number of the entity update in progress or last finished
+	entityTickTime atomic.Uint32 // Server time the current entity update started at
+)
+
+// ServerTime returns the milliseconds since the server started
+func ServerTime() uint32 {
+	return uint32(time.Since(serverStartTime).Milliseconds())
+}
+
+// CurrentTick returns the number of the entity update in progress or last finished
+func CurrentTick() uint32 {
+	return entityTick.Load()
+}
+
+// advanceEntityTick starts the next entity update and returns its number
+func advanceEntityTick() uint32 {
+	entityTickTime.Store(ServerTime())
+	return entityTick.Add(1)
+}
+
+// nextEntityTick returns the first entity update an entity added now takes part in
+// and the server time it is expected to start at
+func nextEntityTick() (uint32, uint32) {
+	return entityTick.Load() + 1, entityTickTime.Load() + ENTITY_UPDATE_INTERVAL
+}
@@ -4,6 +4,7 @@ import (
 	"math"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,6 +29,8 @@ type Unit struct {
 	LastTargetPositionUpdate  time.Time
 	ExactTargetPositonRequest PositionInt
 	RemoveFlag                bool // Flag to mark unit for removal
+	history                   positionHistory
+	inBush                    atomic.Bool // Set by the visibility update
 	sync.RWMutex
 }
 
@@ -51,6 +54,13 @@ func (u *Unit) GetPosition() PositionFloat {
 	return u.Position
 }
 
+// movement returns the position and target of a unit that may be moved by another goroutine
+func (u *Unit) movement() (PositionFloat, PositionFloat) {
+	u.RLock()
+	defer u.RUnlock()
+	return u.Position, u.TargetPosition
+}
+
 func (u *Unit) TakeDamage(amount uint16) bool {
 	u.Health.Decrement(amount)
 	return u.Health.IsAlive()
@@ -87,30 +97,108 @@ func (u *Unit) IsExplosiv() bool {
 func easeOut(t float64) float64 {
 	return 1 - math.Pow(1-t, 3)
 }
+
+// hasArrived reports whether a unit at position is resting on its target
+func hasArrived(position, target PositionFloat) bool {
+	dx := target.X - position.X
+	dy := target.Y - position.Y
+	return dx*dx+dy*dy <= 1.0
+}
+
+// calculateSeparation returns the boids-style separation push away from overlapping friendly units
+// and whether one of those neighbours has already arrived at its target
+func (u *Unit) calculateSeparation(units []*Unit) (float64, float64, bool) {
+	var pushX, pushY float64
+	crowdedByArrived := false
+	position, _ := u.movement()
+
+	for _, other := range units {
+		if other == u || other.IsMarkedForRemoval() {
+			continue
+		}
+
+		// Neighbours are moved by their own update, read them under their LOCK
+		otherPosition, otherTarget := other.movement()
+		dx := float64(position.X - otherPosition.X)
+		dy := float64(position.Y - otherPosition.Y)
+		separationRadius := float64(u.Size+other.Size) * UNIT_SEPARATION_RADIUS_FACTOR
+
+		distanceSquared := dx*dx + dy*dy
+		if distanceSquared >= separationRadius*separationRadius {
+			continue
+		}
+
+		distance := math.Sqrt(distanceSquared)
+		if distance == 0 {
+			// Units are stacked exactly, spread them by ID so both don't pick the same direction
+			angle := float64(u.ID) * 2.399963 // Golden angle in radians
+			dx, dy, distance = math.Cos(angle), math.Sin(angle), 1
+		}
+
+		// The closer the neighbour the stronger the push
+		strength := (separationRadius - distance) / separationRadius
+		pushX += dx / distance * strength
+		pushY += dy / distance * strength
+
+		if hasArrived(otherPosition, otherTarget) {
+			crowdedByArrived = true
+		}
+	}
+
+	return pushX, pushY, crowdedByArrived
+}
+
 func (u *Unit) UpdatePosition(deltaTime time.Duration, units []*Unit) bool {
+	separationX, separationY, crowdedByArrived := u.calculateSeparation(units)
+
 	u.Lock()
 	defer u.Unlock()
 
+	// Calculate base distance to move in this frame
+	// Use a fixed delta time to avoid frame rate dependency
+	distanceToMove := u.Speed * float64(deltaTime) / float64(time.Second)
+
+	// Scale the separation push by the unit speed and never let it exceed a full step
+	separationX *= distanceToMove * UNIT_SEPARATION_WEIGHT
+	separationY *= distanceToMove * UNIT_SEPARATION_WEIGHT
+	separationLength := math.Sqrt(separationX*separationX + separationY*separationY)
+	if separationLength > distanceToMove {
+		separationX *= distanceToMove / separationLength
+		separationY *= distanceToMove / separationLength
+		separationLength = distanceToMove
+	}
+	hasSeparation := separationLength >= UNIT_MIN_SEPARATION_MOVEMENT
+
 	// Calculate distance to target position
 	dx := float64(u.TargetPosition.X - u.Position.X)
 	dy := float64(u.TargetPosition.Y - u.Position.Y)
 	distanceSquared := dx*dx + dy*dy
+	distance := math.Sqrt(distanceSquared)
+
+	// Arrival: stop short of a target that is already occupied by units which got there first
+	isCrowdedTarget := crowdedByArrived && distance <= float64(u.Size)*UNIT_ARRIVAL_RADIUS_FACTOR
 
 	// Snap to target position when close enough
-	if distanceSquared <= 1.0 {
-		u.Position = u.TargetPosition
-		return false
+	if distanceSquared <= 1.0 || isCrowdedTarget {
+		if distanceSquared <= 1.0 {
+			u.Position = u.TargetPosition
+		}
+		if !hasSeparation {
+			u.TargetPosition = u.Position
+			return false
+		}
+
+		// Resting units make room for each other and settle where they got pushed to
+		u.Position.X += float32(separationX)
+		u.Position.Y += float32(separationY)
+		u.TargetPosition = u.Position
+		return true
 	}
 
 	// Normalize dx and dy to get unit direction vector
-	distance := math.Sqrt(distanceSquared)
 	unitVectorX := dx / distance
 	unitVectorY := dy / distance
 
-	// Calculate base distance to move in this frame
-	// Use a fixed delta time to avoid frame rate dependency
-	distanceToMove := u.Speed * float64(deltaTime) / float64(time.Second)
-
 	// Apply ease-out only when within a threshold distance to the target
 	easeThreshold := 100.0
 	minMovementThreshold := 0.05 // Minimum movement threshold to consider easing
@@ -131,9 +219,13 @@ func (u *Unit) UpdatePosition(deltaTime time.Duration, units []*Unit) bool {
 		distanceToMove = distance
 	}
 
-	// Calculate new position
+	// Calculate new position, steering away from crowded neighbours
 	newX := float64(u.Position.X) + unitVectorX*distanceToMove
 	newY := float64(u.Position.Y) + unitVectorY*distanceToMove
+	if hasSeparation {
+		newX += separationX
+		newY += separationY
+	}
 
 	// Update position
 	u.Position.X = float32(newX)
@@ -301,6 +393,14 @@ var unitTypes = map[UnitType]map[UnitVariant]UnitStats{
 	},
 }
 
+// Sight ranges under fog of war, units see at least as far as they shoot
+var unitSightRanges = map[UnitType]int{
+	SOLDIER:    450,
+	TANK:       500,
+	SIEGE_TANK: 550,
+	COMMANDER:  700,
+}
+
 var unitPolygons = map[UnitType]map[UnitVariant]Polygon{
 	SOLDIER: {
 		BASIC_UNIT:          GeneratePolygon(ShapeTriangle, getSize(SOLDIER, BASIC_UNIT), 0),
@@ -369,6 +469,10 @@ func GetUnitPolygon(unitType UnitType, variant UnitVariant) (Polygon, bool) {
 	return polygon, ok
 }
 
+func GetUnitSightRange(unitType UnitType) int {
+	return unitSightRanges[unitType]
+}
+
 func GetUnitStats(unitType UnitType, variant UnitVariant) (UnitStats, bool) {
 	stats, ok := unitTypes[unitType][variant]
 	return stats, ok
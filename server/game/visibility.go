@@ -0,0 +1,458 @@
+package game
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Units inside a bush are hidden from their enemies. A player sees into a bush when one of its own units or
+// a unit of an ally is within BUSH_REVEAL_RADIUS of the hidden unit.
+//
+// Under fog of war a player only sees what its base, buildings and units see, together with those of its
+// allies. Visibility is computed once per entity update into a snapshot that is read without locks, so
+// broadcasts and turret targeting agree on what a player can see. Entities the update has not looked at yet,
+// like units that just spawned, are checked against the vision grid of the snapshot when they are first sent
+
+// FogOfWar is set once per server, every server hosts a single room
+var FogOfWar bool
+
+type visionSource struct {
+	position PositionFloat
+	radius   float32
+}
+
+// visionGrid buckets the vision sources of every vision group by cell. Cells are as large as the longest
+// sight range plus the largest margin, so a position only has to check its own and the neighbouring cells
+type visionGrid map[interface{}]map[[2]int][]visionSource
+
+// visionSnapshot is what every player saw at the last entity update. It is replaced as a whole and never
+// changed afterwards, so reading it needs no locks
+type visionSnapshot struct {
+	grid   visionGrid // Only under fog of war
+	groups map[*Player]interface{}
+	views  map[*Player]*playerView
+}
+
+// playerView lists the enemy units and bases a player sees or not. Units and bases of the same vision group are not listed
+type playerView struct {
+	hiddenUnits map[*Unit]bool // Hidden in bushes or by fog of war
+	seenUnits   map[*Unit]bool // Only under fog of war
+	hiddenBases map[*Player]bool
+	seenBases   map[*Player]bool
+}
+
+var currentVision atomic.Pointer[visionSnapshot]
+
+// visionGroup returns what a player shares its vision with, its alliance or only itself
+func visionGroup(player *Player) interface{} {
+	allianceMutex.RLock()
+	defer allianceMutex.RUnlock()
+
+	if player.Alliance != nil {
+		return player.Alliance
+	}
+	return player
+}
+
+func visionCell(position PositionFloat) [2]int {
+	return [2]int{
+		int(math.Floor(float64(position.X) / VISION_GRID_CELL_SIZE)),
+		int(math.Floor(float64(position.Y) / VISION_GRID_CELL_SIZE)),
+	}
+}
+
+func (g visionGrid) add(group interface{}, position PositionFloat, radius int) {
+	cells, ok := g[group]
+	if !ok {
+		cells = make(map[[2]int][]visionSource)
+		g[group] = cells
+	}
+	cell := visionCell(position)
+	cells[cell] = append(cells[cell], visionSource{position: position, radius: float32(radius)})
+}
+
+// sees reports if the group sees any point within the margin of the position
+func (g visionGrid) sees(group interface{}, position PositionFloat, margin float32) bool {
+	cells := g[group]
+	cell := visionCell(position)
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			for _, source := range cells[[2]int{cell[0] + dx, cell[1] + dy}] {
+				if source.position.DistanceTo(position) <= source.radius+margin {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// buildVisionGrid collects the vision of every base, building and unit
+func buildVisionGrid(players []*Player, unitsByPlayer map[*Player][]*Unit, groups map[*Player]interface{}) visionGrid {
+	grid := make(visionGrid)
+
+	addBase := func(group interface{}, base *Base, radius int) {
+		center := IntToFloat(base.Position)
+		grid.add(group, center, radius)
+
+		base.RLock()
+		defer base.RUnlock()
+		for _, building := range base.Buildings {
+			sight := GetBuildingSightRange(building.Type)
+			// Most buildings see nothing beyond the vision of their base
+			if building.Position.DistanceTo(center)+float32(sight) > float32(radius) {
+				grid.add(group, building.Position, sight)
+			}
+		}
+	}
+
+	for _, player := range players {
+		group := groups[player]
+		addBase(group, player.Base, PLAYER_BASE_VISION_RADIUS)
+
+		player.RLock()
+		neutrals := make([]*NeutralBase, 0, len(player.CapturedNeutralBases))
+		neutrals = append(neutrals, player.CapturedNeutralBases...)
+		player.RUnlock()
+		for _, neutral := range neutrals {
+			addBase(group, neutral.Base, NEUTRAL_BASE_VISION_RADIUS)
+		}
+
+		for _, unit := range unitsByPlayer[player] {
+			grid.add(group, unit.GetPosition(), GetUnitSightRange(unit.Type))
+		}
+	}
+	return grid
+}
+
+// isInBush reports if a position is covered by one of the bushes
+func isInBush(position PositionFloat, bushes []PositionInt) bool {
+	for _, bush := range bushes {
+		if position.DistanceTo(IntToFloat(bush)) <= BUSH_RADIUS {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHiddenInBush reports if the unit was inside a bush at the last entity update
+func (u *Unit) IsHiddenInBush() bool {
+	return u.inBush.Load()
+}
+
+// sameGroup reports if both players share their vision. Players who joined after the last update are not
+// in the snapshot yet
+func (s *visionSnapshot) sameGroup(player, other *Player) bool {
+	if player == other {
+		return true
+	}
+	if s != nil {
+		group, ok := s.groups[player]
+		otherGroup, otherOk := s.groups[other]
+		if ok && otherOk {
+			return group == otherGroup
+		}
+	}
+	return AreAllied(player, other)
+}
+
+// sees reports if the vision group of the player sees any point within the margin of the position
+func (s *visionSnapshot) sees(player *Player, position PositionFloat, margin float32) bool {
+	if s == nil {
+		return false
+	}
+	group, ok := s.groups[player]
+	if !ok {
+		group = visionGroup(player)
+	}
+	return s.grid.sees(group, position, margin)
+}
+
+func (s *visionSnapshot) view(player *Player) *playerView {
+	if s == nil {
+		return nil
+	}
+	return s.views[player]
+}
+
+// CanSee reports if the player sees the unit. Without a player, as for turrets of uncaptured neutral bases,
+// units in bushes are never seen and fog of war does not apply
+func (p *Player) CanSee(unit *Unit) bool {
+	if p == nil {
+		return !unit.IsHiddenInBush()
+	}
+	if unit.Player == p {
+		return true
+	}
+
+	vision := currentVision.Load()
+	if view := vision.view(p); view != nil {
+		if view.hiddenUnits[unit] {
+			return false
+		}
+		if view.seenUnits[unit] {
+			return true
+		}
+	}
+	if !FogOfWar || vision.sameGroup(p, unit.Player) {
+		return true
+	}
+
+	// Not looked at by the last update, like units that just spawned. A hidden unit stays hidden until the
+	// next update, which then reveals it if it came into sight
+	p.visionMutex.Lock()
+	defer p.visionMutex.Unlock()
+
+	if p.lateHiddenUnits[unit] {
+		return false
+	}
+	visible := !unit.IsHiddenInBush() && vision.sees(p, unit.GetPosition(), 0)
+	if !visible {
+		p.lateHiddenUnits = setVisible(p.lateHiddenUnits, unit)
+	}
+	return visible
+}
+
+// CanSeeBase reports if the player sees the buildings of a base. Neutral bases are always seen,
+// captured ones included
+func (p *Player) CanSeeBase(base *Base) bool {
+	owner, ok := base.Owner.(*Player)
+	if !FogOfWar || !ok || p == nil || owner == p {
+		return true
+	}
+
+	vision := currentVision.Load()
+	if view := vision.view(p); view != nil {
+		if view.hiddenBases[owner] {
+			return false
+		}
+		if view.seenBases[owner] {
+			return true
+		}
+	}
+	if vision.sameGroup(p, owner) {
+		return true
+	}
+
+	p.visionMutex.Lock()
+	defer p.visionMutex.Unlock()
+
+	if p.lateHiddenBases[owner] {
+		return false
+	}
+	visible := vision.sees(p, IntToFloat(base.Position), PLAYER_MAX_BUILDING_RADIUS)
+	if !visible {
+		p.lateHiddenBases = setVisible(p.lateHiddenBases, owner)
+	}
+	return visible
+}
+
+// CanSeePosition reports if the position is within the vision of the player or its allies
+func (p *Player) CanSeePosition(position PositionFloat) bool {
+	if !FogOfWar || p == nil {
+		return true
+	}
+	return currentVision.Load().sees(p, position, 0)
+}
+
+func setVisible[K comparable](set map[K]bool, key K) map[K]bool {
+	if set == nil {
+		set = make(map[K]bool)
+	}
+	set[key] = true
+	return set
+}
+
+// updateVisibility marks the units standing in bushes and updates which units and bases every player sees.
+// Players are told about what went out of sight or came into sight
+func updateVisibility(players []*Player, bushes []PositionInt) {
+	unitsByPlayer := make(map[*Player][]*Unit, len(players))
+	var inBush []*Unit
+
+	for _, player := range players {
+		player.RLock()
+		units := make([]*Unit, 0, len(player.Units))
+		for _, unit := range player.Units {
+			if !unit.IsMarkedForRemoval() {
+				units = append(units, unit)
+			}
+		}
+		player.RUnlock()
+
+		for _, unit := range units {
+			hidden := isInBush(unit.GetPosition(), bushes)
+			unit.inBush.Store(hidden)
+			if hidden {
+				inBush = append(inBush, unit)
+			}
+		}
+		unitsByPlayer[player] = units
+	}
+
+	groups := make(map[*Player]interface{}, len(players))
+	for _, player := range players {
+		groups[player] = visionGroup(player)
+	}
+
+	vision := &visionSnapshot{
+		groups: groups,
+		views:  make(map[*Player]*playerView, len(players)),
+	}
+	if FogOfWar {
+		vision.grid = buildVisionGrid(players, unitsByPlayer, groups)
+	}
+	grid := vision.grid
+
+	for _, viewer := range players {
+		group := groups[viewer]
+
+		// Units of the viewer and its allies reveal what is close to them
+		var scouts []*Unit
+		for _, player := range players {
+			if groups[player] == group {
+				scouts = append(scouts, unitsByPlayer[player]...)
+			}
+		}
+
+		hiddenUnits := make(map[*Unit]bool)
+		for _, unit := range inBush {
+			if groups[unit.Player] != group && !isRevealed(unit, scouts) {
+				hiddenUnits[unit] = true
+			}
+		}
+
+		var seenUnits map[*Unit]bool
+		var hiddenBases, seenBases map[*Player]bool
+		if FogOfWar {
+			seenUnits = make(map[*Unit]bool)
+			hiddenBases = make(map[*Player]bool)
+			seenBases = make(map[*Player]bool)
+
+			for _, player := range players {
+				if groups[player] == group {
+					continue
+				}
+
+				if grid.sees(group, IntToFloat(player.Base.Position), PLAYER_MAX_BUILDING_RADIUS) {
+					seenBases[player] = true
+				} else {
+					hiddenBases[player] = true
+				}
+
+				for _, unit := range unitsByPlayer[player] {
+					if hiddenUnits[unit] {
+						continue
+					}
+					if grid.sees(group, unit.GetPosition(), 0) {
+						seenUnits[unit] = true
+					} else {
+						hiddenUnits[unit] = true
+					}
+				}
+			}
+		}
+
+		vision.views[viewer] = &playerView{
+			hiddenUnits: hiddenUnits,
+			seenUnits:   seenUnits,
+			hiddenBases: hiddenBases,
+			seenBases:   seenBases,
+		}
+	}
+
+	previous := currentVision.Swap(vision)
+
+	isActive := func(player *Player) bool {
+		_, active := unitsByPlayer[player]
+		return active
+	}
+	for _, viewer := range players {
+		viewer.announceVisibility(vision, previous.view(viewer), isActive)
+	}
+}
+
+func isRevealed(unit *Unit, scouts []*Unit) bool {
+	position := unit.GetPosition()
+	for _, scout := range scouts {
+		if scout.GetPosition().DistanceTo(position) <= BUSH_REVEAL_RADIUS {
+			return true
+		}
+	}
+	return false
+}
+
+// announceVisibility tells the player what changed since the previous update. Units and bases the player was told
+// nothing about because they were hidden when first checked count as hidden before, those the update did not look at
+// yet stay hidden until the next one. Units and bases of players who left the game are forgotten without being revealed
+func (p *Player) announceVisibility(vision *visionSnapshot, previous *playerView, isActive func(player *Player) bool) {
+	current := vision.views[p]
+
+	p.visionMutex.Lock()
+	wasHiddenUnit, wasHiddenBase := p.lateHiddenUnits, p.lateHiddenBases
+	p.lateHiddenUnits, p.lateHiddenBases = nil, nil
+	p.visionMutex.Unlock()
+
+	var lateUnits []*Unit
+	var lateBases []*Player
+
+	if previous != nil {
+		for unit := range previous.hiddenUnits {
+			wasHiddenUnit = setVisible(wasHiddenUnit, unit)
+		}
+		for owner := range previous.hiddenBases {
+			wasHiddenBase = setVisible(wasHiddenBase, owner)
+		}
+	}
+
+	var hidden, revealed []*Unit
+	for unit := range current.hiddenUnits {
+		if !wasHiddenUnit[unit] {
+			hidden = append(hidden, unit)
+		}
+	}
+	for unit := range wasHiddenUnit {
+		if current.hiddenUnits[unit] || !isActive(unit.Player) || unit.IsMarkedForRemoval() {
+			continue
+		}
+		if !FogOfWar || current.seenUnits[unit] || vision.sameGroup(p, unit.Player) {
+			revealed = append(revealed, unit)
+		} else {
+			lateUnits = append(lateUnits, unit)
+		}
+	}
+
+	var hiddenOwners, revealedOwners []*Player
+	for owner := range current.hiddenBases {
+		if !wasHiddenBase[owner] {
+			hiddenOwners = append(hiddenOwners, owner)
+		}
+	}
+	for owner := range wasHiddenBase {
+		if current.hiddenBases[owner] || !isActive(owner) {
+			continue
+		}
+		if current.seenBases[owner] || vision.sameGroup(p, owner) {
+			revealedOwners = append(revealedOwners, owner)
+		} else {
+			lateBases = append(lateBases, owner)
+		}
+	}
+
+	if len(lateUnits) > 0 || len(lateBases) > 0 {
+		p.visionMutex.Lock()
+		for _, unit := range lateUnits {
+			p.lateHiddenUnits = setVisible(p.lateHiddenUnits, unit)
+		}
+		for _, owner := range lateBases {
+			p.lateHiddenBases = setVisible(p.lateHiddenBases, owner)
+		}
+		p.visionMutex.Unlock()
+	}
+
+	if len(hidden) > 0 || len(revealed) > 0 {
+		TriggerUnitVisibilityEvent(p, hidden, revealed)
+	}
+	if len(hiddenOwners) > 0 || len(revealedOwners) > 0 {
+		TriggerBaseVisibilityEvent(p, hiddenOwners, revealedOwners)
+	}
+}
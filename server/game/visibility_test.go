@@ -0,0 +1,122 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func addTestUnit(player *Player, id ID, x, y float32) *Unit {
+	unit := &Unit{Player: player, ID: id, Position: PositionFloat{X: x, Y: y}}
+	if player.Units == nil {
+		player.Units = make(map[ID]*Unit)
+	}
+	player.Units[id] = unit
+	return unit
+}
+
+func TestUpdateVisibilityBushes(t *testing.T) {
+	sub := Bus.Subscribe(SubscriptionOptions{Topics: []EventType{UnitVisibility}, BufferSize: 16})
+	defer Bus.Unsubscribe(sub)
+
+	bushes := []PositionInt{{X: 0, Y: 0}}
+	hider, scout, ally := &Player{}, &Player{}, &Player{}
+	alliance := &Alliance{Members: []*Player{hider, ally}}
+	hider.Alliance, ally.Alliance = alliance, alliance
+	players := []*Player{hider, scout, ally}
+
+	hidden := addTestUnit(hider, 1, BUSH_RADIUS/2, 0)
+	inTheOpen := addTestUnit(hider, 2, 1000, 0)
+	farScout := addTestUnit(scout, 1, BUSH_REVEAL_RADIUS+100, 0)
+
+	updateVisibility(players, bushes)
+	if scout.CanSee(hidden) || !scout.CanSee(inTheOpen) {
+		t.Error("an enemy far from the bush sees into it or misses the unit in the open")
+	}
+	if !hider.CanSee(hidden) || !ally.CanSee(hidden) {
+		t.Error("a unit in a bush is hidden from its own player or an ally")
+	}
+	if (*Player)(nil).CanSee(hidden) {
+		t.Error("neutral turrets see into bushes")
+	}
+
+	// An enemy unit walking up to the bush reveals it
+	farScout.Position.X = BUSH_REVEAL_RADIUS - 10
+	updateVisibility(players, bushes)
+	if !scout.CanSee(hidden) {
+		t.Error("an enemy unit next to the bush does not see into it")
+	}
+
+	// Leaving the bush makes the unit visible even after the scout walked away
+	farScout.Position.X = 2000
+	updateVisibility(players, bushes)
+	hidden.Position.X = BUSH_RADIUS + 10
+	updateVisibility(players, bushes)
+	if !scout.CanSee(hidden) {
+		t.Error("a unit that left the bush is still hidden")
+	}
+
+	var events []*UnitVisibilityEvent
+	for len(events) < 4 {
+		select {
+		case event := <-sub.Events():
+			if event := event.(*UnitVisibilityEvent); event.Player == scout {
+				events = append(events, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%d visibility changes of the scout received, expected 4", len(events))
+		}
+	}
+	for i, want := range []struct{ hidden, revealed int }{{1, 0}, {0, 1}, {1, 0}, {0, 1}} {
+		if len(events[i].Hidden) != want.hidden || len(events[i].Revealed) != want.revealed {
+			t.Errorf("change %d hid %d and revealed %d units, expected %d and %d",
+				i, len(events[i].Hidden), len(events[i].Revealed), want.hidden, want.revealed)
+		}
+	}
+}
+
+func TestUpdateVisibilityFogOfWar(t *testing.T) {
+	defer func(enabled bool) { FogOfWar = enabled }(FogOfWar)
+	FogOfWar = true
+
+	newPlayer := func(x int16) *Player {
+		player := &Player{}
+		player.Base = &Base{Owner: player, Position: PositionInt{X: x}}
+		return player
+	}
+	viewer, enemy, ally := newPlayer(0), newPlayer(10000), newPlayer(-10000)
+	alliance := &Alliance{Members: []*Player{viewer, ally}}
+	viewer.Alliance, ally.Alliance = alliance, alliance
+	players := []*Player{viewer, enemy, ally}
+
+	target := addTestUnit(enemy, 1, 5000, 0)
+	scout := addTestUnit(viewer, 1, 0, 0)
+	sight := float32(GetUnitSightRange(scout.Type))
+
+	updateVisibility(players, nil)
+	if viewer.CanSee(target) {
+		t.Error("an enemy unit far from every base and unit was seen")
+	}
+	if viewer.CanSeeBase(enemy.Base) || enemy.CanSeeBase(viewer.Base) {
+		t.Error("bases far apart see each other")
+	}
+	if !viewer.CanSeeBase(ally.Base) || !viewer.CanSeeBase(viewer.Base) {
+		t.Error("the own or an allied base is hidden")
+	}
+
+	// A unit of an ally shares what it sees
+	addTestUnit(ally, 1, 5000-sight/2, 0)
+	updateVisibility(players, nil)
+	if !viewer.CanSee(target) {
+		t.Error("an enemy unit seen by an ally is hidden")
+	}
+	if enemy.CanSeePosition(IntToFloat(viewer.Base.Position)) {
+		t.Error("the enemy sees the viewer's base without anything close to it")
+	}
+
+	// Walking up to the enemy base brings it into sight
+	scout.Position.X = float32(enemy.Base.Position.X) - PLAYER_MAX_BUILDING_RADIUS - sight/2
+	updateVisibility(players, nil)
+	if !viewer.CanSeeBase(enemy.Base) {
+		t.Error("an enemy base next to a unit of the viewer is hidden")
+	}
+}
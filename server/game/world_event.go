@@ -0,0 +1,310 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorldEventSettings selects the world events of the room, it is read once when the game starts
+type WorldEventSettings struct {
+	Enabled  bool
+	Interval time.Duration    // Between the start of two events
+	Types    []WorldEventType // Drawn at random for every event
+}
+
+var WorldEventConfig = WorldEventSettings{
+	Interval: WORLD_EVENT_DEFAULT_INTERVAL * time.Second,
+	Types:    []WorldEventType{WORLD_EVENT_POWER_CRATE, WORLD_EVENT_HAZARD_ZONE, WORLD_EVENT_DOUBLE_SCORE},
+}
+
+// WorldEvent is a crate, hazard zone or double score period. Events are announced when they are scheduled
+// and take effect from StartsAt until EndsAt, a collected crate ends early
+type WorldEvent struct {
+	ID       ID
+	Type     WorldEventType
+	Position PositionFloat // Unused for double score
+	Radius   int
+	Value    uint16 // Power of a crate, damage per second of a hazard, score multiplier of double score
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+var worldEventTypes = [WORLD_EVENT_TYPE_COUNT]struct {
+	name     string
+	radius   int
+	value    uint16
+	announce time.Duration
+	duration time.Duration
+}{
+	WORLD_EVENT_POWER_CRATE:  {"crate", WORLD_EVENT_CRATE_RADIUS, WORLD_EVENT_CRATE_POWER, 0, WORLD_EVENT_CRATE_DURATION * time.Second},
+	WORLD_EVENT_HAZARD_ZONE:  {"hazard", WORLD_EVENT_HAZARD_RADIUS, WORLD_EVENT_HAZARD_DAMAGE, WORLD_EVENT_ANNOUNCE_DELAY * time.Second, WORLD_EVENT_HAZARD_DURATION * time.Second},
+	WORLD_EVENT_DOUBLE_SCORE: {"double_score", 0, 2, WORLD_EVENT_ANNOUNCE_DELAY * time.Second, WORLD_EVENT_DOUBLE_SCORE_DURATION * time.Second},
+}
+
+var (
+	worldEvents            []*WorldEvent
+	worldEventsMutex       sync.RWMutex
+	availableWorldEventIDs = InitAvailableIDs(MAX_WORLD_EVENTS)
+)
+
+func (t WorldEventType) String() string {
+	if int(t) < len(worldEventTypes) {
+		return worldEventTypes[t].name
+	}
+	return fmt.Sprintf("world event %d", t)
+}
+
+// ParseWorldEventType returns the world event type with the given name
+func ParseWorldEventType(name string) (WorldEventType, bool) {
+	for eventType, entry := range worldEventTypes {
+		if strings.EqualFold(name, entry.name) {
+			return WorldEventType(eventType), true
+		}
+	}
+	return 0, false
+}
+
+// IsActive reports if the event takes effect at the given time
+func (e *WorldEvent) IsActive(now time.Time) bool {
+	return !now.Before(e.StartsAt) && now.Before(e.EndsAt)
+}
+
+// GetWorldEvents returns the announced and active world events
+func GetWorldEvents() []*WorldEvent {
+	worldEventsMutex.RLock()
+	defer worldEventsMutex.RUnlock()
+
+	events := make([]*WorldEvent, len(worldEvents))
+	copy(events, worldEvents)
+	return events
+}
+
+// ScoreMultiplier returns how many times score gains count, double score periods double them
+func ScoreMultiplier() uint32 {
+	worldEventsMutex.RLock()
+	defer worldEventsMutex.RUnlock()
+
+	now := time.Now()
+	multiplier := uint32(1)
+	for _, event := range worldEvents {
+		if event.Type == WORLD_EVENT_DOUBLE_SCORE && event.IsActive(now) {
+			multiplier = max(multiplier, uint32(event.Value))
+		}
+	}
+	return multiplier
+}
+
+func startWorldEventLoop() {
+	duration := WORLD_EVENT_UPDATE_INTERVAL * time.Millisecond
+	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
+
+	nextEvent := time.Now().Add(WorldEventConfig.Interval)
+	for now := range ticker.C {
+		if !now.Before(nextEvent) {
+			scheduleWorldEvent(now)
+			nextEvent = now.Add(WorldEventConfig.Interval)
+		}
+
+		State.RLock()
+		players := make([]*Player, 0, len(State.Players))
+		for _, player := range State.Players {
+			if !player.IsMarkedForRemoval() {
+				players = append(players, player)
+			}
+		}
+		State.RUnlock()
+
+		updateWorldEvents(now, players, duration)
+	}
+}
+
+// scheduleWorldEvent announces a random event of the configured types
+func scheduleWorldEvent(now time.Time) {
+	if len(WorldEventConfig.Types) == 0 {
+		return
+	}
+	eventType := WorldEventConfig.Types[rand.Intn(len(WorldEventConfig.Types))]
+	settings := worldEventTypes[eventType]
+
+	event := &WorldEvent{
+		Type:     eventType,
+		Radius:   settings.radius,
+		Value:    settings.value,
+		StartsAt: now.Add(settings.announce),
+		EndsAt:   now.Add(settings.announce + settings.duration),
+	}
+	if eventType != WORLD_EVENT_DOUBLE_SCORE {
+		position, ok := findWorldEventPosition(settings.radius)
+		if !ok {
+			log.Println("Could not find a free position for world event", eventType)
+			return
+		}
+		event.Position = position
+	}
+
+	id, ok := availableWorldEventIDs.getNextAvailableID()
+	if !ok {
+		log.Println("No IDs left for world events")
+		return
+	}
+	event.ID = id
+
+	worldEventsMutex.Lock()
+	worldEvents = append(worldEvents, event)
+	worldEventsMutex.Unlock()
+
+	TriggerWorldEventStartEvent(event)
+}
+
+// findWorldEventPosition picks a random position on the map that keeps the event clear of bases and rocks
+func findWorldEventPosition(radius int) (PositionFloat, bool) {
+	type obstacle struct {
+		position PositionFloat
+		radius   float32
+	}
+
+	State.RLock()
+	mapRadius := float64(State.Map.Radius) - MAP_EDGE_MARGIN/2
+	obstacles := make([]obstacle, 0, len(State.AvailablePositions)+len(State.NeutralBases)+len(State.Rocks))
+	// Taken spawn positions stay in the map, so this also covers the bases of all players
+	for position := range State.AvailablePositions {
+		obstacles = append(obstacles, obstacle{IntToFloat(position), PLAYER_SPAWN_PROTECTION_RADIUS})
+	}
+	for _, neutral := range State.NeutralBases {
+		obstacles = append(obstacles, obstacle{IntToFloat(neutral.Base.Position), NEUTRAL_BASE_MAX_BUILDING_RADIUS})
+	}
+	for _, rock := range State.Rocks {
+		obstacles = append(obstacles, obstacle{rock.Polygon.Center, float32(rock.Size)})
+	}
+	State.RUnlock()
+
+	if mapRadius <= 0 {
+		return PositionFloat{}, false
+	}
+
+	for attempt := 0; attempt < WORLD_EVENT_PLACEMENT_ATTEMPTS; attempt++ {
+		// The square root spreads the positions evenly over the area of the map
+		position := polarPosition(PositionFloat{}, mapRadius*math.Sqrt(rand.Float64()), rand.Float64()*2*math.Pi)
+
+		free := true
+		for _, other := range obstacles {
+			if position.DistanceTo(other.position) < other.radius+float32(radius) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return position, true
+		}
+	}
+	return PositionFloat{}, false
+}
+
+// updateWorldEvents ends expired events, hands out crates and damages the units inside hazard zones
+func updateWorldEvents(now time.Time, players []*Player, duration time.Duration) {
+	var expired []*WorldEvent
+	var active []*WorldEvent
+
+	worldEventsMutex.Lock()
+	remaining := worldEvents[:0]
+	for _, event := range worldEvents {
+		if !now.Before(event.EndsAt) {
+			expired = append(expired, event)
+			continue
+		}
+		remaining = append(remaining, event)
+		if event.IsActive(now) {
+			active = append(active, event)
+		}
+	}
+	clear(worldEvents[len(remaining):])
+	worldEvents = remaining
+	worldEventsMutex.Unlock()
+
+	for _, event := range expired {
+		availableWorldEventIDs.returnID(event.ID)
+		TriggerWorldEventEndEvent(event, nil)
+	}
+
+	for _, event := range active {
+		switch event.Type {
+		case WORLD_EVENT_POWER_CRATE:
+			collectPowerCrate(event, players)
+		case WORLD_EVENT_HAZARD_ZONE:
+			// Damage is given per second and dealt in steps of the update interval
+			damage := uint16(math.Round(float64(event.Value) * duration.Seconds()))
+			applyHazardDamage(event, players, damage)
+		}
+	}
+}
+
+// collectPowerCrate gives the power of the crate to the owner of the first unit touching it
+func collectPowerCrate(event *WorldEvent, players []*Player) {
+	for _, player := range players {
+		player.RLock()
+		units := make([]*Unit, 0, len(player.Units))
+		for _, unit := range player.Units {
+			units = append(units, unit)
+		}
+		player.RUnlock()
+
+		for _, unit := range units {
+			if unit.IsMarkedForRemoval() || !unit.IsWithinRadius(event.Position, float32(event.Radius+unit.Size)) {
+				continue
+			}
+			if !removeWorldEvent(event) {
+				return // Expired or collected in the meantime
+			}
+
+			player.Resources.Power.Increment(event.Value)
+			TriggerResourceUpdateEvent(player)
+			TriggerWorldEventEndEvent(event, player)
+			return
+		}
+	}
+}
+
+// applyHazardDamage damages every unit inside a hazard zone, units of all players alike
+func applyHazardDamage(event *WorldEvent, players []*Player, damage uint16) {
+	for _, player := range players {
+		player.RLock()
+		units := make([]*Unit, 0, len(player.Units))
+		for _, unit := range player.Units {
+			units = append(units, unit)
+		}
+		player.RUnlock()
+
+		for _, unit := range units {
+			if unit.IsMarkedForRemoval() || !unit.IsWithinRadius(event.Position, float32(event.Radius)) {
+				continue
+			}
+			recordDamage(nil, player, unit.Health.Current, damage)
+			isAlive := unit.TakeDamage(damage)
+			if !isAlive {
+				unit.MarkForRemoval()
+				handleUnitDestroyed(unit)
+			}
+		}
+	}
+}
+
+// removeWorldEvent removes an event before it expired and reports if it was still there
+func removeWorldEvent(event *WorldEvent) bool {
+	worldEventsMutex.Lock()
+	defer worldEventsMutex.Unlock()
+
+	for i, other := range worldEvents {
+		if other == event {
+			worldEvents = append(worldEvents[:i], worldEvents[i+1:]...)
+			availableWorldEventIDs.returnID(event.ID)
+			return true
+		}
+	}
+	return false
+}
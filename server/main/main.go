@@ -1,16 +1,18 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"server/game"
 	"server/network"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -30,6 +32,37 @@ func playerCountHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// Handler to return how well join and resync snapshots compress
+func compressionStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(network.GetCompressionStats())
+}
+
+// Handler to return the all-time, daily and weekly high scores
+func highScoresHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	boards := game.GetHighScores()
+	response := map[string]game.HighScoreBoard{
+		"allTime": boards[game.HIGH_SCORE_ALL_TIME],
+		"daily":   boards[game.HIGH_SCORE_DAILY],
+		"weekly":  boards[game.HIGH_SCORE_WEEKLY],
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
 func serverRebootHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
@@ -112,48 +145,18 @@ func wsEndpoint(w http.ResponseWriter, r *http.Request) {
 	//log.Printf("Received refresh token: %s", refreshToken)
 
 	// Fetch user data with the refresh token
-	body := map[string]string{"refreshToken": refreshToken}
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		log.Printf("Failed to marshal JSON body: %v", err)
-		http.Error(w, "Failed to retrieve user data", http.StatusInternalServerError)
+	clientIP := userData.ClientIP
+	userData, err = network.Auth.FetchUserData(refreshToken)
+	if errors.Is(err, network.ErrInvalidRefreshToken) {
+		http.Error(w, "Invalid or expired refresh token.", http.StatusForbidden)
 		return
 	}
-
-	// Create a new POST request
-	req, err := http.NewRequest(http.MethodPost, "https://auth.blobl.io/api/user", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		log.Printf("Failed to create request: %v", err)
+		log.Printf("Failed to retrieve user data: %v", err)
 		http.Error(w, "Failed to retrieve user data", http.StatusInternalServerError)
 		return
 	}
-	req.Header.Set("Content-Type", "application/json")
-	origin := fmt.Sprintf("http://127.0.0.1:%s", PORT)
-	req.Header.Set("Origin", origin)
-
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Failed to send request to user API: %v", err)
-		http.Error(w, "Failed to retrieve user data", http.StatusInternalServerError)
-		return
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Invalid or expired refresh token: %s", resp.Status)
-		http.Error(w, "Invalid or expired refresh token.", http.StatusForbidden)
-		return
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&userData); err != nil {
-		log.Printf("Failed to parse user data: %v", err)
-		http.Error(w, "Failed to parse user data", http.StatusInternalServerError)
-		return
-	}
+	userData.ClientIP = clientIP
 
 	// Pass the request to the WebSocket handler
 	network.WsEndpoint(w, r, userData)
@@ -167,6 +170,148 @@ func main() {
 		log.Printf("Port not specified. Defaulting to port %s\n", PORT)
 	}
 
+	// Optional cap on alliance size to prevent megateams
+	if allianceMaxSize := os.Getenv("ALLIANCE_MAX_SIZE"); allianceMaxSize != "" {
+		maxSize, err := strconv.Atoi(allianceMaxSize)
+		if err != nil || maxSize < 0 {
+			log.Printf("Invalid ALLIANCE_MAX_SIZE %q, keeping default of %d\n", allianceMaxSize, game.AllianceMaxSize)
+		} else {
+			game.AllianceMaxSize = maxSize
+		}
+	}
+
+	// Snapshots above the threshold are compressed for clients supporting permessage-deflate
+	if threshold := os.Getenv("COMPRESSION_THRESHOLD"); threshold != "" {
+		bytes, err := strconv.Atoi(threshold)
+		if err != nil || bytes < 0 {
+			log.Printf("Invalid COMPRESSION_THRESHOLD %q, keeping default of %d\n", threshold, network.CompressionThreshold)
+		} else {
+			network.CompressionThreshold = bytes
+		}
+	}
+	if level := os.Getenv("COMPRESSION_LEVEL"); level != "" {
+		value, err := strconv.Atoi(level)
+		if err != nil || value < 1 || value > 9 {
+			log.Printf("Invalid COMPRESSION_LEVEL %q, keeping default of %d\n", level, network.CompressionLevel)
+		} else {
+			network.CompressionLevel = value
+		}
+	}
+
+	// The map is generated from a seed, a logged seed reproduces an interesting map
+	if seed := os.Getenv("MAP_SEED"); seed != "" {
+		value, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			log.Printf("Invalid MAP_SEED %q, using a random seed\n", seed)
+		} else {
+			game.MapConfig.Seed = value
+		}
+	}
+	if layout := os.Getenv("MAP_LAYOUT"); layout != "" {
+		value, ok := game.ParseMapLayout(layout)
+		if !ok {
+			log.Printf("Invalid MAP_LAYOUT %q, keeping default of %s\n", layout, game.MapConfig.Layout)
+		} else {
+			game.MapConfig.Layout = value
+		}
+	}
+	if players := os.Getenv("MAP_EXPECTED_PLAYERS"); players != "" {
+		value, err := strconv.Atoi(players)
+		if err != nil || value < 1 || value > game.MAX_PLAYERS {
+			log.Printf("Invalid MAP_EXPECTED_PLAYERS %q, keeping default of %d\n", players, game.MapConfig.ExpectedPlayers)
+		} else {
+			game.MapConfig.ExpectedPlayers = value
+		}
+	}
+
+	// Competitive events play on fixed maps from data/maps instead of a generated one
+	if name := os.Getenv("MAP_NAME"); name != "" {
+		game.MapConfig.File = filepath.Join("data/maps", filepath.Base(name)+".json")
+	}
+
+	// Fog of war hides enemies outside the vision of a player and their allies
+	if fog := os.Getenv("FOG_OF_WAR"); fog != "" {
+		value, err := strconv.ParseBool(fog)
+		if err != nil {
+			log.Printf("Invalid FOG_OF_WAR %q, keeping fog of war disabled\n", fog)
+		} else {
+			game.FogOfWar = value
+		}
+	}
+
+	// World events drop power crates, open hazard zones and announce double score periods
+	if events := os.Getenv("WORLD_EVENTS"); events != "" {
+		value, err := strconv.ParseBool(events)
+		if err != nil {
+			log.Printf("Invalid WORLD_EVENTS %q, keeping world events disabled\n", events)
+		} else {
+			game.WorldEventConfig.Enabled = value
+		}
+	}
+	if interval := os.Getenv("WORLD_EVENT_INTERVAL"); interval != "" {
+		value, err := strconv.Atoi(interval)
+		if err != nil || value < 1 {
+			log.Printf("Invalid WORLD_EVENT_INTERVAL %q, keeping default of %s\n", interval, game.WorldEventConfig.Interval)
+		} else {
+			game.WorldEventConfig.Interval = time.Duration(value) * time.Second
+		}
+	}
+	if types := os.Getenv("WORLD_EVENT_TYPES"); types != "" {
+		var eventTypes []game.WorldEventType
+		for _, name := range strings.Split(types, ",") {
+			eventType, ok := game.ParseWorldEventType(strings.TrimSpace(name))
+			if !ok {
+				log.Printf("Invalid world event type %q in WORLD_EVENT_TYPES, skipping it\n", name)
+				continue
+			}
+			eventTypes = append(eventTypes, eventType)
+		}
+		game.WorldEventConfig.Types = eventTypes
+	}
+
+	// The play zone mode shrinks a safe circle until one player is left, joins close once it shrinks
+	if zone := os.Getenv("PLAY_ZONE"); zone != "" {
+		value, err := strconv.ParseBool(zone)
+		if err != nil {
+			log.Printf("Invalid PLAY_ZONE %q, keeping the play zone disabled\n", zone)
+		} else {
+			game.PlayZoneConfig.Enabled = value
+		}
+	}
+	if players := os.Getenv("PLAY_ZONE_MIN_PLAYERS"); players != "" {
+		value, err := strconv.Atoi(players)
+		if err != nil || value < 2 || value > game.MAX_PLAYERS {
+			log.Printf("Invalid PLAY_ZONE_MIN_PLAYERS %q, keeping default of %d\n", players, game.PlayZoneConfig.MinPlayers)
+		} else {
+			game.PlayZoneConfig.MinPlayers = value
+		}
+	}
+	if delay := os.Getenv("PLAY_ZONE_START_DELAY"); delay != "" {
+		value, err := strconv.Atoi(delay)
+		if err != nil || value < 0 {
+			log.Printf("Invalid PLAY_ZONE_START_DELAY %q, keeping default of %s\n", delay, game.PlayZoneConfig.StartDelay)
+		} else {
+			game.PlayZoneConfig.StartDelay = time.Duration(value) * time.Second
+		}
+	}
+
+	// Resolve users through the auth server or a local users file
+	network.Auth = network.NewAuthProviderFromEnv(fmt.Sprintf("http://127.0.0.1:%s", PORT))
+
+	// Stat updates survive auth outages and restarts in the outbox file
+	statsOutboxFile := os.Getenv("STATS_OUTBOX_FILE")
+	if statsOutboxFile == "" {
+		statsOutboxFile = "data/stats_outbox.json"
+	}
+	network.StartStatsOutbox(statsOutboxFile)
+
+	// Records set on this server are kept across restarts
+	highScoresFile := os.Getenv("HIGH_SCORES_FILE")
+	if highScoresFile == "" {
+		highScoresFile = "data/highscores.json"
+	}
+	game.LoadHighScores(highScoresFile)
+
 	game.Start()
 
 	// Define WebSocket endpoint handlers with session checks
@@ -176,6 +321,8 @@ func main() {
 
 	http.HandleFunc("/playercount", playerCountHandler)
 	http.HandleFunc("/reboot", serverRebootHandler)
+	http.HandleFunc("/compression", compressionStatsHandler)
+	http.Handle("/highscores", corsMiddleware(http.HandlerFunc(highScoresHandler)))
 
 	// Log server start
 	address := fmt.Sprintf("localhost:%s", PORT)
@@ -0,0 +1,189 @@
+// Command mappreview renders a map file or a generated map to a PNG image, so maps can be checked before a game
+//
+//	go run ./mappreview -map main/data/maps/crossroads.json -o crossroads.png
+//	go run ./mappreview -layout islands -seed 42 -players 32 -o islands.png
+package main
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"server/game"
+)
+
+const bushSize = 80 // Average bush radius drawn by the client
+
+var (
+	backgroundColor      = color.RGBA{R: 38, G: 42, B: 36, A: 255}
+	groundColor          = color.RGBA{R: 74, G: 86, B: 62, A: 255}
+	spawnColor           = color.RGBA{R: 66, G: 135, B: 245, A: 255}
+	spawnAreaColor       = color.RGBA{R: 66, G: 135, B: 245, A: 70}
+	neutralAreaColor     = color.RGBA{R: 230, G: 200, B: 80, A: 70}
+	neutralCoreColor     = color.RGBA{R: 230, G: 200, B: 80, A: 255}
+	neutralBuildingColor = color.RGBA{R: 190, G: 150, B: 60, A: 255}
+	bushColor            = color.RGBA{R: 46, G: 125, B: 50, A: 200}
+	rockColor            = color.RGBA{R: 140, G: 140, B: 140, A: 255}
+)
+
+// preview maps world coordinates with the centre of the map in the middle of the image
+type preview struct {
+	image *image.RGBA
+	scale float64
+	half  float64
+}
+
+func main() {
+	mapFile := flag.String("map", "", "Map file to render, a map is generated if empty")
+	layoutName := flag.String("layout", game.MapConfig.Layout.String(), "Layout of the generated map: hex, islands, lanes or poisson")
+	seed := flag.Int64("seed", 1, "Seed of the generated map")
+	players := flag.Int("players", game.MapConfig.ExpectedPlayers, "Expected players of the generated map")
+	output := flag.String("o", "map.png", "Output PNG file")
+	size := flag.Int("size", 1024, "Width and height of the image in pixels")
+	flag.Parse()
+
+	var gameMap game.GameMap
+	if *mapFile != "" {
+		var err error
+		gameMap, err = game.LoadMapFile(*mapFile)
+		if err != nil {
+			log.Fatal("Error loading map file:", err)
+		}
+	} else {
+		layout, ok := game.ParseMapLayout(*layoutName)
+		if !ok {
+			log.Fatalf("Unknown layout %q", *layoutName)
+		}
+		gameMap = game.GenerateMap(game.MapSettings{Seed: *seed, Layout: layout, ExpectedPlayers: *players})
+	}
+
+	p := &preview{
+		image: image.NewRGBA(image.Rect(0, 0, *size, *size)),
+		scale: float64(*size) / (2 * float64(gameMap.Info.Radius)),
+		half:  float64(*size) / 2,
+	}
+	p.render(gameMap)
+
+	file, err := os.Create(*output)
+	if err != nil {
+		log.Fatal("Error creating output file:", err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, p.image); err != nil {
+		log.Fatal("Error encoding PNG:", err)
+	}
+
+	log.Printf("Rendered %s map with %d spawn positions and %d neutral bases to %s",
+		gameMap.Info.Layout, len(gameMap.PlayerPositions), len(gameMap.NeutralBases), *output)
+}
+
+func (p *preview) render(gameMap game.GameMap) {
+	p.fillRect(p.image.Bounds(), backgroundColor)
+	p.fillCircle(game.PositionFloat{}, float64(gameMap.Info.Radius), groundColor)
+
+	for _, bush := range gameMap.Bushes {
+		p.fillCircle(game.IntToFloat(bush), bushSize, bushColor)
+	}
+
+	for _, spawn := range gameMap.PlayerPositions {
+		p.fillCircle(game.IntToFloat(spawn), game.PLAYER_SPAWN_PROTECTION_RADIUS, spawnAreaColor)
+		p.fillCircle(game.IntToFloat(spawn), game.PLAYER_MAX_CORE_RADIUS, spawnColor)
+	}
+
+	for _, neutral := range gameMap.NeutralBases {
+		center := game.IntToFloat(neutral.Position)
+		p.fillCircle(center, game.NEUTRAL_BASE_MAX_BUILDING_RADIUS, neutralAreaColor)
+		p.fillCircle(center, game.NEUTRAL_BASE_MAX_CORE_RADIUS, neutralCoreColor)
+
+		buildings := neutral.Buildings
+		if len(buildings) == 0 {
+			archetype, _ := game.GetNeutralBaseArchetype(neutral.Type)
+			buildings = archetype.Buildings()
+		}
+		for _, building := range buildings {
+			polygon, ok := game.GetBuildingPolygon(building.Type)
+			if !ok {
+				continue
+			}
+			polygon.SetCenter(game.PositionFloat{X: center.X + building.Offset.X, Y: center.Y + building.Offset.Y})
+			polygon.SetRotation(math.Atan2(float64(building.Offset.Y), float64(building.Offset.X)))
+			p.fillPolygon(polygon.GetGlobalVertices(), neutralBuildingColor)
+		}
+	}
+
+	for _, rock := range gameMap.Rocks {
+		p.fillPolygon(rock.Polygon.GetGlobalVertices(), rockColor)
+	}
+}
+
+func (p *preview) toPixel(position game.PositionFloat) (float64, float64) {
+	return float64(position.X)*p.scale + p.half, float64(position.Y)*p.scale + p.half
+}
+
+func (p *preview) fillRect(rect image.Rectangle, c color.RGBA) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			p.image.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// fillCircle draws a circle of at least one pixel, so small things stay visible on large maps
+func (p *preview) fillCircle(center game.PositionFloat, radius float64, c color.RGBA) {
+	cx, cy := p.toPixel(center)
+	r := max(radius*p.scale, 1)
+
+	bounds := image.Rect(int(cx-r), int(cy-r), int(cx+r)+1, int(cy+r)+1).Intersect(p.image.Bounds())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			if dx*dx+dy*dy <= r*r {
+				p.blend(x, y, c)
+			}
+		}
+	}
+}
+
+// fillPolygon draws a polygon given in world coordinates, pixels are tested against it with the even-odd rule
+func (p *preview) fillPolygon(vertices []game.PositionFloat, c color.RGBA) {
+	if len(vertices) == 0 {
+		return
+	}
+
+	xs := make([]float64, len(vertices))
+	ys := make([]float64, len(vertices))
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for i, vertex := range vertices {
+		xs[i], ys[i] = p.toPixel(vertex)
+		minX, minY = min(minX, xs[i]), min(minY, ys[i])
+		maxX, maxY = max(maxX, xs[i]), max(maxY, ys[i])
+	}
+
+	bounds := image.Rect(int(minX), int(minY), int(maxX)+1, int(maxY)+1).Intersect(p.image.Bounds())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			inside := false
+			for i, j := 0, len(xs)-1; i < len(xs); j, i = i, i+1 {
+				if (ys[i] > py) != (ys[j] > py) && px < (xs[j]-xs[i])*(py-ys[i])/(ys[j]-ys[i])+xs[i] {
+					inside = !inside
+				}
+			}
+			if inside {
+				p.blend(x, y, c)
+			}
+		}
+	}
+}
+
+func (p *preview) blend(x, y int, c color.RGBA) {
+	dst := p.image.RGBAAt(x, y)
+	alpha := uint32(c.A)
+	mix := func(src, dst uint8) uint8 {
+		return uint8((uint32(src)*alpha + uint32(dst)*(255-alpha)) / 255)
+	}
+	p.image.SetRGBA(x, y, color.RGBA{R: mix(c.R, dst.R), G: mix(c.G, dst.G), B: mix(c.B, dst.B), A: 255})
+}
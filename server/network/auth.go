@@ -0,0 +1,350 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"server/game"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrInvalidRefreshToken is returned when a refresh token does not belong to any user
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// StatsRejectedError is returned when a stat update can never succeed, like for an unknown user or a bad payload.
+// Other errors are worth retrying
+type StatsRejectedError struct {
+	Reason string
+}
+
+func (e *StatsRejectedError) Error() string {
+	return "stat update rejected: " + e.Reason
+}
+
+// UserStats holds the statistics of a finished run
+type UserStats struct {
+	SessionID string // Identifies the run, a session is only counted once
+	Score     uint32
+	Kills     uint32
+	Playtime  time.Duration
+	Session   game.SessionStats // Detailed counters of the run
+
+	// Keys of the achievements earned during the run
+	Achievements []string
+}
+
+// AuthProvider resolves users and records their progression
+type AuthProvider interface {
+	// FetchUserData resolves a refresh token to the user's role, discord account and skins
+	FetchUserData(refreshToken string) (UserData, error)
+	// UpdateUserStats records the stats of a run and returns the skins it unlocked
+	UpdateUserStats(userId string, stats UserStats) ([]int, error)
+}
+
+// Auth is the provider used for all user lookups and stat updates
+var Auth AuthProvider = NewHTTPAuthProvider("https://auth.blobl.io", "")
+
+// NewAuthProviderFromEnv creates the provider configured by AUTH_PROVIDER ("http" or "local").
+// The HTTP provider uses AUTH_URL, the local provider stores users in AUTH_LOCAL_FILE
+func NewAuthProviderFromEnv(origin string) AuthProvider {
+	switch os.Getenv("AUTH_PROVIDER") {
+	case "local":
+		path := os.Getenv("AUTH_LOCAL_FILE")
+		if path == "" {
+			path = "data/users.json"
+		}
+		log.Printf("Using local auth provider with users from %s\n", path)
+		return NewLocalAuthProvider(path)
+	case "", "http":
+		baseURL := os.Getenv("AUTH_URL")
+		if baseURL == "" {
+			baseURL = "https://auth.blobl.io"
+		}
+		return NewHTTPAuthProvider(baseURL, origin)
+	default:
+		log.Printf("Unknown AUTH_PROVIDER %q, defaulting to http\n", os.Getenv("AUTH_PROVIDER"))
+		return NewHTTPAuthProvider("https://auth.blobl.io", origin)
+	}
+}
+
+// HTTPAuthProvider talks to the authentication server
+type HTTPAuthProvider struct {
+	BaseURL string
+	Origin  string
+	Client  *http.Client
+}
+
+func NewHTTPAuthProvider(baseURL string, origin string) *HTTPAuthProvider {
+	return &HTTPAuthProvider{
+		BaseURL: baseURL,
+		Origin:  origin,
+		Client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (a *HTTPAuthProvider) FetchUserData(refreshToken string) (UserData, error) {
+	var userData UserData
+
+	body := map[string]string{"refreshToken": refreshToken}
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return userData, fmt.Errorf("failed to marshal JSON body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.BaseURL+"/api/user", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return userData, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.Origin != "" {
+		req.Header.Set("Origin", a.Origin)
+	}
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return userData, fmt.Errorf("failed to send request to user API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Invalid or expired refresh token: %s", resp.Status)
+		return userData, ErrInvalidRefreshToken
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&userData); err != nil {
+		return userData, fmt.Errorf("failed to parse user data: %w", err)
+	}
+
+	return userData, nil
+}
+
+func (a *HTTPAuthProvider) UpdateUserStats(userId string, stats UserStats) ([]int, error) {
+	jsonPayload, err := json.Marshal(NewUserStatsPayload(userId, stats))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.BaseURL+"/api/user/update/stats", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Client errors are permanent, except for timeouts and rate limits
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, &StatsRejectedError{Reason: resp.Status}
+		}
+		return nil, fmt.Errorf("failed to update progression: %s", resp.Status)
+	}
+
+	// Parse the response body to get the newly unlocked skins
+	var response struct {
+		Message            string `json:"message"`
+		NewlyUnlockedSkins []int  `json:"newlyUnlockedSkins"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse response JSON: %w", err)
+	}
+
+	return response.NewlyUnlockedSkins, nil
+}
+
+// Progression rules, kept in sync with the authentication server
+const (
+	localMaxLevel          = 40
+	localBaseXP            = 50
+	localVeteranSkinBaseID = 99
+	localMaxSessions       = 20
+)
+
+type localUser struct {
+	RefreshToken string         `json:"refreshToken"`
+	Role         string         `json:"role"`
+	Discord      DiscordDetails `json:"discord"`
+	Skins        SkinDetails    `json:"skins"`
+	Progression  struct {
+		Level int `json:"level"`
+		XP    int `json:"xp"`
+	} `json:"progression"`
+	Statistics struct {
+		Highscore int `json:"highscore"`
+		Kills     int `json:"kills"`
+		Playtime  int `json:"playtime"`
+
+		// Totals of all session counters
+		Session game.SessionStats `json:"session"`
+	} `json:"statistics"`
+	Achievements      []string `json:"achievements,omitempty"`
+	ProcessedSessions []string `json:"processedSessions,omitempty"`
+}
+
+// LocalAuthProvider keeps users in a JSON file so an offline server still has roles, skins and progression
+type LocalAuthProvider struct {
+	Path string
+	sync.Mutex
+}
+
+func NewLocalAuthProvider(path string) *LocalAuthProvider {
+	return &LocalAuthProvider{Path: path}
+}
+
+func (a *LocalAuthProvider) FetchUserData(refreshToken string) (UserData, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	users, err := a.load()
+	if err != nil {
+		return UserData{}, err
+	}
+
+	for _, user := range users {
+		if user.RefreshToken != "" && user.RefreshToken == refreshToken {
+			return UserData{
+				Role:    user.Role,
+				Discord: user.Discord,
+				Skins:   user.Skins,
+			}, nil
+		}
+	}
+
+	return UserData{}, ErrInvalidRefreshToken
+}
+
+func (a *LocalAuthProvider) UpdateUserStats(userId string, stats UserStats) ([]int, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	users, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var user *localUser
+	for i := range users {
+		if users[i].Discord.ID == userId {
+			user = &users[i]
+			break
+		}
+	}
+	if user == nil {
+		return nil, &StatsRejectedError{Reason: fmt.Sprintf("user %s not found", userId)}
+	}
+
+	// Retried submissions of a session must only be counted once
+	if stats.SessionID != "" && slices.Contains(user.ProcessedSessions, stats.SessionID) {
+		return nil, nil
+	}
+
+	payload := NewUserStatsPayload(userId, stats)
+
+	user.Statistics.Highscore = max(user.Statistics.Highscore, payload.Data.Score)
+	user.Statistics.Kills += payload.Data.Kills
+	user.Statistics.Playtime += payload.Data.Playtime
+	user.Statistics.Session.Add(stats.Session)
+
+	var newUnlockedSkins []int
+
+	// Achievements are kept forever and may unlock skins tied to them
+	for _, achievement := range stats.Achievements {
+		if slices.Contains(user.Achievements, achievement) {
+			continue
+		}
+		user.Achievements = append(user.Achievements, achievement)
+
+		for _, skinID := range game.GetAchievementSkinIDs(achievement) {
+			if !slices.Contains(user.Skins.Unlocked, skinID) {
+				user.Skins.Unlocked = append(user.Skins.Unlocked, skinID)
+				newUnlockedSkins = append(newUnlockedSkins, skinID)
+			}
+		}
+	}
+
+	if user.Progression.Level < 1 {
+		user.Progression.Level = 1
+	}
+	user.Progression.XP += payload.Data.XP
+
+	requiredXP := calculateRequiredXP(user.Progression.Level)
+	for user.Progression.XP >= requiredXP && user.Progression.Level < localMaxLevel {
+		user.Progression.Level++
+		user.Progression.XP -= requiredXP
+
+		// Unlock veteran skins every 5 levels
+		if user.Progression.Level%5 == 0 {
+			skinID := localVeteranSkinBaseID + user.Progression.Level/5
+			user.Skins.Unlocked = append(user.Skins.Unlocked, skinID)
+			newUnlockedSkins = append(newUnlockedSkins, skinID)
+		}
+
+		requiredXP = calculateRequiredXP(user.Progression.Level)
+	}
+
+	// Ensure XP does not exceed the required XP for the max level
+	if user.Progression.Level == localMaxLevel {
+		user.Progression.XP = min(user.Progression.XP, calculateRequiredXP(localMaxLevel))
+	}
+
+	if stats.SessionID != "" {
+		user.ProcessedSessions = append(user.ProcessedSessions, stats.SessionID)
+		if len(user.ProcessedSessions) > localMaxSessions {
+			user.ProcessedSessions = user.ProcessedSessions[len(user.ProcessedSessions)-localMaxSessions:]
+		}
+	}
+
+	if err := a.save(users); err != nil {
+		return nil, err
+	}
+
+	return newUnlockedSkins, nil
+}
+
+func calculateRequiredXP(level int) int {
+	return int(math.Round(localBaseXP * math.Pow(float64(level), 1.1)))
+}
+
+// load reads all users, a missing file counts as no users
+func (a *LocalAuthProvider) load() ([]localUser, error) {
+	var file struct {
+		Users []localUser `json:"users"`
+	}
+
+	data, err := os.ReadFile(a.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse users file: %w", err)
+	}
+	return file.Users, nil
+}
+
+// save writes all users atomically
+func (a *LocalAuthProvider) save(users []localUser) error {
+	file := struct {
+		Users []localUser `json:"users"`
+	}{Users: users}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal users: %w", err)
+	}
+	return writeFileAtomic(a.Path, data)
+}
@@ -9,24 +9,47 @@ import (
 	"net"
 	"os"
 	"server/game"
-	"sync"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-var connMutex sync.Mutex
-
 func sendToClient(conn *websocket.Conn, message []byte, toRemove *[]*websocket.Conn) error {
 	if conn == nil {
 		log.Println("Connection is nil, cannot send message")
 		return errors.New("nil connection")
 	}
 
-	connMutex.Lock()
-	defer connMutex.Unlock()
+	userConn, ok := getUserConnection(conn)
+	if !ok {
+		// Connections are registered right after the upgrade and removed once they closed
+		return errors.New("connection not registered")
+	}
+
+	userConn.WriteLock.Lock()
+	defer userConn.WriteLock.Unlock()
+
+	return writeToClient(conn, userConn, message, toRemove)
+}
+
+// writeToClient writes a message and records it in the journal of the connection, its WriteLock has to be held
+func writeToClient(conn *websocket.Conn, userConn UserConnection, message []byte, toRemove *[]*websocket.Conn) error {
+	compress := shouldCompress(userConn, message)
+
+	// Compression applies to the next writes of the connection, so it is only enabled for this one
+	if compress {
+		conn.EnableWriteCompression(true)
+		defer conn.EnableWriteCompression(false)
+	}
 
 	err := conn.WriteMessage(websocket.BinaryMessage, message)
+	if err == nil && userConn.Journal != nil {
+		userConn.Journal.record(message)
+	}
+	if err == nil && compress {
+		go recordCompression(message) // Measured outside of the write lock
+	}
 	if err != nil {
 		log.Printf("Network error while writing message to %s: %v", conn.RemoteAddr().String(), err)
 
@@ -90,6 +113,51 @@ func broadcastToAllExcept(message []byte, exceptPlayerID game.ID) {
 	}
 }
 
+// broadcastToNearby sends the message to every player whose base or units are within the radius of the position
+func broadcastToNearby(message []byte, position game.PositionFloat, radius float32) {
+	var toRemove []*websocket.Conn
+
+	game.State.RLock()
+
+	for _, player := range game.State.Players {
+		if !player.IsMarkedForRemoval() && player.IsNear(position, radius) {
+			sendToClient(player.Conn, message, &toRemove)
+		}
+	}
+
+	game.State.RUnlock()
+
+	for _, conn := range toRemove {
+		removePlayerByConnection(conn)
+	}
+}
+
+// broadcastToViewers sends the message to every player that sees what it is about
+func broadcastToViewers(message []byte, canSee func(player *game.Player) bool) {
+	var toRemove []*websocket.Conn
+
+	game.State.RLock()
+
+	for _, player := range game.State.Players {
+		if !player.IsMarkedForRemoval() && canSee(player) {
+			sendToClient(player.Conn, message, &toRemove)
+		}
+	}
+
+	game.State.RUnlock()
+
+	for _, conn := range toRemove {
+		removePlayerByConnection(conn)
+	}
+}
+
+// broadcastToBaseViewers sends a message about a base and its buildings to the players seeing the base through the fog of war
+func broadcastToBaseViewers(message []byte, base *game.Base) {
+	broadcastToViewers(message, func(player *game.Player) bool {
+		return player.CanSeeBase(base)
+	})
+}
+
 func BroadcastRebootAlert(minutesLeft byte) {
 	message := Message{
 		Type: MessageTypeRebootAlertMessage,
@@ -108,12 +176,169 @@ func broadcastChatMessage(playerID game.ID, text []byte) {
 	}
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(playerID))
+	writeID(buffer, playerID)
+	if len(text) > 64 {
+		text = text[:64]
+	}
+	buffer.Write(text)
+
+	message.Payload = buffer.Bytes()
+	broadcastToAll(EncodeMessage(message))
+}
+
+func sendAllyChatMessage(members []*game.Player, playerID game.ID, text []byte) {
+	message := Message{
+		Type: MessageTypeAllyChatMessage,
+	}
+
+	buffer := new(bytes.Buffer)
+	writeID(buffer, playerID)
 	if len(text) > 64 {
 		text = text[:64]
 	}
 	buffer.Write(text)
 
+	message.Payload = buffer.Bytes()
+	encodedMessage := EncodeMessage(message)
+
+	var toRemove []*websocket.Conn
+	for _, member := range members {
+		if member.ID != playerID && !member.IsMarkedForRemoval() {
+			sendToClient(member.Conn, encodedMessage, &toRemove)
+		}
+	}
+
+	for _, conn := range toRemove {
+		removePlayerByConnection(conn)
+	}
+}
+
+func sendAllianceRequest(target *game.Player, requesterID game.ID) {
+	message := Message{
+		Type:    MessageTypeAllianceRequest,
+		Payload: binary.BigEndian.AppendUint16(nil, uint16(requesterID)),
+	}
+
+	sendToClient(target.Conn, EncodeMessage(message), nil)
+}
+
+func encodeAllianceUpdate(alliance game.Alliance) []byte {
+	message := Message{
+		Type: MessageTypeAllianceUpdate,
+	}
+
+	buffer := new(bytes.Buffer)
+	writeID(buffer, alliance.ID)
+	buffer.WriteByte(byte(len(alliance.Members)))
+	for _, member := range alliance.Members {
+		writeID(buffer, member.ID)
+	}
+
+	message.Payload = buffer.Bytes()
+	return EncodeMessage(message)
+}
+
+func broadcastAllianceUpdate(alliance game.Alliance) {
+	broadcastToAll(encodeAllianceUpdate(alliance))
+}
+
+func sendAlliances(player *game.Player) {
+	for _, alliance := range game.GetAlliances() {
+		sendToClient(player.Conn, encodeAllianceUpdate(alliance), nil)
+	}
+}
+
+// encodeWorldEventStart announces a world event, times are relative to when the message is sent
+func encodeWorldEventStart(event *game.WorldEvent) []byte {
+	message := Message{
+		Type: MessageTypeWorldEventStart,
+	}
+
+	now := time.Now()
+	buffer := new(bytes.Buffer)
+	writeID(buffer, event.ID)
+	buffer.WriteByte(byte(event.Type))
+	writeBasePosition(buffer, game.FloatToInt(event.Position))
+	binary.Write(buffer, binary.BigEndian, uint16(event.Radius))
+	binary.Write(buffer, binary.BigEndian, event.Value)
+	binary.Write(buffer, binary.BigEndian, uint32(max(event.StartsAt.Sub(now), 0).Milliseconds()))
+	binary.Write(buffer, binary.BigEndian, uint32(max(event.EndsAt.Sub(now), 0).Milliseconds()))
+
+	message.Payload = buffer.Bytes()
+	return EncodeMessage(message)
+}
+
+func broadcastWorldEventStart(event *game.WorldEvent) {
+	broadcastToAll(encodeWorldEventStart(event))
+}
+
+func broadcastWorldEventEnd(event *game.WorldEvent, collectedBy *game.Player) {
+	message := Message{
+		Type: MessageTypeWorldEventEnd,
+	}
+
+	buffer := new(bytes.Buffer)
+	writeID(buffer, event.ID)
+	if collectedBy != nil {
+		writeID(buffer, collectedBy.ID)
+	}
+
+	message.Payload = buffer.Bytes()
+	broadcastToAll(EncodeMessage(message))
+}
+
+// sendWorldEvents tells a joining player about the announced and active world events
+func sendWorldEvents(player *game.Player) {
+	for _, event := range game.GetWorldEvents() {
+		sendToClient(player.Conn, encodeWorldEventStart(event), nil)
+	}
+}
+
+// encodePlayZone describes the play zone around the centre of the map, times are relative to when the message is sent
+func encodePlayZone(zone game.PlayZone) []byte {
+	message := Message{
+		Type: MessageTypePlayZone,
+	}
+
+	now := time.Now()
+	buffer := new(bytes.Buffer)
+	if zone.Announced {
+		buffer.WriteByte(1)
+	} else {
+		buffer.WriteByte(0)
+	}
+	buffer.WriteByte(byte(zone.Phase))
+	binary.Write(buffer, binary.BigEndian, uint16(zone.RadiusAt(now)))
+	binary.Write(buffer, binary.BigEndian, uint16(zone.TargetRadius))
+	binary.Write(buffer, binary.BigEndian, uint32(max(zone.ShrinkStartsAt.Sub(now), 0).Milliseconds()))
+	binary.Write(buffer, binary.BigEndian, uint32(max(zone.ShrinkEndsAt.Sub(now), 0).Milliseconds()))
+
+	message.Payload = buffer.Bytes()
+	return EncodeMessage(message)
+}
+
+func broadcastPlayZone(zone game.PlayZone) {
+	broadcastToAll(encodePlayZone(zone))
+}
+
+// sendPlayZone tells a joining player about the play zone if the room plays with one
+func sendPlayZone(player *game.Player) {
+	zone, enabled := game.GetPlayZone()
+	if enabled {
+		sendToClient(player.Conn, encodePlayZone(zone), nil)
+	}
+}
+
+func broadcastRoundWon(winner *game.Player) {
+	message := Message{
+		Type: MessageTypeRoundWon,
+	}
+
+	buffer := new(bytes.Buffer)
+	if winner != nil {
+		writeID(buffer, winner.ID)
+	}
+
 	message.Payload = buffer.Bytes()
 	broadcastToAll(EncodeMessage(message))
 }
@@ -124,7 +349,7 @@ func broadcastPlayerJoined(player *game.Player) {
 	}
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(player.ID))
+	writeID(buffer, player.ID)
 	colorBytes := player.Base.Color
 	buffer.Write(colorBytes)
 	buffer.WriteByte(byte(player.SkinID))
@@ -142,7 +367,7 @@ func broadcastPlayerLeft(playerID game.ID) {
 	}
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(playerID))
+	writeID(buffer, playerID)
 
 	message.Payload = buffer.Bytes()
 	broadcastToAll(EncodeMessage(message))
@@ -160,12 +385,15 @@ func sendKilledNotification(player *game.Player, killedByID game.ID) {
 	playtimeInSeconds := uint32(player.GetPlayDuration().Seconds())
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(killedByID))
+	writeID(buffer, killedByID)
 	binary.Write(buffer, binary.BigEndian, score)
 	binary.Write(buffer, binary.BigEndian, xp)
 	binary.Write(buffer, binary.BigEndian, kills)
 	binary.Write(buffer, binary.BigEndian, playtimeInSeconds)
 
+	// End-of-life summary
+	EncodeSessionStats(buffer, player.GetSessionStats())
+
 	message.Payload = buffer.Bytes()
 	var toRemove []*websocket.Conn
 
@@ -218,27 +446,71 @@ func sendSkinData(conn *websocket.Conn, skinCategory *game.SkinCategory) {
 	sendToClient(conn, EncodeMessage(message), nil)
 }
 
-func broadcastBaseHealthUpdate(base *game.Base) {
+func sendAchievementUnlocked(player *game.Player, achievementID game.AchievementID) {
+	if player.IsMarkedForRemoval() {
+		return
+	}
+
+	message := Message{
+		Type:    MessageTypeAchievementUnlocked,
+		Payload: []byte{byte(achievementID)},
+	}
+
+	sendToClient(player.Conn, EncodeMessage(message), nil)
+}
+
+func sendHighScores(conn *websocket.Conn, boards map[game.HighScorePeriod]game.HighScoreBoard) {
+	message := Message{
+		Type: MessageTypeHighScores,
+	}
+
+	buffer := new(bytes.Buffer)
+
+	EncodeHighScores(buffer, boards)
+
+	message.Payload = buffer.Bytes()
+
+	sendToClient(conn, EncodeMessage(message), nil)
+}
+
+func sendControlGroup(player *game.Player, group byte, unitIDs []game.ID) {
+	message := Message{
+		Type: MessageTypeControlGroup,
+	}
+
+	buffer := new(bytes.Buffer)
+	buffer.WriteByte(group)
+	binary.Write(buffer, binary.BigEndian, uint16(len(unitIDs)))
+	for _, unitID := range unitIDs {
+		writeID(buffer, unitID)
+	}
+
+	message.Payload = buffer.Bytes()
+	sendToClient(player.Conn, EncodeMessage(message), nil)
+}
+
+func broadcastBaseHealthUpdate(base *game.Base, tick uint32) {
 
 	message := Message{
 		Type: MessageTypeBaseHealthUpdate,
 	}
 
 	buffer := new(bytes.Buffer)
+	writeTick(buffer, tick)
 
 	// Determine if the owner is a Player or a NeutralBase and write the relevant data
 	if player, ok := base.Owner.(*game.Player); ok {
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 		binary.Write(buffer, binary.BigEndian, player.Base.Health.Current)
 	} else if neutral, ok := base.Owner.(*game.NeutralBase); ok {
 		buffer.WriteByte(0) // Indicating it's a NeutralBase
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 		binary.Write(buffer, binary.BigEndian, neutral.Base.Health.Current)
 	}
 
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToBaseViewers(EncodeMessage(message), base)
 }
 
 func broadcastNeutralBaseCaptured(neutral *game.NeutralBase) {
@@ -247,15 +519,15 @@ func broadcastNeutralBaseCaptured(neutral *game.NeutralBase) {
 	}
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(neutral.ID))
+	writeID(buffer, neutral.ID)
 
 	// Write playerID only if CapturedBy is not nil
 	if neutral.CapturedBy != nil {
-		buffer.WriteByte(byte(neutral.CapturedBy.ID))
+		writeID(buffer, neutral.CapturedBy.ID)
 
 		// Iterate over buildings and write their details
 		for _, building := range neutral.Base.Buildings {
-			buffer.WriteByte(byte(building.ID))
+			writeBuildingRef(buffer, building)
 			buffer.WriteByte(byte(building.Type))
 			buffer.WriteByte(byte(building.Variant))
 
@@ -269,6 +541,30 @@ func broadcastNeutralBaseCaptured(neutral *game.NeutralBase) {
 	broadcastToAll(EncodeMessage(message))
 }
 
+func broadcastNeutralBaseCaptureProgress(neutral *game.NeutralBase, progress byte, capturingPlayer *game.Player, contested bool, tick uint32) {
+	message := Message{
+		Type: MessageTypeCaptureProgress,
+	}
+
+	buffer := new(bytes.Buffer)
+	writeTick(buffer, tick)
+	writeID(buffer, neutral.ID)
+	buffer.WriteByte(progress)
+	if contested {
+		buffer.WriteByte(1)
+	} else {
+		buffer.WriteByte(0)
+	}
+
+	// Write playerID only if a player is capturing
+	if capturingPlayer != nil {
+		writeID(buffer, capturingPlayer.ID)
+	}
+
+	message.Payload = buffer.Bytes()
+	broadcastToNearby(EncodeMessage(message), game.IntToFloat(neutral.Base.Position), game.NEUTRAL_BASE_CAPTURE_BROADCAST_RADIUS)
+}
+
 func broadcastBuildingPlaced(base *game.Base, buildingID game.ID) {
 	message := Message{
 		Type: MessageTypeBuildingPlaced,
@@ -284,13 +580,13 @@ func broadcastBuildingPlaced(base *game.Base, buildingID game.ID) {
 	if player_, ok := base.Owner.(*game.Player); ok {
 		player = player_
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 	} else if neutral, ok := base.Owner.(*game.NeutralBase); ok {
 		player = neutral.CapturedBy
 		buffer.WriteByte(0) // Indicating it's a NeutralBase
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 	}
-	buffer.WriteByte(byte(building.ID))
+	writeBuildingRef(buffer, building)
 	buffer.WriteByte(byte(building.Type))
 	binary.Write(buffer, binary.BigEndian, building.Position.X)
 	binary.Write(buffer, binary.BigEndian, building.Position.Y)
@@ -305,7 +601,7 @@ func broadcastBuildingPlaced(base *game.Base, buildingID game.ID) {
 	}
 
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToBaseViewers(EncodeMessage(message), base)
 }
 
 func broadcastBuildingsUpgraded(base *game.Base, buildingIDs []game.ID) {
@@ -320,21 +616,21 @@ func broadcastBuildingsUpgraded(base *game.Base, buildingIDs []game.ID) {
 	// Determine if the owner is a Player or a NeutralBase and write the relevant data
 	if player, ok := base.Owner.(*game.Player); ok {
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 	} else if neutral, ok := base.Owner.(*game.NeutralBase); ok {
 		buffer.WriteByte(0) // Indicating it's a NeutralBase
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 	}
 	buffer.WriteByte(byte(building.Variant))
 
 	// Write all buildingIDs to the buffer
 	for _, buildingID := range buildingIDs {
-		buffer.WriteByte(byte(buildingID)) // Each buildingID in the payload
+		writeID(buffer, buildingID) // Each buildingID in the payload
 	}
 
 	message.Payload = buffer.Bytes()
 
-	broadcastToAll(EncodeMessage(message))
+	broadcastToBaseViewers(EncodeMessage(message), base)
 }
 
 func broadcastBuildingsDestroyed(base *game.Base, buildingIDs []game.ID) {
@@ -347,19 +643,19 @@ func broadcastBuildingsDestroyed(base *game.Base, buildingIDs []game.ID) {
 	// Determine if the owner is a Player or a NeutralBase and write the relevant data
 	if player, ok := base.Owner.(*game.Player); ok {
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 	} else if neutral, ok := base.Owner.(*game.NeutralBase); ok {
 		buffer.WriteByte(0) // Indicating it's a NeutralBase (unowned)
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 	}
 
 	// Write all buildingIDs to the buffer
 	for _, buildingID := range buildingIDs {
-		buffer.WriteByte(byte(buildingID)) // Each buildingID in the payload
+		writeID(buffer, buildingID) // Each buildingID in the payload
 	}
 
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToBaseViewers(EncodeMessage(message), base)
 }
 
 func broadcastBulletSpawn(owner game.Owner, turretID game.ID, bullet *game.Bullet) {
@@ -372,40 +668,54 @@ func broadcastBulletSpawn(owner game.Owner, turretID game.ID, bullet *game.Bulle
 	// Determine if the owner is a Player or a NeutralBase and write the relevant data
 	if player, ok := owner.(*game.Player); ok {
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 	} else if neutral, ok := owner.(*game.NeutralBase); ok {
 		buffer.WriteByte(0) // Indicating it's a NeutralBase (unowned)
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 	}
 
-	buffer.WriteByte(byte(turretID))
-	buffer.WriteByte(byte(bullet.ID))
+	writeID(buffer, turretID)
+	writeID(buffer, bullet.ID)
 
-	binary.Write(buffer, binary.BigEndian, bullet.Position.X)
-	binary.Write(buffer, binary.BigEndian, bullet.Position.Y)
+	writePosition(buffer, bullet.SpawnPosition)
+	writeBulletTrajectory(buffer, bullet)
 
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToViewers(EncodeMessage(message), func(player *game.Player) bool {
+		if !player.CanSeeBase(owner.GetBase()) {
+			return false
+		}
+		bullet.AddViewer(player)
+		return true
+	})
 }
 
-func broadcastUnitBulletSpawn(playerID game.ID, unitID game.ID, bullet *game.Bullet) {
+func broadcastUnitBulletSpawn(playerID game.ID, unit *game.Unit, bullet *game.Bullet) {
 	message := Message{
 		Type: MessageTypeUnitSpawnBullet,
 	}
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(playerID))
-	buffer.WriteByte(byte(unitID))
-	buffer.WriteByte(byte(bullet.ID))
+	writeID(buffer, playerID)
+	writeID(buffer, unit.ID)
+	writeID(buffer, bullet.ID)
 
-	binary.Write(buffer, binary.BigEndian, bullet.Position.X)
-	binary.Write(buffer, binary.BigEndian, bullet.Position.Y)
+	writePosition(buffer, bullet.SpawnPosition)
+	writeBulletTrajectory(buffer, bullet)
 
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToViewers(EncodeMessage(message), func(player *game.Player) bool {
+		if !player.CanSee(unit) {
+			return false
+		}
+		bullet.AddViewer(player)
+		return true
+	})
 }
 
-func broadcastBulletRemove(owner game.Owner, bulletID game.ID) {
+// broadcastBulletRemove tells the players that were sent the bullet to remove it. The ID is passed on its own
+// because the bullet may have been given a new one before the event is handled
+func broadcastBulletRemove(owner game.Owner, bullet *game.Bullet, bulletID game.ID, position game.PositionFloat) {
 	message := Message{
 		Type: MessageTypeRemoveBullet,
 	}
@@ -415,19 +725,22 @@ func broadcastBulletRemove(owner game.Owner, bulletID game.ID) {
 	// Determine if the owner is a Player or a NeutralBase and write the relevant data
 	if player, ok := owner.(*game.Player); ok {
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 	} else if neutral, ok := owner.(*game.NeutralBase); ok {
 		buffer.WriteByte(0) // Indicating it's a NeutralBase (unowned)
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 	}
 
-	buffer.WriteByte(byte(bulletID))
+	writeID(buffer, bulletID)
+	writePosition(buffer, position) // Clients snap to the final position before fading the bullet out
 
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToViewers(EncodeMessage(message), bullet.IsKnownTo)
 }
 
-func broadcastBulletPositionUpdate(owner game.Owner, bullet *game.Bullet) {
+// broadcastBulletPositionUpdate corrects a bullet that left its predicted trajectory.
+// Clients continue the simulation from the position and target after the given entity update
+func broadcastBulletPositionUpdate(owner game.Owner, bullet *game.Bullet, position game.PositionFloat, targetPosition game.PositionFloat, tick uint32) {
 	message := Message{
 		Type: MessageTypeBulletPositionUpdate,
 	}
@@ -437,19 +750,20 @@ func broadcastBulletPositionUpdate(owner game.Owner, bullet *game.Bullet) {
 	// Determine if the owner is a Player or a NeutralBase and write the relevant data
 	if player, ok := owner.(*game.Player); ok {
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 	} else if neutral, ok := owner.(*game.NeutralBase); ok {
 		buffer.WriteByte(0) // Indicating it's a NeutralBase (unowned)
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 	}
 
-	buffer.WriteByte(byte(bullet.ID))
+	writeID(buffer, bullet.ID)
 
-	binary.Write(buffer, binary.BigEndian, bullet.Position.X)
-	binary.Write(buffer, binary.BigEndian, bullet.Position.Y)
+	writePosition(buffer, position)
+	writePosition(buffer, targetPosition)
+	binary.Write(buffer, binary.BigEndian, tick)
 
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToViewers(EncodeMessage(message), bullet.IsKnownTo)
 }
 
 func broadcastBarracksActivationUpdate(owner game.Owner, unitSpawning *game.UnitSpawning) {
@@ -462,13 +776,13 @@ func broadcastBarracksActivationUpdate(owner game.Owner, unitSpawning *game.Unit
 	// Determine if the owner is a Player or a NeutralBase and write the relevant data
 	if player, ok := owner.(*game.Player); ok {
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 	} else if neutral, ok := owner.(*game.NeutralBase); ok {
 		buffer.WriteByte(0) // Indicating it's a NeutralBase (unowned)
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 	}
 
-	buffer.WriteByte(byte(unitSpawning.Barracks.ID))
+	writeID(buffer, unitSpawning.Barracks.ID)
 	if unitSpawning.Activated {
 		buffer.WriteByte(1)
 	} else {
@@ -476,27 +790,28 @@ func broadcastBarracksActivationUpdate(owner game.Owner, unitSpawning *game.Unit
 	}
 
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToBaseViewers(EncodeMessage(message), owner.GetBase())
 }
 
-func broadcastUnitSpawn(owner game.Owner, barracksID game.ID, unit *game.Unit) {
+func broadcastUnitSpawn(owner game.Owner, barracksID game.ID, unit *game.Unit, tick uint32) {
 	message := Message{
 		Type: MessageTypeSpawnUnit,
 	}
 
 	buffer := new(bytes.Buffer)
+	writeTick(buffer, tick)
 
 	// Determine if the owner is a Player or a NeutralBase and write the relevant data
 	if player, ok := owner.(*game.Player); ok {
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 	} else if neutral, ok := owner.(*game.NeutralBase); ok {
 		buffer.WriteByte(0) // Indicating it's a NeutralBase (unowned)
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 	}
 
-	buffer.WriteByte(byte(barracksID))
-	buffer.WriteByte(byte(unit.ID))
+	writeID(buffer, barracksID)
+	writeID(buffer, unit.ID)
 	buffer.WriteByte(byte(unit.Type))
 	buffer.WriteByte(byte(unit.Variant))
 
@@ -504,25 +819,74 @@ func broadcastUnitSpawn(owner game.Owner, barracksID game.ID, unit *game.Unit) {
 	binary.Write(buffer, binary.BigEndian, unit.TargetPosition.Y)
 
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToViewers(EncodeMessage(message), func(player *game.Player) bool {
+		return player.CanSee(unit)
+	})
 }
 
-func broadcastUnitPositionUpdates(playerID game.ID, units []*game.Unit) {
-	message := Message{
-		Type: MessageTypeUnitPositionUpdates,
-	}
+func broadcastUnitPositionUpdates(playerID game.ID, units []*game.Unit, tick uint32) {
+	broadcastVisibleUnits(units, func(units []*game.Unit) []byte {
+		message := Message{
+			Type: MessageTypeUnitPositionUpdates,
+		}
 
-	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(playerID))
-	// Encode unit data into the buffer
-	for _, unit := range units {
-		buffer.WriteByte(byte(unit.ID))
-		binary.Write(buffer, binary.BigEndian, unit.Position.X)
-		binary.Write(buffer, binary.BigEndian, unit.Position.Y)
+		buffer := new(bytes.Buffer)
+		writeTick(buffer, tick)
+		writeID(buffer, playerID)
+		// Encode unit data into the buffer
+		for _, unit := range units {
+			writeID(buffer, unit.ID)
+			binary.Write(buffer, binary.BigEndian, unit.Position.X)
+			binary.Write(buffer, binary.BigEndian, unit.Position.Y)
+		}
+
+		message.Payload = buffer.Bytes()
+		return EncodeMessage(message)
+	})
+}
+
+// broadcastVisibleUnits sends every player a message about the units it can see. Most players see all of them
+// and share one message, players some units are hidden from get their own
+func broadcastVisibleUnits(units []*game.Unit, encode func(units []*game.Unit) []byte) {
+	var toRemove []*websocket.Conn
+	var message []byte
+
+	game.State.RLock()
+
+	for _, player := range game.State.Players {
+		if player.IsMarkedForRemoval() {
+			continue
+		}
+
+		visible := units
+		for i, unit := range units {
+			if !player.CanSee(unit) {
+				visible = make([]*game.Unit, i, len(units))
+				copy(visible, units[:i])
+				for _, unit := range units[i+1:] {
+					if player.CanSee(unit) {
+						visible = append(visible, unit)
+					}
+				}
+				break
+			}
+		}
+
+		if len(visible) == len(units) {
+			if message == nil {
+				message = encode(units)
+			}
+			sendToClient(player.Conn, message, &toRemove)
+		} else if len(visible) > 0 {
+			sendToClient(player.Conn, encode(visible), &toRemove)
+		}
 	}
 
-	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	game.State.RUnlock()
+
+	for _, conn := range toRemove {
+		removePlayerByConnection(conn)
+	}
 }
 
 // ! Like a resync for camera movement (not used yet)
@@ -532,8 +896,9 @@ func SendUnitPositionUpdate(conn *websocket.Conn, player *game.Player, unit *gam
 	}
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(player.ID))
-	buffer.WriteByte(byte(unit.ID))
+	writeTick(buffer, game.CurrentTick())
+	writeID(buffer, player.ID)
+	writeID(buffer, unit.ID)
 	binary.Write(buffer, binary.BigEndian, unit.Position.X)
 	binary.Write(buffer, binary.BigEndian, unit.Position.Y)
 
@@ -557,56 +922,137 @@ func SendUnitPositionUpdate(conn *websocket.Conn, player *game.Player, unit *gam
 	}
 }
 
-func sendInitialBulletStates(conn *websocket.Conn, bullets []*game.Bullet) {
+// sendInitialBulletStates sends a player the bullets it sees through the fog of war and records it as their viewer
+func sendInitialBulletStates(player *game.Player, bullets []*game.Bullet) {
 	message := Message{
 		Type: MessageTypeInitialBulletStates,
 	}
 
 	buffer := new(bytes.Buffer)
 	for _, bullet := range bullets {
+		position := bullet.GetPosition()
+		if bullet.Owner != game.Owner(player) && !player.CanSeePosition(position) {
+			continue
+		}
+		bullet.AddViewer(player)
+
 		// Identify the owner type and write the relevant identifier byte
 		if player, ok := bullet.Owner.(*game.Player); ok {
 			buffer.WriteByte(1) // Indicate it's a Player
-			buffer.WriteByte(byte(player.ID))
+			writeID(buffer, player.ID)
 		} else if neutral, ok := bullet.Owner.(*game.NeutralBase); ok {
 			buffer.WriteByte(0) // Indicate it's a NeutralBase
-			buffer.WriteByte(byte(neutral.ID))
+			writeID(buffer, neutral.ID)
 		}
 
 		// Write the bullet's ID and position data
-		buffer.WriteByte(byte(bullet.ID))
-		binary.Write(buffer, binary.BigEndian, bullet.Position.X)
-		binary.Write(buffer, binary.BigEndian, bullet.Position.Y)
+		writeID(buffer, bullet.ID)
+		binary.Write(buffer, binary.BigEndian, position.X)
+		binary.Write(buffer, binary.BigEndian, position.Y)
+	}
+	if buffer.Len() == 0 {
+		return
 	}
 
 	message.Payload = buffer.Bytes()
 
 	var toRemove []*websocket.Conn
 
-	sendToClient(conn, EncodeMessage(message), &toRemove)
+	sendToClient(player.Conn, EncodeMessage(message), &toRemove)
 
 	for _, conn := range toRemove {
 		removePlayerByConnection(conn)
 	}
 }
 
-func BroadcastUnitsRotationUpdate(playerID game.ID, units []*game.Unit) {
+func BroadcastUnitsRotationUpdate(playerID game.ID, units []*game.Unit, tick uint32) {
+	broadcastVisibleUnits(units, func(units []*game.Unit) []byte {
+		message := Message{
+			Type: MessageUnitsRotationUpdate,
+		}
+
+		buffer := new(bytes.Buffer)
+		writeTick(buffer, tick)
+		writeID(buffer, playerID)
+
+		for _, unit := range units {
+			writeID(buffer, unit.ID)
+			binary.Write(buffer, binary.BigEndian, unit.TargetRotation.Rotation)
+		}
+		message.Payload = buffer.Bytes()
+		return EncodeMessage(message)
+	})
+}
+
+// sendBaseVisibility tells a player which enemy bases went into the fog of war and sends the buildings of revealed bases
+func sendBaseVisibility(player *game.Player, hidden []*game.Player, revealed []*game.Player, tick uint32) {
 	message := Message{
-		Type: MessageUnitsRotationUpdate,
+		Type: MessageTypeBaseVisibility,
 	}
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(playerID))
+	writeTick(buffer, tick)
+	binary.Write(buffer, binary.BigEndian, uint16(len(hidden)))
+	for _, owner := range hidden {
+		writeID(buffer, owner.ID)
+	}
+	binary.Write(buffer, binary.BigEndian, uint16(len(revealed)))
+	for _, owner := range revealed {
+		writeID(buffer, owner.ID)
+		binary.Write(buffer, binary.BigEndian, owner.Base.Health.Get())
+
+		owner.Base.RLock()
+		binary.Write(buffer, binary.BigEndian, uint16(len(owner.Base.Buildings)))
+		for _, building := range owner.Base.Buildings {
+			writeBuildingData(buffer, building, owner)
+		}
+		owner.Base.RUnlock()
+	}
+	message.Payload = buffer.Bytes()
 
-	for _, unit := range units {
-		buffer.WriteByte(byte(unit.ID))
-		binary.Write(buffer, binary.BigEndian, unit.TargetRotation.Rotation)
+	var toRemove []*websocket.Conn
+
+	if !player.IsMarkedForRemoval() {
+		sendToClient(player.Conn, EncodeMessage(message), &toRemove)
+	}
+
+	for _, conn := range toRemove {
+		removePlayerByConnection(conn)
+	}
+}
+
+// sendUnitVisibility tells a player which enemy units went into hiding and sends the full data of revealed units
+func sendUnitVisibility(player *game.Player, hidden []*game.Unit, revealed []*game.Unit, tick uint32) {
+	message := Message{
+		Type: MessageTypeUnitVisibility,
+	}
+
+	buffer := new(bytes.Buffer)
+	writeTick(buffer, tick)
+	binary.Write(buffer, binary.BigEndian, uint16(len(hidden)))
+	for _, unit := range hidden {
+		writeID(buffer, unit.Player.ID)
+		writeID(buffer, unit.ID)
+	}
+	binary.Write(buffer, binary.BigEndian, uint16(len(revealed)))
+	for _, unit := range revealed {
+		writeID(buffer, unit.Player.ID)
+		writeUnitData(buffer, unit)
 	}
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+
+	var toRemove []*websocket.Conn
+
+	if !player.IsMarkedForRemoval() {
+		sendToClient(player.Conn, EncodeMessage(message), &toRemove)
+	}
+
+	for _, conn := range toRemove {
+		removePlayerByConnection(conn)
+	}
 }
 
-func broadcastTurretRotationUpdate(owner game.Owner, turret *game.Building, target game.PositionFloat) {
+func broadcastTurretRotationUpdate(owner game.Owner, turret *game.Building, target game.PositionFloat, tick uint32) {
 	message := Message{
 		Type: MessageTypeTurretRotationUpdate,
 	}
@@ -619,20 +1065,21 @@ func broadcastTurretRotationUpdate(owner game.Owner, turret *game.Building, targ
 	angle := math.Atan2(float64(directionY), float64(directionX))
 
 	buffer := new(bytes.Buffer)
+	writeTick(buffer, tick)
 
 	// Determine if the owner is a Player or a NeutralBase and write the relevant data
 	if player, ok := owner.(*game.Player); ok {
 		buffer.WriteByte(1) // Indicating it's a Player
-		buffer.WriteByte(byte(player.ID))
+		writeID(buffer, player.ID)
 	} else if neutral, ok := owner.(*game.NeutralBase); ok {
 		buffer.WriteByte(0) // Indicating it's a NeutralBase
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 	}
 
-	buffer.WriteByte(byte(turret.ID))
+	writeID(buffer, turret.ID)
 	binary.Write(buffer, binary.BigEndian, float32(angle))
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToBaseViewers(EncodeMessage(message), owner.GetBase())
 }
 
 func SendUnitsRotationUpdate(conn *websocket.Conn, playerID game.ID, units []*game.Unit) {
@@ -641,10 +1088,11 @@ func SendUnitsRotationUpdate(conn *websocket.Conn, playerID game.ID, units []*ga
 	}
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(playerID))
+	writeTick(buffer, game.CurrentTick())
+	writeID(buffer, playerID)
 
 	for _, unit := range units {
-		buffer.WriteByte(byte(unit.ID))
+		writeID(buffer, unit.ID)
 		binary.Write(buffer, binary.BigEndian, unit.TargetRotation.Rotation)
 	}
 
@@ -660,15 +1108,18 @@ func SendUnitsRotationUpdate(conn *websocket.Conn, playerID game.ID, units []*ga
 	}
 }
 
-func broadcastRemoveUnit(playerID game.ID, unitID game.ID) {
+// broadcastRemoveUnit announces a removed unit to the players who saw it, so hidden units do not give away their ID
+func broadcastRemoveUnit(playerID game.ID, unitID game.ID, unit *game.Unit) {
 	message := Message{
 		Type: MessageTypeRemoveUnit,
 	}
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(playerID))
-	buffer.WriteByte(byte(unitID))
+	writeID(buffer, playerID)
+	writeID(buffer, unitID)
 	message.Payload = buffer.Bytes()
-	broadcastToAll(EncodeMessage(message))
+	broadcastToViewers(EncodeMessage(message), func(player *game.Player) bool {
+		return player.CanSee(unit)
+	})
 }
 
 func broadcastRemoveSpawnProtection(playerID game.ID) {
@@ -676,7 +1127,7 @@ func broadcastRemoveSpawnProtection(playerID game.ID) {
 		Type: MessageTypeRemoveSpawnProtection,
 	}
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(playerID))
+	writeID(buffer, playerID)
 	message.Payload = buffer.Bytes()
 	broadcastToAll(EncodeMessage(message))
 }
@@ -693,7 +1144,7 @@ func broadcastLeaderboardUpdate(changes *[]game.LeaderboardEntry) {
 
 	// Encode each changed leaderboard entry
 	for _, entry := range *changes {
-		buffer.WriteByte(byte(entry.Player.ID))
+		writeID(buffer, entry.Player.ID)
 
 		// Encode the score
 		EncodeScore(buffer, entry.Score)
@@ -714,7 +1165,7 @@ func broadcastLeaderboardUpdateToAllExcept(changes *[]game.LeaderboardEntry, pla
 
 	// Encode each changed leaderboard entry
 	for _, entry := range *changes {
-		buffer.WriteByte(byte(entry.Player.ID))
+		writeID(buffer, entry.Player.ID)
 
 		// Encode the score
 		EncodeScore(buffer, entry.Score)
@@ -737,7 +1188,7 @@ func sendInitialLeaderboardUpdate(player *game.Player) {
 
 	// Encode each leaderboard entry
 	for _, entry := range leaderboardEntries {
-		buffer.WriteByte(byte(entry.Player.ID))
+		writeID(buffer, entry.Player.ID)
 
 		// Encode the score
 		EncodeScore(buffer, entry.Score)
@@ -774,14 +1225,30 @@ func SendServerVersion(conn *websocket.Conn, version byte) {
 	sendToClient(conn, EncodeMessage(message), nil)
 }
 
+// sendHeartbeat starts a time sync exchange, the client echoes the server time right away
+func sendHeartbeat(conn *websocket.Conn, rtt time.Duration) {
+	message := Message{
+		Type: MessageTypeHeartbeat,
+	}
+	buffer := new(bytes.Buffer)
+	binary.Write(buffer, binary.BigEndian, game.ServerTime())
+	binary.Write(buffer, binary.BigEndian, game.CurrentTick())
+	binary.Write(buffer, binary.BigEndian, uint16(min(rtt.Milliseconds(), math.MaxUint16)))
+
+	message.Payload = buffer.Bytes()
+
+	sendToClient(conn, EncodeMessage(message), nil)
+}
+
 func sendGameState(player *game.Player, excludePlayer *game.ID) {
 	message := Message{
 		Type: MessageTypeGameState,
 	}
 
 	buffer := new(bytes.Buffer)
+	writeTick(buffer, game.CurrentTick())
 	game.State.RLock()
-	err := PreparePlayerData(buffer, game.State.Players, excludePlayer)
+	err := PreparePlayerData(buffer, game.State.Players, excludePlayer, player)
 	if err != nil {
 		log.Printf("failed to prepare player data: %v", err)
 		game.State.RUnlock()
@@ -812,7 +1279,7 @@ func sendInitialPlayerData(player *game.Player) {
 	}
 
 	buffer := new(bytes.Buffer)
-	buffer.WriteByte(byte(player.ID))
+	writeID(buffer, player.ID)
 	colorBytes := player.Base.Color
 	buffer.Write(colorBytes)
 	buffer.WriteByte(byte(player.SkinID))
@@ -833,12 +1300,37 @@ func sendInitialPlayerData(player *game.Player) {
 	}
 }
 
-func sendResourceUpdate(player *game.Player) {
+// sendMapInfo tells a joining player which seed and layout the map was generated from
+func sendMapInfo(player *game.Player) {
+	message := Message{
+		Type: MessageTypeMapInfo,
+	}
+
+	info := game.State.Map
+	buffer := new(bytes.Buffer)
+	binary.Write(buffer, binary.BigEndian, info.Seed)
+	buffer.WriteByte(byte(info.Layout))
+	binary.Write(buffer, binary.BigEndian, uint16(info.ExpectedPlayers))
+	binary.Write(buffer, binary.BigEndian, info.Radius)
+	if game.FogOfWar {
+		buffer.WriteByte(1)
+	} else {
+		buffer.WriteByte(0)
+	}
+	buffer.WriteString(info.Name)
+
+	message.Payload = buffer.Bytes()
+
+	sendToClient(player.Conn, EncodeMessage(message), nil)
+}
+
+func sendResourceUpdate(player *game.Player, tick uint32) {
 	message := Message{
 		Type: MessageTypeResourceUpdate,
 	}
 
 	buffer := new(bytes.Buffer)
+	writeTick(buffer, tick)
 	binary.Write(buffer, binary.BigEndian, player.Resources.Power.Current)
 	message.Payload = buffer.Bytes()
 
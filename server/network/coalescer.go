@@ -0,0 +1,103 @@
+package network
+
+import (
+	"server/game"
+	"time"
+)
+
+const (
+	eventCoalesceInterval = game.ENTITY_UPDATE_INTERVAL * time.Millisecond // One entity update
+	coalescerStateTTL     = time.Minute                                    // Sent state of entities without updates is forgotten after this
+)
+
+// EventCoalescer sits between the event bus and the worker pool. Within one tick, updates of the
+// same entity are merged into the latest one and updates that would not change what clients
+// already see are dropped. Events keep their order, so they are delayed by at most one tick
+type EventCoalescer struct {
+	queue     *game.CoalescingQueue
+	lastSent  map[entityKey]sentState
+	lastPrune time.Time
+}
+
+type entityKey struct {
+	eventType game.EventType
+	entity    interface{}
+}
+
+type sentState struct {
+	state  interface{}
+	seenAt time.Time
+}
+
+func NewEventCoalescer() *EventCoalescer {
+	return &EventCoalescer{
+		queue:     game.NewCoalescingQueue(),
+		lastSent:  make(map[entityKey]sentState),
+		lastPrune: time.Now(),
+	}
+}
+
+// Run collects the events in the order they were published and hands them to out once per tick until events is closed
+func (c *EventCoalescer) Run(events <-chan game.Event, out chan<- game.Event) {
+	ticker := time.NewTicker(eventCoalesceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				c.flush(out, time.Now())
+				return
+			}
+			c.queue.Push(event)
+		case now := <-ticker.C:
+			c.flush(out, now)
+		}
+	}
+}
+
+func (c *EventCoalescer) flush(out chan<- game.Event, now time.Time) {
+	for _, event := range c.queue.Drain() {
+		if c.isNoOp(event, now) {
+			continue
+		}
+		out <- event
+	}
+
+	if now.Sub(c.lastPrune) >= coalescerStateTTL {
+		c.prune(now)
+	}
+}
+
+// isNoOp reports if an update would resend the state clients already have, otherwise the new state is remembered.
+// The state is read when the tick is flushed, the handlers send the same latest state right after
+func (c *EventCoalescer) isNoOp(event game.Event, now time.Time) bool {
+	var state interface{}
+	switch e := event.(type) {
+	case *game.ResourceUpdateEvent:
+		// The client predicts spending, so any change has to be confirmed even if the amount is the same again
+		state = e.Player.Resources.Power.Revision()
+	case *game.BaseHealthUpdateEvent:
+		state = e.Base.Health.Get()
+	case *game.TurretRotationUpdateEvent:
+		state = e.TargetPosition
+	default:
+		return false
+	}
+
+	key := entityKey{eventType: event.Type(), entity: event.(game.CoalescingEvent).CoalesceKey()}
+	last, exists := c.lastSent[key]
+	c.lastSent[key] = sentState{state: state, seenAt: now}
+
+	return exists && last.state == state
+}
+
+// prune forgets the state of entities that were not updated for a while, most of them are gone
+func (c *EventCoalescer) prune(now time.Time) {
+	for key, sent := range c.lastSent {
+		if now.Sub(sent.seenAt) > coalescerStateTTL {
+			delete(c.lastSent, key)
+		}
+	}
+	c.lastPrune = now
+}
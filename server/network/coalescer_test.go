@@ -0,0 +1,43 @@
+package network
+
+import (
+	"server/game"
+	"testing"
+	"time"
+)
+
+func TestEventCoalescerFlush(t *testing.T) {
+	c := NewEventCoalescer()
+	turret := &game.Building{}
+	out := make(chan game.Event, 10)
+	now := time.Now()
+
+	rotate := func(x float32) {
+		c.queue.Push(&game.TurretRotationUpdateEvent{Turret: turret, TargetPosition: game.PositionFloat{X: x}})
+	}
+
+	rotate(1)
+	c.queue.Push(&game.KickEvent{})
+	rotate(2)
+	c.flush(out, now)
+	if len(out) != 2 {
+		t.Fatalf("flushed %d events, expected the latest rotation and the kick", len(out))
+	}
+	if event := (<-out).(*game.TurretRotationUpdateEvent); event.TargetPosition.X != 2 {
+		t.Errorf("flushed rotation to %v, expected the latest target", event.TargetPosition)
+	}
+	<-out
+
+	// Clients already have this target
+	rotate(2)
+	c.flush(out, now)
+	if len(out) != 0 {
+		t.Errorf("a rotation to the target clients already have was sent again")
+	}
+
+	rotate(3)
+	c.flush(out, now)
+	if len(out) != 1 {
+		t.Errorf("a rotation to a new target was dropped")
+	}
+}
@@ -0,0 +1,99 @@
+package network
+
+import (
+	"compress/flate"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+// Snapshots are sent with permessage-deflate when the client negotiated it. Frequent small state
+// messages stay uncompressed, deflating them costs more CPU than it saves bandwidth.
+// The extension has no preset dictionary and gorilla never keeps the context between messages,
+// so every snapshot is compressed on its own
+var (
+	CompressionThreshold = 1024 // Bytes, smaller messages are sent uncompressed
+	CompressionLevel     = 6    // Snapshots are rare, so a better ratio is worth the extra CPU
+)
+
+// Message types carrying a full snapshot, sent on join and resync
+var snapshotMessageTypes = map[byte]bool{
+	MessageTypeGameState:           true,
+	MessageTypeInitalPlayerData:    true,
+	MessageTypeInitialBulletStates: true,
+}
+
+// CompressionStats counts the compressed snapshots and the bytes they saved
+type CompressionStats struct {
+	Messages        uint64  `json:"messages"`
+	RawBytes        uint64  `json:"rawBytes"`
+	CompressedBytes uint64  `json:"compressedBytes"`
+	Ratio           float64 `json:"ratio"` // Compressed size relative to the raw size
+}
+
+var compressionStats struct {
+	messages        atomic.Uint64
+	rawBytes        atomic.Uint64
+	compressedBytes atomic.Uint64
+}
+
+// GetCompressionStats returns the counters of all snapshots compressed since the server started
+func GetCompressionStats() CompressionStats {
+	stats := CompressionStats{
+		Messages:        compressionStats.messages.Load(),
+		RawBytes:        compressionStats.rawBytes.Load(),
+		CompressedBytes: compressionStats.compressedBytes.Load(),
+	}
+	if stats.RawBytes > 0 {
+		stats.Ratio = float64(stats.CompressedBytes) / float64(stats.RawBytes)
+	}
+	return stats
+}
+
+// setupCompression disables compression by default, it is only enabled for single snapshots if the client negotiated it
+func setupCompression(conn *websocket.Conn, r *http.Request) {
+	conn.EnableWriteCompression(false)
+	conn.SetCompressionLevel(CompressionLevel)
+
+	negotiated := false
+	for _, extensions := range r.Header.Values("Sec-Websocket-Extensions") {
+		if strings.Contains(extensions, "permessage-deflate") {
+			negotiated = true
+			break
+		}
+	}
+	setConnectionCompression(conn, negotiated)
+}
+
+// shouldCompress reports if an encoded message is a snapshot large enough to be compressed for this connection
+func shouldCompress(userConn UserConnection, message []byte) bool {
+	return userConn.Compression && len(message) >= CompressionThreshold && snapshotMessageTypes[message[0]]
+}
+
+// recordCompression measures the deflated size of a sent snapshot. gorilla does not report the
+// size it wrote, so the message is deflated once more with the same level. This only happens for snapshots
+// and runs in its own goroutine, so it never holds up the next writes to the connection
+func recordCompression(message []byte) {
+	counter := &byteCounter{}
+	writer, err := flate.NewWriter(counter, CompressionLevel)
+	if err != nil {
+		return
+	}
+	writer.Write(message)
+	writer.Close()
+
+	compressionStats.messages.Add(1)
+	compressionStats.rawBytes.Add(uint64(len(message)))
+	compressionStats.compressedBytes.Add(uint64(counter.count))
+}
+
+type byteCounter struct {
+	count int
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.count += len(p)
+	return len(p), nil
+}
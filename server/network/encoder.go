@@ -26,8 +26,8 @@ func EncodeMessage(msg Message) []byte {
 	return buffer.Bytes()
 }
 
-// PreparePlayerData prepares player data for transmission.
-func PreparePlayerData(buffer *bytes.Buffer, players map[game.ID]*game.Player, excludePlayerID *game.ID) error {
+// PreparePlayerData prepares player data for transmission. Units the viewer cannot see are left out
+func PreparePlayerData(buffer *bytes.Buffer, players map[game.ID]*game.Player, excludePlayerID *game.ID, viewer *game.Player) error {
 	numPlayers := len(players)
 
 	// If we are excluding a player, reduce the count by 1
@@ -35,7 +35,7 @@ func PreparePlayerData(buffer *bytes.Buffer, players map[game.ID]*game.Player, e
 		numPlayers--
 	}
 
-	buffer.WriteByte(byte(numPlayers))
+	binary.Write(buffer, binary.BigEndian, uint16(numPlayers))
 
 	for _, otherPlayer := range players {
 		// If excludePlayerID is not nil, skip the player with that ID
@@ -43,7 +43,7 @@ func PreparePlayerData(buffer *bytes.Buffer, players map[game.ID]*game.Player, e
 			continue
 		}
 
-		if err := writePlayerData(buffer, otherPlayer); err != nil {
+		if err := writePlayerData(buffer, otherPlayer, viewer); err != nil {
 			return err
 		}
 	}
@@ -52,23 +52,25 @@ func PreparePlayerData(buffer *bytes.Buffer, players map[game.ID]*game.Player, e
 
 // PrepareNeutralBaseData prepares neutral base data for transmission.
 func PrepareNeutralBaseData(buffer *bytes.Buffer, neutralBases []*game.NeutralBase) {
-	buffer.WriteByte(byte(len(neutralBases))) // Write number of neutral bases
+	binary.Write(buffer, binary.BigEndian, uint16(len(neutralBases))) // Write number of neutral bases
 
 	for _, neutral := range neutralBases {
 		// Write neutral base data
-		buffer.WriteByte(byte(neutral.ID))
+		writeID(buffer, neutral.ID)
 		if neutral.CapturedBy != nil {
-			buffer.WriteByte(byte(neutral.CapturedBy.ID)) // Write capturing player ID
+			writeID(buffer, neutral.CapturedBy.ID) // Write capturing player ID
 		} else {
-			buffer.WriteByte(255) // Write 255 if CapturedBy is nil
+			writeID(buffer, game.NO_ID) // Not captured
 		}
 		writeBasePosition(buffer, neutral.Base.GetPosition())
 
-		// Write current health of the base
+		// Write the archetype, current and max health of the base
+		buffer.WriteByte(byte(neutral.Type))
 		binary.Write(buffer, binary.BigEndian, neutral.Base.Health.Get())
+		binary.Write(buffer, binary.BigEndian, neutral.Base.Health.Max)
 
 		// Write buildings data
-		buffer.WriteByte(byte(len(neutral.Base.Buildings))) // Write number of buildings
+		binary.Write(buffer, binary.BigEndian, uint16(len(neutral.Base.Buildings))) // Write number of buildings
 		for _, building := range neutral.Base.Buildings {
 			writeBuildingData(buffer, building, neutral.CapturedBy)
 		}
@@ -77,7 +79,7 @@ func PrepareNeutralBaseData(buffer *bytes.Buffer, neutralBases []*game.NeutralBa
 
 // PrepareBushData prepares bush positions for transmission.
 func PrepareBushData(buffer *bytes.Buffer, bushes []game.PositionInt) {
-	buffer.WriteByte(byte(len(bushes))) // Write number of bushes
+	binary.Write(buffer, binary.BigEndian, uint16(len(bushes))) // Write number of bushes
 
 	for _, bush := range bushes {
 		writeBasePosition(buffer, bush)
@@ -85,7 +87,7 @@ func PrepareBushData(buffer *bytes.Buffer, bushes []game.PositionInt) {
 }
 
 func PrepareRockData(buffer *bytes.Buffer, rocks []game.Rock) {
-	buffer.WriteByte(byte(len(rocks))) // Write number of rocks
+	binary.Write(buffer, binary.BigEndian, uint16(len(rocks))) // Write number of rocks
 
 	// Iterate through each rock and write its data
 	for _, rock := range rocks {
@@ -95,14 +97,17 @@ func PrepareRockData(buffer *bytes.Buffer, rocks []game.Rock) {
 		// Write the size (random size between 40 and 80)
 		buffer.WriteByte(byte(rock.Size)) // Write the size (since it is an integer between 40 and 80)
 
+		// Write the shape, map files may use other shapes than hexagons
+		buffer.WriteByte(byte(rock.Shape))
+
 		// Write the rotation
 		binary.Write(buffer, binary.BigEndian, float32(rock.Polygon.Rotation))
 	}
 }
 
-func writePlayerData(buffer *bytes.Buffer, player *game.Player) error {
+func writePlayerData(buffer *bytes.Buffer, player *game.Player, viewer *game.Player) error {
 	// Write player ID
-	buffer.WriteByte(byte(player.ID))
+	writeID(buffer, player.ID)
 
 	// Write spawn protection status
 	if player.HasSpawnProtection {
@@ -111,8 +116,13 @@ func writePlayerData(buffer *bytes.Buffer, player *game.Player) error {
 		buffer.WriteByte(byte(0))
 	}
 
-	// Write current health of the base
-	binary.Write(buffer, binary.BigEndian, player.Base.Health.Get())
+	// Write current health of the base, bases in the fog of war are sent with full health until they are revealed
+	canSeeBase := viewer.CanSeeBase(player.Base)
+	if canSeeBase {
+		binary.Write(buffer, binary.BigEndian, player.Base.Health.Get())
+	} else {
+		binary.Write(buffer, binary.BigEndian, player.Base.Health.Max)
+	}
 
 	// Write color of the base
 	colorBytes := player.Base.Color
@@ -126,23 +136,31 @@ func writePlayerData(buffer *bytes.Buffer, player *game.Player) error {
 	// Write player name (fixed-size array)
 	buffer.Write(player.Name[:]) // This writes 12 bytes, regardless of name length
 
-	// Write the number of buildings for the player
-	numBuildings := len(player.Base.Buildings)
-	buffer.WriteByte(byte(numBuildings))
+	// Write the number of buildings for the player, bases in the fog of war are sent once they are revealed
+	buildings := player.Base.Buildings
+	if !canSeeBase {
+		buildings = nil
+	}
+	binary.Write(buffer, binary.BigEndian, uint16(len(buildings)))
 
 	// Write each building's data
-	for _, building := range player.Base.Buildings {
+	for _, building := range buildings {
 		if err := writeBuildingData(buffer, building, player); err != nil {
 			return err
 		}
 	}
 
-	// Write the number of units for the player
-	numUnits := len(player.Units)
-	buffer.WriteByte(byte(numUnits))
+	// Write the number of units for the player, units hidden in bushes are sent once they are revealed
+	units := make([]*game.Unit, 0, len(player.Units))
+	for _, unit := range player.Units {
+		if viewer.CanSee(unit) {
+			units = append(units, unit)
+		}
+	}
+	binary.Write(buffer, binary.BigEndian, uint16(len(units)))
 
 	// Write each unit's data
-	for _, unit := range player.Units {
+	for _, unit := range units {
 		if err := writeUnitData(buffer, unit); err != nil {
 			return err
 		}
@@ -152,7 +170,7 @@ func writePlayerData(buffer *bytes.Buffer, player *game.Player) error {
 }
 
 func writeUnitData(buffer *bytes.Buffer, unit *game.Unit) error {
-	buffer.WriteByte(byte(unit.ID))
+	writeID(buffer, unit.ID)
 	buffer.WriteByte(byte(unit.Type))
 	buffer.WriteByte(byte(unit.Variant))
 	writePosition(buffer, unit.Position)
@@ -161,7 +179,7 @@ func writeUnitData(buffer *bytes.Buffer, unit *game.Unit) error {
 
 // Helper function to write building data to buffer.
 func writeBuildingData(buffer *bytes.Buffer, building *game.Building, player *game.Player) error {
-	buffer.WriteByte(byte(building.ID))
+	writeBuildingRef(buffer, building)
 	buffer.WriteByte(byte(building.Type))
 	buffer.WriteByte(byte(building.Variant))
 
@@ -188,6 +206,37 @@ func writePosition(buffer *bytes.Buffer, position game.PositionFloat) error {
 	return nil
 }
 
+// Entity IDs are written as 2 bytes since server version 10
+const idSize = 2
+
+func writeID(buffer *bytes.Buffer, id game.ID) {
+	binary.Write(buffer, binary.BigEndian, uint16(id))
+}
+
+// Buildings are named by their ID and generation, commands naming an older building with the same ID are rejected
+const buildingRefSize = idSize + 1
+
+func writeBuildingRef(buffer *bytes.Buffer, building *game.Building) {
+	writeID(buffer, building.ID)
+	buffer.WriteByte(byte(building.Generation))
+}
+
+// writeTick stamps a state message with the entity update it describes, clients interpolate between ticks
+func writeTick(buffer *bytes.Buffer, tick uint32) {
+	binary.Write(buffer, binary.BigEndian, tick)
+}
+
+// writeBulletTrajectory writes what clients need to simulate a bullet after its spawn position:
+// target, speed, behavior and the entity update it starts moving in with its expected server time
+func writeBulletTrajectory(buffer *bytes.Buffer, bullet *game.Bullet) {
+	_, targetPosition := bullet.GetTrajectory()
+	writePosition(buffer, targetPosition)
+	binary.Write(buffer, binary.BigEndian, float32(bullet.Speed))
+	buffer.WriteByte(byte(bullet.Behavior))
+	binary.Write(buffer, binary.BigEndian, bullet.StartTick)
+	binary.Write(buffer, binary.BigEndian, bullet.StartTime)
+}
+
 func writeBasePosition(buffer *bytes.Buffer, position game.PositionInt) error {
 	binary.Write(buffer, binary.BigEndian, position.X)
 	binary.Write(buffer, binary.BigEndian, position.Y)
@@ -237,6 +286,56 @@ func EncodeScore(buffer *bytes.Buffer, score game.LeaderboardScore) {
 	}
 }
 
+// EncodeSessionStats writes the end-of-life summary. Per type counters are prefixed with the number of types
+func EncodeSessionStats(buffer *bytes.Buffer, stats game.SessionStats) {
+	buffer.WriteByte(byte(len(stats.BuildingsPlaced)))
+	for i := range stats.BuildingsPlaced {
+		binary.Write(buffer, binary.BigEndian, stats.BuildingsPlaced[i])
+		binary.Write(buffer, binary.BigEndian, stats.BuildingsUpgraded[i])
+		binary.Write(buffer, binary.BigEndian, stats.BuildingsDestroyed[i])
+	}
+
+	buffer.WriteByte(byte(len(stats.UnitsSpawned)))
+	for i := range stats.UnitsSpawned {
+		binary.Write(buffer, binary.BigEndian, stats.UnitsSpawned[i])
+		binary.Write(buffer, binary.BigEndian, stats.UnitsLost[i])
+	}
+
+	binary.Write(buffer, binary.BigEndian, stats.DamageDealt)
+	binary.Write(buffer, binary.BigEndian, stats.DamageTaken)
+	binary.Write(buffer, binary.BigEndian, stats.NeutralBasesCaptured)
+	binary.Write(buffer, binary.BigEndian, stats.NeutralBasesLost)
+	binary.Write(buffer, binary.BigEndian, stats.PeakPower)
+	binary.Write(buffer, binary.BigEndian, stats.PeakPopulation)
+}
+
+// EncodeHighScores writes every board ordered by period. Each entry holds the name (12 bytes),
+// a logged in flag (1 byte), the score (4 bytes) and the time it was achieved in unix seconds (4 bytes)
+func EncodeHighScores(buffer *bytes.Buffer, boards map[game.HighScorePeriod]game.HighScoreBoard) {
+	periods := []game.HighScorePeriod{game.HIGH_SCORE_ALL_TIME, game.HIGH_SCORE_DAILY, game.HIGH_SCORE_WEEKLY}
+
+	buffer.WriteByte(byte(len(periods)))
+	for _, period := range periods {
+		board := boards[period]
+
+		buffer.WriteByte(byte(period))
+		buffer.WriteByte(byte(len(board.Entries)))
+		for _, entry := range board.Entries {
+			var name [12]byte
+			copy(name[:], entry.Name)
+			buffer.Write(name[:])
+
+			if entry.LoggedIn {
+				buffer.WriteByte(1)
+			} else {
+				buffer.WriteByte(0)
+			}
+			binary.Write(buffer, binary.BigEndian, entry.Score)
+			binary.Write(buffer, binary.BigEndian, uint32(entry.AchievedAt.Unix()))
+		}
+	}
+}
+
 func EncodeSkinData(buffer *bytes.Buffer, skinCategory game.SkinCategory) {
 
 	// Encode the default skins
@@ -31,7 +31,7 @@ func handleMessage(conn *websocket.Conn, message []byte) {
 
 	switch messageType {
 	case MessageTypeHeartbeat:
-		break
+		handleHeartbeat(conn, payload)
 	case MessageTypeJoin:
 		handleJoinMessage(conn, payload)
 	case MessageTypeClientPlaceBuilding:
@@ -51,11 +51,29 @@ func handleMessage(conn *websocket.Conn, message []byte) {
 	case MessageTypeClientCameraUpdate:
 		handleCameraUpdate(conn, payload)
 	case MessageTypeClientRequestResync:
-		handleClientRequestResync(conn)
+		handleClientRequestResync(conn, payload)
 	case MessageTypeClientRequestSkinData:
 		handleClientRequestSkinData(conn)
+	case MessageTypeClientRequestHighScores:
+		handleClientRequestHighScores(conn)
 	case MessageTypeClientNewChatMessage:
 		handleClientNewChatMessage(conn, payload)
+	case MessageTypeClientAssignControlGroup:
+		handleAssignControlGroupMessage(conn, payload)
+	case MessageTypeClientRecallControlGroup:
+		handleRecallControlGroupMessage(conn, payload)
+	case MessageTypeClientMoveControlGroup:
+		handleMoveControlGroupMessage(conn, payload)
+	case MessageTypeClientSetFormation:
+		handleSetFormationMessage(conn, payload)
+	case MessageTypeClientAllianceRequest:
+		handleAllianceRequestMessage(conn, payload)
+	case MessageTypeClientAllianceAccept:
+		handleAllianceAcceptMessage(conn, payload)
+	case MessageTypeClientAllianceLeave:
+		handleAllianceLeaveMessage(conn)
+	case MessageTypeClientNewAllyChatMessage:
+		handleClientNewAllyChatMessage(conn, payload)
 
 	default:
 		log.Printf("Received unsupported message type: %d", messageType)
@@ -68,7 +86,7 @@ func handleJoinMessage(conn *websocket.Conn, payload []byte) {
 		return
 	}
 
-	if SERVER_REBOOTING {
+	if SERVER_REBOOTING || game.IsJoiningClosed() {
 		sendError(conn)
 		return
 	}
@@ -113,6 +131,9 @@ func handleJoinMessage(conn *websocket.Conn, payload []byte) {
 		AddPlayingDiscordAccount(userData.Discord.ID)
 	}
 
+	// Every join is a new session, its stats are submitted exactly once
+	StartSessionForConn(conn)
+
 	cleanName := filterProfanity(string(name))
 
 	var skinData game.SkinData
@@ -148,10 +169,14 @@ func handleJoinMessage(conn *websocket.Conn, payload []byte) {
 		return
 	}
 
+	sendMapInfo(player)
 	sendGameState(player, &player.ID)
 	sendUnitsRotations(player)
 	collectAndSendTrapperBullets(player)
 	sendInitialPlayerData(player)
+	sendAlliances(player)
+	sendWorldEvents(player)
+	sendPlayZone(player)
 	broadcastPlayerJoined(player)
 
 	changes, changed := game.State.Leaderboard.Update(game.State.Players)
@@ -161,35 +186,14 @@ func handleJoinMessage(conn *websocket.Conn, payload []byte) {
 	}
 }
 
-const resyncCooldown = 10 * time.Second
-
-func handleClientRequestResync(conn *websocket.Conn) {
-	player, ok := game.GetPlayerByConn(conn)
-	if !ok {
-		//log.Println("Player not found for connection with address:", conn.RemoteAddr())
-		return
-	}
-
-	lastActivity := player.LastResync
-	currentTime := time.Now()
-
-	// Check if the last activity was within the cooldown period
-	if currentTime.Sub(lastActivity) < resyncCooldown {
-		//log.Printf("Resync request is on cooldown for player %s. Try again later.\n", player.Name)
-		return
-	}
-	player.LastResync = currentTime
-
-	sendGameState(player, nil)
-	sendUnitsRotations(player)
-	collectAndSendTrapperBullets(player)
-	sendInitialLeaderboardUpdate(player)
-}
-
 func handleClientRequestSkinData(conn *websocket.Conn) {
 	sendSkinData(conn, &game.AllSkins)
 }
 
+func handleClientRequestHighScores(conn *websocket.Conn) {
+	sendHighScores(conn, game.GetHighScores())
+}
+
 func sendUnitsRotations(player *game.Player) {
 	game.State.RLock()
 	players := make([]*game.Player, 0, len(game.State.Players))
@@ -255,12 +259,12 @@ func collectAndSendTrapperBullets(player *game.Player) {
 
 	// Send collected trapper bullets to the specified player
 	if len(trapperBullets) > 0 {
-		sendInitialBulletStates(player.Conn, trapperBullets)
+		sendInitialBulletStates(player, trapperBullets)
 	}
 }
 
 func handlePlacedBuildingMessage(conn *websocket.Conn, payload []byte) {
-	if len(payload) != 9 {
+	if len(payload) != 9 && len(payload) != 13 {
 		log.Println("Invalid payload length for placed building message")
 		return
 	}
@@ -279,6 +283,12 @@ func handlePlacedBuildingMessage(conn *websocket.Conn, payload []byte) {
 	buildingType := game.BuildingType(payload[0])
 	position := getPositionFloatFromPayload(payload[1:])
 
+	// Clients sending the tick they saw get the unit positions of that tick
+	tick := game.CurrentTick()
+	if len(payload) == 13 {
+		tick = game.RewindTick(binary.BigEndian.Uint32(payload[9:13]), lagCompensationLimit(conn))
+	}
+
 	// Validate building type
 	if !game.ValidateBuildingType(buildingType) {
 		log.Println("Invalid building type")
@@ -385,7 +395,7 @@ func handlePlacedBuildingMessage(conn *websocket.Conn, payload []byte) {
 		return
 	}
 
-	if game.CheckBuildingOverlapWithUnits(player, buildingType, position) {
+	if game.CheckBuildingOverlapWithUnits(player, buildingType, position, tick) {
 		player.Resources.Power.Increment(costs)
 		SendBuildingPlacementFailed(player, buildingType)
 		return
@@ -416,11 +426,11 @@ func handlePlacedBuildingMessage(conn *websocket.Conn, payload []byte) {
 	// Update the player's last activity timestamp
 	player.SetLastActivity()
 
-	broadcastBuildingPlaced(base, building.ID)
+	game.TriggerBuildingPlacedEvent(base, building)
 }
 
 func handleUpgradeBuildingsMessage(conn *websocket.Conn, payload []byte) {
-	if len(payload) < 3 {
+	if len(payload) < 2+buildingRefSize {
 		log.Println("Invalid payload length for upgrade building message")
 		return
 	}
@@ -436,13 +446,13 @@ func handleUpgradeBuildingsMessage(conn *websocket.Conn, payload []byte) {
 	// First byte of the payload contains the flag indicating neutralBaseID presence
 	flag := payload[0]
 	if flag == 1 {
-		// If flag is 1, neutralBaseID is present in the second byte
-		if len(payload) < 4 {
+		// If flag is 1, neutralBaseID follows the flag
+		if len(payload) < 2+idSize+buildingRefSize {
 			log.Println("Payload length mismatch, neutralBaseID expected")
 			return
 		}
 
-		neutralBaseID := game.ID(payload[1]) // Get neutralBaseID from the second byte
+		neutralBaseID := getIDFromPayload(payload[1:])
 
 		neutral, ok := player.GetCapturedNeutralBase(neutralBaseID)
 		if !ok {
@@ -451,7 +461,7 @@ func handleUpgradeBuildingsMessage(conn *websocket.Conn, payload []byte) {
 		}
 
 		base = neutral.Base
-		payload = payload[2:] // Skip flag + neutralBaseID
+		payload = payload[1+idSize:] // Skip flag + neutralBaseID
 	} else {
 		payload = payload[1:] // Skip flag only
 	}
@@ -459,24 +469,24 @@ func handleUpgradeBuildingsMessage(conn *websocket.Conn, payload []byte) {
 	buildingVariant := game.BuildingVariant(payload[0])
 	payload = payload[1:] // Skip buildingVariant
 
+	refs, ok := getBuildingRefsFromPayload(payload)
+	if !ok {
+		log.Println("Invalid building IDs in upgrade building message")
+		return
+	}
+
 	// Now, process each buildingID in the payload
 	var buildingIDs []game.ID
-	for _, buildingByte := range payload {
-
-		buildingID := game.ID(buildingByte)
-
+	for _, ref := range refs {
 		// Add the buildingID to the list
-		buildingIDs = append(buildingIDs, buildingID)
+		buildingIDs = append(buildingIDs, ref.ID)
 
-		base.RLock()
-		buildings := base.Buildings
-		building, ok := buildings[buildingID]
+		// A building with an outdated generation was replaced by a new one with the same ID
+		building, ok := base.GetBuilding(ref.ID, ref.Generation)
 		if !ok {
-			base.RUnlock()
 			log.Println("Failed to upgrade building: Building not found")
 			return
 		}
-		base.RUnlock()
 
 		if !game.ValidateUpgradePath(building.Type, building.Variant, buildingVariant) {
 			log.Printf(
@@ -544,7 +554,7 @@ func handleUpgradeBuildingsMessage(conn *websocket.Conn, payload []byte) {
 			base.RemoveBulletSpawning(building)
 		}
 
-		if !base.UpgradeBuilding(buildingID, buildingVariant) {
+		if !base.UpgradeBuilding(building.ID, buildingVariant) {
 			log.Println("Could not upgrade building:", building.Type, buildingVariant)
 			// Restore resources if building upgrade failed
 			player.Resources.Power.Increment(costs)
@@ -566,7 +576,7 @@ func handleUpgradeBuildingsMessage(conn *websocket.Conn, payload []byte) {
 }
 
 func handleDestroyBuildingsMessage(conn *websocket.Conn, payload []byte) {
-	if len(payload) < 2 {
+	if len(payload) < 1+buildingRefSize {
 		log.Println("Invalid payload length for destroy building message")
 		return
 	}
@@ -584,13 +594,13 @@ func handleDestroyBuildingsMessage(conn *websocket.Conn, payload []byte) {
 	flag := payload[0]
 
 	if flag == 1 {
-		// If flag is 1, neutralBaseID is present in the second byte
-		if len(payload) < 3 {
+		// If flag is 1, neutralBaseID follows the flag
+		if len(payload) < 1+idSize+buildingRefSize {
 			log.Println("Payload length mismatch, neutralBaseID expected")
 			return
 		}
 
-		neutralBaseID := game.ID(payload[1]) // Get neutralBaseID from the second byte
+		neutralBaseID := getIDFromPayload(payload[1:])
 
 		neutral, ok := player.GetCapturedNeutralBase(neutralBaseID)
 		if !ok {
@@ -599,15 +609,27 @@ func handleDestroyBuildingsMessage(conn *websocket.Conn, payload []byte) {
 		}
 
 		base = neutral.Base
-		payload = payload[2:] // Remove the second byte (neutralBaseID) for the building IDs processing
+		payload = payload[1+idSize:] // Remove the flag and neutralBaseID for the building IDs processing
 	} else {
 		payload = payload[1:] // Remove the first byte (flag) for the building IDs processing
 	}
 
+	refs, ok := getBuildingRefsFromPayload(payload)
+	if !ok {
+		log.Println("Invalid building IDs in destroy building message")
+		return
+	}
+
 	// Now, process each buildingID in the payload
 	var buildingIDs []game.ID
-	for _, buildingByte := range payload {
-		buildingID := game.ID(buildingByte)
+	for _, ref := range refs {
+		buildingID := ref.ID
+
+		// A building with an outdated generation was replaced by a new one with the same ID, which has to stay
+		if _, ok := base.GetBuilding(buildingID, ref.Generation); !ok {
+			log.Println("Failed to remove building: Building not found, ID:", buildingID)
+			continue
+		}
 
 		// Add the buildingID to the list
 		buildingIDs = append(buildingIDs, buildingID)
@@ -628,13 +650,13 @@ func handleDestroyBuildingsMessage(conn *websocket.Conn, payload []byte) {
 }
 
 func handleMoveUnitsMessage(conn *websocket.Conn, payload []byte) {
-	if len(payload) < 6 {
+	if len(payload) < 6+idSize {
 		log.Println("Invalid payload length for move units message. Payload length:", len(payload))
 		return
 	}
 
-	numUnits := int(payload[0])
-	if numUnits <= 0 || numUnits > len(payload[1:]) {
+	numUnits := int(binary.BigEndian.Uint16(payload[0:2]))
+	if numUnits <= 0 || numUnits*idSize > len(payload[2:]) {
 		log.Println("Invalid number of units to move:", numUnits)
 		return
 	}
@@ -647,25 +669,28 @@ func handleMoveUnitsMessage(conn *websocket.Conn, payload []byte) {
 
 	player.SetLastActivity()
 
-	offset := 1
+	offset := 2
 	targetPosition := getPositionIntFromPayload(payload[offset:])
 	offset += 4
 
-	unitIDs := payload[offset:]
-	if len(unitIDs) != numUnits {
+	unitIDs, ok := getIDsFromPayload(payload[offset:])
+	if !ok || len(unitIDs) != numUnits {
 		log.Println("Mismatch between declared number of units and actual units in payload")
 		return
 	}
 
-	// Continue with normal movement processing
+	moveUnits(player, targetPosition, unitIDs)
+}
+
+// moveUnits sends the given units of a player to the target position using the player's formation
+func moveUnits(player *game.Player, targetPosition game.PositionInt, unitIDs []game.ID) {
 	// Collect valid units
 	player.RLock()
 	unitsToUpdate := make([]*game.Unit, 0, len(unitIDs))
 	validUnitCount := 0                                        // Count of valid units
 	unitPositions := make([]game.PositionInt, 0, len(unitIDs)) // To store the positions of the valid units
 
-	for _, unitIDByte := range unitIDs {
-		unitID := game.ID(unitIDByte)
+	for _, unitID := range unitIDs {
 		unit, exists := player.Units[unitID]
 		if !exists || unit.IsMarkedForRemoval() || time.Since(unit.LastTargetPositionUpdate) < time.Millisecond*50 {
 			continue
@@ -712,69 +737,170 @@ func handleMoveUnitsMessage(conn *websocket.Conn, payload []byte) {
 		return
 	}
 
-	if validUnitCount == 1 {
-		unitsToUpdate[0].SetTargetPosition(game.IntToFloat(targetPosition))
-		BroadcastUnitsRotationUpdate(player.ID, unitsToUpdate)
+	game.ArrangeFormation(player.GetFormation(), targetPosition, unitsToUpdate)
+	BroadcastUnitsRotationUpdate(player.ID, unitsToUpdate, game.CurrentTick())
+}
+
+func handleAssignControlGroupMessage(conn *websocket.Conn, payload []byte) {
+	if len(payload) < 1 {
+		log.Println("Invalid payload length for assign control group message")
+		return
+	}
+
+	player, ok := game.GetPlayerByConn(conn)
+	if !ok {
+		log.Println("Player not found for connection")
+		return
+	}
+
+	player.SetLastActivity()
+
+	group := payload[0]
+	unitIDs, ok := getIDsFromPayload(payload[1:])
+	if !ok {
+		log.Println("Invalid unit IDs in assign control group message")
 		return
 	}
 
-	// Set the spacing between units
-	const spacing = 50.0 // Space between units
-	radius := spacing    // Start radius
-	totalUnits := 0      // Count total units placed
+	groupUnits, ok := player.SetControlGroup(group, unitIDs)
+	if !ok {
+		log.Println("Invalid control group:", group)
+		return
+	}
 
-	// Store the index of the nearest unit
-	var nearestUnitIndex int
-	nearestDistance := float32(math.MaxFloat32)
+	sendControlGroup(player, group, groupUnits)
+}
 
-	for {
-		circumference := 2.0 * math.Pi * radius
-		unitsInLayer := int(circumference / spacing)
+func handleRecallControlGroupMessage(conn *websocket.Conn, payload []byte) {
+	if len(payload) != 1 {
+		log.Println("Invalid payload length for recall control group message")
+		return
+	}
 
-		// Exit if no units can fit or we placed all valid units
-		if unitsInLayer <= 0 || totalUnits >= validUnitCount {
-			break
-		}
+	player, ok := game.GetPlayerByConn(conn)
+	if !ok {
+		log.Println("Player not found for connection")
+		return
+	}
+
+	group := payload[0]
+	groupUnits, ok := player.GetControlGroup(group)
+	if !ok {
+		log.Println("Invalid control group:", group)
+		return
+	}
+
+	sendControlGroup(player, group, groupUnits)
+}
+
+func handleMoveControlGroupMessage(conn *websocket.Conn, payload []byte) {
+	if len(payload) != 5 {
+		log.Println("Invalid payload length for move control group message")
+		return
+	}
 
-		// Define the magnitude of the random offset
-		const offsetMagnitude float32 = 50.0 // Adjust this value to control how large the offset is
+	player, ok := game.GetPlayerByConn(conn)
+	if !ok {
+		log.Println("Player not found for connection with address:", conn.RemoteAddr())
+		return
+	}
 
-		// Place units for the current layer
-		for i := 0; i < unitsInLayer && totalUnits < validUnitCount; i++ {
-			angle := float64(i) * (2.0 * math.Pi / float64(unitsInLayer))
-			targetX := float32(targetPosition.X) + float32(radius)*float32(math.Cos(angle))
-			targetY := float32(targetPosition.Y) + float32(radius)*float32(math.Sin(angle))
+	player.SetLastActivity()
 
-			// Add random offset
-			offsetX := (rand.Float32() - 0.5) * offsetMagnitude
-			offsetY := (rand.Float32() - 0.5) * offsetMagnitude
+	group := payload[0]
+	groupUnits, ok := player.GetControlGroup(group)
+	if !ok {
+		log.Println("Invalid control group:", group)
+		return
+	}
 
-			// Apply the offset to target positions
-			targetX += offsetX
-			targetY += offsetY
+	if len(groupUnits) == 0 {
+		return
+	}
 
-			// Set the target position for the unit
-			unitsToUpdate[totalUnits].SetTargetPosition(game.PositionFloat{X: targetX, Y: targetY})
+	targetPosition := getPositionIntFromPayload(payload[1:])
+	moveUnits(player, targetPosition, groupUnits)
+}
 
-			// Get the current position of the unit
-			currentPosition := unitsToUpdate[totalUnits].Position
-			// Calculate distance from the current position of the unit to the targetPosition
-			distance := float32(math.Sqrt(float64((currentPosition.X-float32(targetPosition.X))*(currentPosition.X-float32(targetPosition.X)) +
-				(currentPosition.Y-float32(targetPosition.Y))*(currentPosition.Y-float32(targetPosition.Y)))))
+func handleSetFormationMessage(conn *websocket.Conn, payload []byte) {
+	if len(payload) != 1 {
+		log.Println("Invalid payload length for set formation message")
+		return
+	}
 
-			// Check if this unit is the nearest to the targetPosition
-			if distance < nearestDistance {
-				nearestDistance = distance
-				nearestUnitIndex = totalUnits
-			}
+	player, ok := game.GetPlayerByConn(conn)
+	if !ok {
+		log.Println("Player not found for connection")
+		return
+	}
 
-			totalUnits++
-		}
-		radius += spacing // Increase the radius for the next layer
+	if !player.SetFormation(game.Formation(payload[0])) {
+		log.Println("Invalid formation:", payload[0])
+	}
+}
+
+func handleAllianceRequestMessage(conn *websocket.Conn, payload []byte) {
+	if len(payload) != idSize {
+		log.Println("Invalid payload length for alliance request message")
+		return
+	}
+
+	player, ok := game.GetPlayerByConn(conn)
+	if !ok {
+		log.Println("Player not found for connection")
+		return
+	}
+
+	player.SetLastActivity()
+
+	targetID := getIDFromPayload(payload)
+	target, ok := game.GetPlayerByID(targetID)
+	if !ok {
+		log.Println("Alliance request target not found:", targetID)
+		return
+	}
+
+	if !game.RequestAlliance(player, target) {
+		return
+	}
+
+	sendAllianceRequest(target, player.ID)
+}
+
+func handleAllianceAcceptMessage(conn *websocket.Conn, payload []byte) {
+	if len(payload) != idSize {
+		log.Println("Invalid payload length for alliance accept message")
+		return
+	}
+
+	player, ok := game.GetPlayerByConn(conn)
+	if !ok {
+		log.Println("Player not found for connection")
+		return
+	}
+
+	player.SetLastActivity()
+
+	requesterID := getIDFromPayload(payload)
+	requester, ok := game.GetPlayerByID(requesterID)
+	if !ok {
+		log.Println("Alliance requester not found:", requesterID)
+		return
+	}
+
+	game.AcceptAlliance(player, requester)
+}
+
+func handleAllianceLeaveMessage(conn *websocket.Conn) {
+	player, ok := game.GetPlayerByConn(conn)
+	if !ok {
+		log.Println("Player not found for connection")
+		return
 	}
-	// Set the nearest unit's target position to the exact targetPosition if it’s not already set
-	unitsToUpdate[nearestUnitIndex].SetTargetPosition(game.IntToFloat(targetPosition))
-	BroadcastUnitsRotationUpdate(player.ID, unitsToUpdate)
+
+	player.SetLastActivity()
+
+	game.LeaveAlliance(player)
 }
 
 // isSuspiciousMovement checks if the current movement is suspicious based on the last 5 movement packages
@@ -829,7 +955,7 @@ func areUnitsGrouped(lastUnitPositions, newUnitPositions []game.PositionInt, rad
 }
 
 func handleToggleUnitSpawning(conn *websocket.Conn, payload []byte) {
-	if len(payload) > 2 {
+	if len(payload) != idSize && len(payload) != 2*idSize {
 		log.Println("Invalid payload length for upgrade building message")
 		return
 	}
@@ -840,10 +966,10 @@ func handleToggleUnitSpawning(conn *websocket.Conn, payload []byte) {
 		return
 	}
 
-	buildingID := game.ID(payload[0])
+	buildingID := getIDFromPayload(payload)
 	base := player.Base
-	if len(payload) == 2 {
-		neutralBaseID := game.ID(payload[1])
+	if len(payload) == 2*idSize {
+		neutralBaseID := getIDFromPayload(payload[idSize:])
 		neutral, ok := player.GetCapturedNeutralBase(neutralBaseID)
 		if !ok {
 			log.Println("Neutral base not captured by player")
@@ -905,7 +1031,7 @@ func handleBuyCommander(conn *websocket.Conn, payload []byte) {
 		return
 	}
 
-	broadcastUnitSpawn(player.Base.Owner, 255, unit) //! 255 to signal its a commander that is spawned
+	broadcastUnitSpawn(player.Base.Owner, game.NO_ID, unit, game.CurrentTick()) // No barracks signals a spawned commander
 }
 
 func handleBuyRepair(conn *websocket.Conn, payload []byte) {
@@ -928,7 +1054,7 @@ func handleBuyRepair(conn *websocket.Conn, payload []byte) {
 	}
 
 	player.Base.Repair()
-	broadcastBaseHealthUpdate(player.Base)
+	game.TriggerBaseHealthUpdateEvent(player.Base)
 }
 
 func handleCameraUpdate(conn *websocket.Conn, payload []byte) {
@@ -1003,16 +1129,18 @@ type PlayerMessageState struct {
 	lastMessage     string
 }
 
+const (
+	maxChatMessageLength = 64
+	chatRateLimit        = 5 * time.Second
+)
+
 var (
 	messageState = make(map[game.ID]*PlayerMessageState)
 	messageMx    sync.Mutex
 )
 
 func handleClientNewChatMessage(conn *websocket.Conn, payload []byte) {
-	maxLength := 64
-	rateLimit := 5 * time.Second
-
-	if len(payload) == 0 || len(payload) > maxLength {
+	if len(payload) == 0 || len(payload) > maxChatMessageLength {
 		log.Println("Invalid payload length for a chat message. Payload length:", len(payload))
 		return
 	}
@@ -1026,6 +1154,45 @@ func handleClientNewChatMessage(conn *websocket.Conn, payload []byte) {
 
 	player.SetLastActivity()
 
+	cleanMessageBytes, ok := prepareChatMessage(player, payload)
+	if !ok {
+		return
+	}
+
+	// Broadcast the sanitized message to all except the sender
+	broadcastChatMessage(player.ID, cleanMessageBytes)
+}
+
+func handleClientNewAllyChatMessage(conn *websocket.Conn, payload []byte) {
+	if len(payload) == 0 || len(payload) > maxChatMessageLength {
+		log.Println("Invalid payload length for an ally chat message. Payload length:", len(payload))
+		return
+	}
+
+	player, ok := game.GetPlayerByConn(conn)
+	if !ok {
+		log.Println("Player not found for connection with address:", conn.RemoteAddr())
+		return
+	}
+
+	player.SetLastActivity()
+
+	members, ok := game.GetAllianceMembers(player)
+	if !ok {
+		return
+	}
+
+	cleanMessageBytes, ok := prepareChatMessage(player, payload)
+	if !ok {
+		return
+	}
+
+	sendAllyChatMessage(members, player.ID, cleanMessageBytes)
+}
+
+// prepareChatMessage applies the rate limit and duplicate check shared by all chat channels
+// and returns the filtered message
+func prepareChatMessage(player *game.Player, payload []byte) ([]byte, bool) {
 	// Copy payload to avoid race conditions
 	message := payload[:]
 
@@ -1041,10 +1208,10 @@ func handleClientNewChatMessage(conn *websocket.Conn, payload []byte) {
 
 	// Check rate limit
 	now := time.Now()
-	if now.Sub(state.lastMessageTime) < rateLimit {
+	if now.Sub(state.lastMessageTime) < chatRateLimit {
 		messageMx.Unlock() // Release lock before returning
 		log.Println("Rate limit exceeded for player:", player.ID)
-		return
+		return nil, false
 	}
 
 	// Check for duplicate messages
@@ -1052,7 +1219,7 @@ func handleClientNewChatMessage(conn *websocket.Conn, payload []byte) {
 	if messageStr == state.lastMessage {
 		messageMx.Unlock() // Release lock before returning
 		log.Println("Duplicate message detected for player:", player.ID)
-		return
+		return nil, false
 	}
 
 	// Update message state before unlocking
@@ -1065,10 +1232,7 @@ func handleClientNewChatMessage(conn *websocket.Conn, payload []byte) {
 	cleanMessage := filterProfanity(messageStr)
 
 	// Convert the cleaned message back to bytes
-	cleanMessageBytes := []byte(cleanMessage)
-
-	// Broadcast the sanitized message to all except the sender
-	broadcastChatMessage(player.ID, cleanMessageBytes)
+	return []byte(cleanMessage), true
 }
 
 func removePlayerMessageState(playerID game.ID) {
@@ -1078,6 +1242,46 @@ func removePlayerMessageState(playerID game.ID) {
 	delete(messageState, playerID)
 }
 
+// getIDFromPayload reads an entity ID from the start of the payload
+func getIDFromPayload(payload []byte) game.ID {
+	return game.ID(binary.BigEndian.Uint16(payload[0:idSize]))
+}
+
+// getIDsFromPayload reads a list of entity IDs, ok is false if the payload ends inside an ID
+func getIDsFromPayload(payload []byte) ([]game.ID, bool) {
+	if len(payload)%idSize != 0 {
+		return nil, false
+	}
+
+	ids := make([]game.ID, 0, len(payload)/idSize)
+	for offset := 0; offset < len(payload); offset += idSize {
+		ids = append(ids, getIDFromPayload(payload[offset:]))
+	}
+	return ids, true
+}
+
+// buildingRef names a building as the client knows it
+type buildingRef struct {
+	ID         game.ID
+	Generation game.Generation
+}
+
+// getBuildingRefsFromPayload reads a list of building IDs with their generation, ok is false if the payload ends inside one
+func getBuildingRefsFromPayload(payload []byte) ([]buildingRef, bool) {
+	if len(payload)%buildingRefSize != 0 {
+		return nil, false
+	}
+
+	refs := make([]buildingRef, 0, len(payload)/buildingRefSize)
+	for offset := 0; offset < len(payload); offset += buildingRefSize {
+		refs = append(refs, buildingRef{
+			ID:         getIDFromPayload(payload[offset:]),
+			Generation: game.Generation(payload[offset+idSize]),
+		})
+	}
+	return refs, true
+}
+
 func getPositionIntFromPayload(payload []byte) game.PositionInt {
 	// Ensure payload contains at least 4 bytes (2 bytes for X, 2 bytes for Y)
 	if len(payload) < 4 {
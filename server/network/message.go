@@ -5,26 +5,30 @@ const (
 	ErrorCodeServerFull byte = 0
 )
 
-// Define message types for communication between client and server
+// Define message types for communication between client and server.
+// Entity IDs (players, alliances, neutral bases, buildings, units and bullets) and the counts of entity lists are 2 bytes.
+// Buildings are announced and commanded with their ID followed by its Generation (1 byte), IDs are reused with a new generation.
+// State messages (base health, capture progress, unit spawn, unit positions and rotations, turret rotation,
+// resources and game state) start with the tick of the entity update they describe (Tick: 4 bytes)
 const (
-	MessageTypeJoin                   byte = 0 // Player joining message (PlayerID: 1 byte, Color: 1 byte)
-	MessageTypeClientPlaceBuilding    byte = 1 // Place building message
-	MessageTypeClientUpgradeBuildings byte = 2 // Upgrade building message
-	MessageTypeClientDestroyBuildings byte = 3 // Destroy building message
-	MessageTypeClientMoveUnits        byte = 4
-	MessageTypePlayerJoined           byte = 5  // Player joined message (PlayerID: 1 byte, Color: 1 byte, Position: 4 bytes, PlayerName: variable bytes)
-	MessageTypePlayerLeft             byte = 6  // Player left message (PlayerID: 1 byte)
-	MessageTypeBaseHealthUpdate       byte = 7  // Player health message (PlayerID: 1 byte, Health: 1 byte)
-	MessageTypeBuildingPlaced         byte = 8  // Building placed message (PlayerID: 1 byte, BuildingType: 1 byte, Position: 4 bytes)
+	MessageTypeJoin                   byte = 0  // Player joining message (PlayerID: 2 bytes, Color: 1 byte)
+	MessageTypeClientPlaceBuilding    byte = 1  // Place building message (BuildingType: 1 byte, Position: 8 bytes, optional Tick: 4 bytes the client saw)
+	MessageTypeClientUpgradeBuildings byte = 2  // Upgrade building message (Flag: 1 byte, NeutralBaseID: 2 bytes if flag is 1, Variant: 1 byte, Buildings: ID and Generation each)
+	MessageTypeClientDestroyBuildings byte = 3  // Destroy building message (Flag: 1 byte, NeutralBaseID: 2 bytes if flag is 1, Buildings: ID and Generation each)
+	MessageTypeClientMoveUnits        byte = 4  // Move units (UnitCount: 2 bytes, Position: 4 bytes, UnitIDs: variable bytes)
+	MessageTypePlayerJoined           byte = 5  // Player joined message (PlayerID: 2 bytes, Color: 1 byte, Position: 4 bytes, PlayerName: variable bytes)
+	MessageTypePlayerLeft             byte = 6  // Player left message (PlayerID: 2 bytes)
+	MessageTypeBaseHealthUpdate       byte = 7  // Player health message (PlayerID: 2 bytes, Health: 1 byte)
+	MessageTypeBuildingPlaced         byte = 8  // Building placed message (PlayerID: 2 bytes, BuildingType: 1 byte, Position: 4 bytes)
 	MessageTypeBuildingsDestroyed     byte = 9  // Building destroyed message (BuildingType: 1 byte, Position: 4 bytes)
 	MessageTypeBuildingsUpgraded      byte = 10 // Building upgraded message (BuildingType: 1 byte, Position: 4 bytes)
-	MessageTypeGameState              byte = 11 // Game state message (PlayerCount: 1 byte, PlayerData: variable bytes, NeutralBaseCount: 1 byte, NeutralBaseData: variable)
+	MessageTypeGameState              byte = 11 // Game state message (PlayerCount: 2 bytes, PlayerData: variable bytes, NeutralBaseCount: 2 bytes, NeutralBaseData: variable)
 	MessageTypeInitalPlayerData       byte = 12 // Initial player data (Position: 4 bytes)
 	MessageTypeResourceUpdate         byte = 13
 	MessageTypeSpawnUnit              byte = 14
 	MessageTypeUnitPositionUpdates    byte = 15
 	MessageTypeRemoveUnit             byte = 16
-	MessageTypeKilled                 byte = 17 // Player killed notification (sent only to the killed player)
+	MessageTypeKilled                 byte = 17 // Player killed notification (sent only to the killed player), the play zone is named by the player's own ID (KillerID: 2 bytes, Score: 4 bytes, XP: 4 bytes, Kills: 4 bytes, Playtime: 4 bytes in seconds, SessionStats: variable bytes)
 	MessageTypeSpawnBullet            byte = 18
 	MessageTypeBulletPositionUpdate   byte = 19
 	MessageTypeRemoveBullet           byte = 20
@@ -39,7 +43,7 @@ const (
 	MessageUnitsRotationUpdate          byte = 30
 	MessageTypeClientCameraUpdate       byte = 31 //! Not used yet but already receiving updates from client
 	MessageTypeInitialBulletStates      byte = 32
-	MessageTypeClientRequestResync      byte = 33
+	MessageTypeClientRequestResync      byte = 33 // Resync request (optional LastSequence: 4 bytes, the count of messages the client received including replays), without it the full game state is sent
 	MessageTypeTurretRotationUpdate     byte = 34
 	MessageTypeNeutralBaseCaptured      byte = 35
 	MessageTypeClientToggleUnitSpawning byte = 36
@@ -48,12 +52,40 @@ const (
 	MessageTypeClientBuyCommander       byte = 39
 	MessageTypeClientRequestSkinData    byte = 40
 	MessageTypeSkinData                 byte = 41
-	MessageTypeHeartbeat                byte = 69
+	MessageTypeClientAssignControlGroup byte = 42 // Store units in a control group (Group: 1 byte, UnitIDs: variable bytes)
+	MessageTypeClientRecallControlGroup byte = 43 // Request the units of a control group (Group: 1 byte)
+	MessageTypeClientMoveControlGroup   byte = 44 // Move a control group (Group: 1 byte, Position: 4 bytes)
+	MessageTypeClientSetFormation       byte = 45 // Change the formation used for movement (Formation: 1 byte)
+	MessageTypeControlGroup             byte = 46 // Units of a control group (Group: 1 byte, UnitCount: 2 bytes, UnitIDs: variable bytes)
+	MessageTypeClientAllianceRequest    byte = 47 // Ask another player for an alliance (PlayerID: 2 bytes)
+	MessageTypeClientAllianceAccept     byte = 48 // Accept a pending alliance request (PlayerID: 2 bytes)
+	MessageTypeClientAllianceLeave      byte = 49 // Leave the current alliance
+	MessageTypeClientNewAllyChatMessage byte = 50 // Chat message only sent to allies (Text: variable bytes)
+	MessageTypeAllianceRequest          byte = 51 // Incoming alliance request (PlayerID: 2 bytes)
+	MessageTypeAllianceUpdate           byte = 52 // Alliance members (AllianceID: 2 bytes, MemberCount: 1 byte, PlayerIDs: variable bytes), no members means dissolved
+	MessageTypeAllyChatMessage          byte = 53 // Ally chat message (PlayerID: 2 bytes, Text: variable bytes)
+	MessageTypeCaptureProgress          byte = 54 // Capture meter (NeutralBaseID: 2 bytes, Progress: 1 byte, Contested: 1 byte, PlayerID: 2 bytes if capturing)
+	MessageTypeClientRequestHighScores  byte = 55 // Request the high score boards of this server
+	MessageTypeHighScores               byte = 56 // High score boards (BoardCount: 1 byte, per board Period: 1 byte, EntryCount: 1 byte, Entries: variable bytes)
+	MessageTypeAchievementUnlocked      byte = 57 // Achievement earned by the receiving player (AchievementID: 1 byte)
+	MessageTypeMapInfo                  byte = 58 // Map the server generated or loaded, sent on join (Seed: 8 bytes, Layout: 1 byte, ExpectedPlayers: 2 bytes, Radius: 2 bytes, FogOfWar: 1 byte, Name: variable bytes), layout 255 is a map file
+	MessageTypeUnitVisibility           byte = 59 // Enemy units hidden in or revealed from bushes (Tick: 4 bytes, HiddenCount: 2 bytes, per unit PlayerID: 2 bytes, UnitID: 2 bytes, RevealedCount: 2 bytes, per unit PlayerID: 2 bytes, unit data as in the game state)
+	MessageTypeBaseVisibility           byte = 60 // Enemy bases hidden by or revealed from the fog of war (Tick: 4 bytes, HiddenCount: 2 bytes, PlayerIDs: 2 bytes each, RevealedCount: 2 bytes, per base PlayerID: 2 bytes, Health: 2 bytes, BuildingCount: 2 bytes, buildings as in the game state)
+	MessageTypeWorldEventStart          byte = 61 // World event announced (EventID: 2 bytes, Type: 1 byte, X: 2 bytes, Y: 2 bytes, Radius: 2 bytes, Value: 2 bytes, StartsIn: 4 bytes, EndsIn: 4 bytes), times in milliseconds, value is the crate power, hazard damage per second or score multiplier
+	MessageTypeWorldEventEnd            byte = 62 // World event expired or crate collected (EventID: 2 bytes, PlayerID: 2 bytes if collected)
+	MessageTypePlayZone                 byte = 63 // Play zone around the centre of the map (Announced: 1 byte, Phase: 1 byte, Radius: 2 bytes, TargetRadius: 2 bytes, ShrinkStartsIn: 4 bytes, ShrinkEndsIn: 4 bytes), times in milliseconds
+	MessageTypeRoundWon                 byte = 64 // Last player standing in the play zone (PlayerID: 2 bytes, none if nobody survived)
+	MessageTypeHeartbeat                byte = 69 // Time sync, server (ServerTime: 4 bytes, Tick: 4 bytes, RTT: 2 bytes), client echo (ServerTime: 4 bytes, ClientTime: 4 bytes)
 	MessageTypeServerVersion            byte = 98
 	MessageTypeRebootAlertMessage       byte = 99
 	MessageTypeError                    byte = 100 // Error message type (ErrorCode: 1 byte)
 )
 
+// The session stats of MessageTypeKilled hold, with every counter 4 bytes unless noted:
+// BuildingTypeCount (1 byte), per building type Placed, Upgraded and Destroyed,
+// UnitTypeCount (1 byte), per unit type Spawned and Lost,
+// DamageDealt, DamageTaken, NeutralBasesCaptured, NeutralBasesLost, PeakPower (2 bytes), PeakPopulation (2 bytes)
+
 // Message represents a communication message.
 type Message struct {
 	Type    byte
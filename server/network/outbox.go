@@ -0,0 +1,271 @@
+package network
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"server/game"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	statsOutboxInitialBackoff = 5 * time.Second
+	statsOutboxMaxBackoff     = 10 * time.Minute
+	statsOutboxMaxAge         = 7 * 24 * time.Hour // Entries older than this are dropped
+	statsOutboxPollInterval   = time.Second
+)
+
+// StatsOutboxEntry is a stat update that has not been accepted by the auth provider yet
+type StatsOutboxEntry struct {
+	SessionID    string            `json:"sessionId"`
+	UserID       string            `json:"userId"`
+	Score        uint32            `json:"score"`
+	Kills        uint32            `json:"kills"`
+	Playtime     time.Duration     `json:"playtime"`
+	Session      game.SessionStats `json:"session"`
+	Achievements []string          `json:"achievements,omitempty"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	Attempts     int               `json:"attempts"`
+	NextAttempt  time.Time         `json:"nextAttempt"`
+
+	conn *websocket.Conn // Receives unlocked skins while still connected, not persisted
+}
+
+// StatsOutbox persists pending stat updates and retries them with exponential backoff.
+// Every entry is keyed by its session ID, so a session is never submitted twice
+type StatsOutbox struct {
+	Path    string // An empty path keeps the outbox in memory only
+	entries map[string]*StatsOutboxEntry
+	wake    chan struct{}
+	sync.Mutex
+}
+
+var statsOutbox = NewStatsOutbox("")
+
+func NewStatsOutbox(path string) *StatsOutbox {
+	return &StatsOutbox{
+		Path:    path,
+		entries: make(map[string]*StatsOutboxEntry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// StartStatsOutbox loads pending stat updates from the given file and starts delivering them
+func StartStatsOutbox(path string) {
+	statsOutbox.Lock()
+	statsOutbox.Path = path
+	if err := statsOutbox.load(); err != nil {
+		log.Printf("Failed to load stats outbox: %v", err)
+	} else if len(statsOutbox.entries) > 0 {
+		log.Printf("Loaded %d pending stat updates from %s\n", len(statsOutbox.entries), path)
+	}
+	statsOutbox.Unlock()
+
+	go statsOutbox.run()
+}
+
+// NewSessionID creates a random ID identifying one run of a player
+func NewSessionID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// Enqueue stores the stats of a finished session and triggers a delivery attempt.
+// Enqueuing a session that is already pending is ignored
+func (o *StatsOutbox) Enqueue(sessionID string, userID string, stats UserStats, conn *websocket.Conn) {
+	if userID == "" {
+		return
+	}
+	if sessionID == "" {
+		sessionID = NewSessionID()
+	}
+
+	o.Lock()
+	if _, exists := o.entries[sessionID]; exists {
+		o.Unlock()
+		return
+	}
+
+	now := time.Now()
+	o.entries[sessionID] = &StatsOutboxEntry{
+		SessionID:    sessionID,
+		UserID:       userID,
+		Score:        stats.Score,
+		Kills:        stats.Kills,
+		Playtime:     stats.Playtime,
+		Session:      stats.Session,
+		Achievements: stats.Achievements,
+		CreatedAt:    now,
+		NextAttempt:  now,
+		conn:         conn,
+	}
+	if err := o.save(); err != nil {
+		log.Printf("Failed to persist stats outbox: %v", err)
+	}
+	o.Unlock()
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns the number of stat updates waiting for delivery
+func (o *StatsOutbox) Pending() int {
+	o.Lock()
+	defer o.Unlock()
+	return len(o.entries)
+}
+
+func (o *StatsOutbox) run() {
+	ticker := time.NewTicker(statsOutboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-o.wake:
+		}
+		o.deliverDue()
+	}
+}
+
+// deliverDue submits every entry whose backoff has expired, one after another, and persists the outbox once at the end.
+// Rejected entries are dropped right away
+func (o *StatsOutbox) deliverDue() {
+	now := time.Now()
+
+	o.Lock()
+	var due []StatsOutboxEntry
+	changed := false
+	for sessionID, entry := range o.entries {
+		if now.Sub(entry.CreatedAt) > statsOutboxMaxAge {
+			log.Printf("Dropping stats of session %s for user %s after %d attempts", sessionID, entry.UserID, entry.Attempts)
+			delete(o.entries, sessionID)
+			changed = true
+			continue
+		}
+		if !now.Before(entry.NextAttempt) {
+			due = append(due, *entry)
+		}
+	}
+	o.Unlock()
+
+	for _, entry := range due {
+		newUnlockedSkins, err := Auth.UpdateUserStats(entry.UserID, UserStats{
+			SessionID:    entry.SessionID,
+			Score:        entry.Score,
+			Kills:        entry.Kills,
+			Playtime:     entry.Playtime,
+			Session:      entry.Session,
+			Achievements: entry.Achievements,
+		})
+
+		var rejected *StatsRejectedError
+		o.Lock()
+		if errors.As(err, &rejected) {
+			log.Printf("Dropping stats of session %s for user %s: %v", entry.SessionID, entry.UserID, err)
+			delete(o.entries, entry.SessionID)
+		} else if err != nil {
+			if pending, ok := o.entries[entry.SessionID]; ok {
+				pending.Attempts++
+				pending.NextAttempt = time.Now().Add(statsOutboxBackoff(pending.Attempts))
+				log.Printf("Failed to update user stats (attempt %d, retrying in %s): %v", pending.Attempts, statsOutboxBackoff(pending.Attempts), err)
+			}
+		} else {
+			delete(o.entries, entry.SessionID)
+		}
+		changed = true
+		o.Unlock()
+
+		if err == nil && entry.conn != nil && len(newUnlockedSkins) > 0 {
+			AddUnlockedSkinsLocally(entry.conn, newUnlockedSkins)
+		}
+	}
+
+	if changed {
+		o.Lock()
+		if err := o.save(); err != nil {
+			log.Printf("Failed to persist stats outbox: %v", err)
+		}
+		o.Unlock()
+	}
+}
+
+// statsOutboxBackoff doubles the delay with every failed attempt up to the maximum
+func statsOutboxBackoff(attempts int) time.Duration {
+	backoff := statsOutboxInitialBackoff
+	for i := 1; i < attempts && backoff < statsOutboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, statsOutboxMaxBackoff)
+}
+
+// load reads the pending entries, a missing file counts as an empty outbox. The caller must hold the lock
+func (o *StatsOutbox) load() error {
+	if o.Path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(o.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read stats outbox: %w", err)
+	}
+
+	var entries []*StatsOutboxEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse stats outbox: %w", err)
+	}
+
+	for _, entry := range entries {
+		// Retry everything right away after a restart
+		entry.NextAttempt = time.Now()
+		o.entries[entry.SessionID] = entry
+	}
+	return nil
+}
+
+// save writes all pending entries, the caller must hold the lock
+func (o *StatsOutbox) save() error {
+	if o.Path == "" {
+		return nil
+	}
+
+	entries := make([]*StatsOutboxEntry, 0, len(o.entries))
+	for _, entry := range o.entries {
+		entries = append(entries, entry)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats outbox: %w", err)
+	}
+	return writeFileAtomic(o.Path, data)
+}
+
+// writeFileAtomic writes to a temporary file first so a crash never leaves a half written file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,158 @@
+package network
+
+import (
+	"encoding/binary"
+	"log"
+	"server/game"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	resyncJournalSize     = 1024             // Messages kept per connection for incremental resyncs
+	resyncJournalMaxBytes = 512 * 1024       // Older messages are dropped once the kept ones exceed this
+	resyncCooldown        = 10 * time.Second // Between two full snapshots
+	replayCooldown        = time.Second      // Between two incremental resyncs
+)
+
+// Messages that are never replayed, they only describe the moment they were sent
+var unreplayableMessageTypes = map[byte]bool{
+	MessageTypeHeartbeat: true,
+}
+
+// SendJournal numbers the messages sent to one connection and keeps the most recent ones.
+// The first message of a connection is 1, so a client knows the sequence number of a message by
+// counting what it received. Replayed messages are journaled again and count like any other.
+// A client that fell behind reports the sequence number of the last message it received and is
+// only sent what came after it
+type SendJournal struct {
+	seq        uint32         // Sequence number of the last sent message
+	entries    []journalEntry // Oldest first
+	bytes      int
+	lastReplay time.Time
+	sync.Mutex
+}
+
+type journalEntry struct {
+	seq     uint32
+	message []byte // Shared with the other receivers of a broadcast, never modified
+}
+
+// record appends a sent message and drops the oldest ones beyond the journal limits
+func (j *SendJournal) record(message []byte) {
+	j.Lock()
+	defer j.Unlock()
+
+	j.seq++
+	j.entries = append(j.entries, journalEntry{seq: j.seq, message: message})
+	j.bytes += len(message)
+
+	for len(j.entries) > resyncJournalSize || j.bytes > resyncJournalMaxBytes {
+		j.bytes -= len(j.entries[0].message)
+		j.entries[0] = journalEntry{}
+		j.entries = j.entries[1:]
+	}
+}
+
+// missedSince returns the replayable messages sent after the acknowledged sequence number. ok is false
+// if some of them are no longer kept or the client acknowledged more than was sent
+func (j *SendJournal) missedSince(ack uint32) (missed [][]byte, ok bool) {
+	j.Lock()
+	defer j.Unlock()
+
+	if ack > j.seq {
+		return nil, false
+	}
+	if ack == j.seq {
+		return nil, true
+	}
+	if len(j.entries) == 0 || j.entries[0].seq > ack+1 {
+		return nil, false
+	}
+
+	for _, entry := range j.entries[ack+1-j.entries[0].seq:] {
+		if !unreplayableMessageTypes[entry.message[0]] {
+			missed = append(missed, entry.message)
+		}
+	}
+	return missed, true
+}
+
+// handleClientRequestResync answers a resync request. A client that sends the sequence number of the
+// last message it received is sent only the messages after it, the full game state is the fallback
+// when they are no longer kept or the client did not send one
+func handleClientRequestResync(conn *websocket.Conn, payload []byte) {
+	player, ok := game.GetPlayerByConn(conn)
+	if !ok {
+		//log.Println("Player not found for connection with address:", conn.RemoteAddr())
+		return
+	}
+
+	switch len(payload) {
+	case 0:
+	case 4:
+		if replayMissedMessages(conn, binary.BigEndian.Uint32(payload)) {
+			return
+		}
+	default:
+		log.Println("Invalid payload length for resync request")
+		return
+	}
+
+	lastActivity := player.LastResync
+	currentTime := time.Now()
+
+	// Check if the last activity was within the cooldown period
+	if currentTime.Sub(lastActivity) < resyncCooldown {
+		//log.Printf("Resync request is on cooldown for player %s. Try again later.\n", player.Name)
+		return
+	}
+	player.LastResync = currentTime
+
+	sendGameState(player, nil)
+	sendUnitsRotations(player)
+	collectAndSendTrapperBullets(player)
+	sendAlliances(player)
+	sendWorldEvents(player)
+	sendPlayZone(player)
+	sendInitialLeaderboardUpdate(player)
+}
+
+// replayMissedMessages resends the messages sent after the acknowledged sequence number and reports if the
+// request was handled, a request on cooldown is handled by ignoring it. The journal is read and the messages
+// are written under the write lock of the connection, so nothing sent in between overtakes the replayed messages
+func replayMissedMessages(conn *websocket.Conn, ack uint32) bool {
+	userConn, ok := getUserConnection(conn)
+	if !ok || userConn.Journal == nil {
+		return false
+	}
+
+	userConn.WriteLock.Lock()
+	defer userConn.WriteLock.Unlock()
+
+	journal := userConn.Journal
+	journal.Lock()
+	onCooldown := time.Since(journal.lastReplay) < replayCooldown
+	if !onCooldown {
+		journal.lastReplay = time.Now()
+	}
+	journal.Unlock()
+	if onCooldown {
+		return true
+	}
+
+	missed, ok := journal.missedSince(ack)
+	if !ok {
+		return false
+	}
+
+	// Replayed messages are journaled again with new sequence numbers, the client keeps counting them
+	for _, message := range missed {
+		if writeToClient(conn, userConn, message, nil) != nil {
+			break
+		}
+	}
+	return true
+}
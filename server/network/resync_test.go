@@ -0,0 +1,93 @@
+package network
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// numbered is a game state message that carries the sequence number it is expected to be recorded with
+func numbered(seq int, size int) []byte {
+	message := make([]byte, max(size, 5))
+	message[0] = MessageTypeGameState
+	binary.BigEndian.PutUint32(message[1:], uint32(seq))
+	return message
+}
+
+func seqsOf(messages [][]byte) []uint32 {
+	seqs := make([]uint32, len(messages))
+	for i, message := range messages {
+		seqs[i] = binary.BigEndian.Uint32(message[1:])
+	}
+	return seqs
+}
+
+func TestSendJournalReplay(t *testing.T) {
+	journal := &SendJournal{}
+	if missed, ok := journal.missedSince(0); !ok || len(missed) != 0 {
+		t.Fatalf("nothing was sent yet but %d messages were replayed (%v)", len(missed), ok)
+	}
+
+	for seq := 1; seq <= 10; seq++ {
+		journal.record(numbered(seq, 8))
+	}
+
+	missed, ok := journal.missedSince(7)
+	if !ok {
+		t.Fatal("messages after 7 are kept but could not be replayed")
+	}
+	if got := seqsOf(missed); len(got) != 3 || got[0] != 8 || got[2] != 10 {
+		t.Errorf("replayed %v after 7, expected 8 to 10", got)
+	}
+
+	if missed, ok := journal.missedSince(10); !ok || len(missed) != 0 {
+		t.Errorf("an up to date client was replayed %d messages (%v)", len(missed), ok)
+	}
+	if _, ok := journal.missedSince(11); ok {
+		t.Error("a client acknowledging more than was sent was not sent a full resync")
+	}
+}
+
+func TestSendJournalEviction(t *testing.T) {
+	journal := &SendJournal{}
+	for seq := 1; seq <= resyncJournalSize+5; seq++ {
+		journal.record(numbered(seq, 8))
+	}
+	if _, ok := journal.missedSince(4); ok {
+		t.Error("message 5 was dropped by the count limit but a replay from 4 succeeded")
+	}
+	missed, ok := journal.missedSince(5)
+	if !ok || len(missed) != resyncJournalSize || seqsOf(missed)[0] != 6 {
+		t.Errorf("replay from the oldest kept message returned %d messages (%v)", len(missed), ok)
+	}
+
+	// Only 8 messages fit in the byte limit, 1 and 2 are dropped
+	journal = &SendJournal{}
+	for seq := 1; seq <= 10; seq++ {
+		journal.record(numbered(seq, resyncJournalMaxBytes/8))
+	}
+	if _, ok := journal.missedSince(1); ok {
+		t.Error("message 2 was dropped by the byte limit but a replay from 1 succeeded")
+	}
+	if missed, ok := journal.missedSince(2); !ok || len(missed) != 8 {
+		t.Errorf("replay after the byte eviction returned %d messages (%v), expected 8", len(missed), ok)
+	}
+}
+
+func TestSendJournalSkipsHeartbeats(t *testing.T) {
+	journal := &SendJournal{}
+	for seq := 1; seq <= 6; seq++ {
+		if seq%2 == 0 {
+			journal.record([]byte{MessageTypeHeartbeat})
+		} else {
+			journal.record(numbered(seq, 8))
+		}
+	}
+
+	missed, ok := journal.missedSince(0)
+	if got := seqsOf(missed); !ok || len(got) != 3 || got[0] != 1 || got[1] != 3 || got[2] != 5 {
+		t.Errorf("replayed %v (%v), expected only the game states 1, 3 and 5", got, ok)
+	}
+	if missed, _ := journal.missedSince(5); len(missed) != 0 {
+		t.Errorf("a missed heartbeat was replayed")
+	}
+}
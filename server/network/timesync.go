@@ -0,0 +1,187 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"server/game"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	timeSyncInterval   = time.Second
+	timeSyncMaxRTT     = 10000 // Milliseconds, older echoes are ignored
+	rttSmoothing       = 0.125 // Weight of a new sample, as for TCP's smoothed round trip time
+	jitterSmoothing    = 0.25
+	latencyLogInterval = time.Minute
+
+	lagCompensationSlack = 100 * time.Millisecond // Interpolation delay and jitter on top of the round trip
+)
+
+// ConnectionClock estimates the round trip time and clock offset of one client from heartbeat exchanges.
+// The server sends its time, the client echoes it together with its own time right away
+type ConnectionClock struct {
+	RTT     float64 // Smoothed round trip time in milliseconds
+	Jitter  float64 // Smoothed deviation of the round trip time in milliseconds
+	Offset  float64 // Client clock minus server clock in milliseconds
+	Samples int
+	sync.Mutex
+}
+
+// addSample folds one exchange into the estimates, the first sample is taken as is
+func (c *ConnectionClock) addSample(rtt float64, offset float64) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Samples == 0 {
+		c.RTT = rtt
+		c.Jitter = rtt / 2
+		c.Offset = offset
+	} else {
+		c.Jitter += jitterSmoothing * (math.Abs(rtt-c.RTT) - c.Jitter)
+		c.RTT += rttSmoothing * (rtt - c.RTT)
+		c.Offset += rttSmoothing * (offset - c.Offset)
+	}
+	c.Samples++
+}
+
+// Estimate returns the smoothed round trip time and jitter, ok is false before the first exchange
+func (c *ConnectionClock) Estimate() (rtt time.Duration, jitter time.Duration, ok bool) {
+	c.Lock()
+	defer c.Unlock()
+	return time.Duration(c.RTT * float64(time.Millisecond)), time.Duration(c.Jitter * float64(time.Millisecond)), c.Samples > 0
+}
+
+// GetClockByConn returns the clock estimates of a connection
+func GetClockByConn(conn *websocket.Conn) (*ConnectionClock, bool) {
+	connectionMutex.Lock()
+	defer connectionMutex.Unlock()
+
+	userConn, exists := activeConnections[conn]
+	if !exists || userConn.Clock == nil {
+		return nil, false
+	}
+	return userConn.Clock, true
+}
+
+// GetConnectionRTT returns the smoothed round trip time of a connection
+func GetConnectionRTT(conn *websocket.Conn) (time.Duration, bool) {
+	clock, ok := GetClockByConn(conn)
+	if !ok {
+		return 0, false
+	}
+	rtt, _, ok := clock.Estimate()
+	return rtt, ok
+}
+
+// lagCompensationLimit returns how far commands of a connection may be rewound, about how far the client is behind.
+// Clients without an estimate yet get the full window
+func lagCompensationLimit(conn *websocket.Conn) time.Duration {
+	limit := game.LAG_COMPENSATION_WINDOW * time.Millisecond
+	if rtt, ok := GetConnectionRTT(conn); ok {
+		limit = min(limit, rtt+lagCompensationSlack)
+	}
+	return limit
+}
+
+// startTimeSyncLoop sends a heartbeat to every connection and periodically logs the latency of the players
+func startTimeSyncLoop() {
+	ticker := time.NewTicker(timeSyncInterval)
+	defer ticker.Stop()
+
+	lastLog := time.Now()
+	for range ticker.C {
+		connectionMutex.Lock()
+		clocks := make(map[*websocket.Conn]*ConnectionClock, len(activeConnections))
+		for conn, userConn := range activeConnections {
+			clocks[conn] = userConn.Clock
+		}
+		connectionMutex.Unlock()
+
+		for conn, clock := range clocks {
+			rtt, _, _ := clock.Estimate()
+			sendHeartbeat(conn, rtt)
+		}
+
+		if time.Since(lastLog) >= latencyLogInterval {
+			logPlayerLatency()
+			lastLog = time.Now()
+		}
+	}
+}
+
+// handleHeartbeat completes a time sync exchange. An empty heartbeat only keeps the connection alive
+func handleHeartbeat(conn *websocket.Conn, payload []byte) {
+	if len(payload) == 0 {
+		return
+	}
+	if len(payload) != 8 {
+		log.Println("Invalid payload length for heartbeat")
+		return
+	}
+
+	clock, ok := GetClockByConn(conn)
+	if !ok {
+		return
+	}
+
+	now := game.ServerTime()
+	echoedServerTime := binary.BigEndian.Uint32(payload[0:4])
+	clientTime := binary.BigEndian.Uint32(payload[4:8])
+
+	// Unsigned subtraction stays correct when the server time wraps around
+	rtt := now - echoedServerTime
+	if rtt > timeSyncMaxRTT {
+		return
+	}
+
+	// The client answered about half a round trip after the server sent its time
+	offset := float64(int32(clientTime-echoedServerTime)) - float64(rtt)/2
+	clock.addSample(float64(rtt), offset)
+}
+
+// logPlayerLatency logs the round trip time of every player, slowest first
+func logPlayerLatency() {
+	type latency struct {
+		name   string
+		rtt    time.Duration
+		jitter time.Duration
+	}
+
+	game.State.RLock()
+	players := make([]*game.Player, 0, len(game.State.Players))
+	for _, player := range game.State.Players {
+		players = append(players, player)
+	}
+	game.State.RUnlock()
+
+	var latencies []latency
+	for _, player := range players {
+		clock, ok := GetClockByConn(player.Conn)
+		if !ok {
+			continue
+		}
+		if rtt, jitter, ok := clock.Estimate(); ok {
+			latencies = append(latencies, latency{name: game.HighScoreName(player.Name), rtt: rtt, jitter: jitter})
+		}
+	}
+	if len(latencies) == 0 {
+		return
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i].rtt > latencies[j].rtt
+	})
+
+	entries := make([]string, len(latencies))
+	for i, l := range latencies {
+		entries[i] = fmt.Sprintf("%s %dms±%d", l.name, l.rtt.Milliseconds(), l.jitter.Milliseconds())
+	}
+	log.Printf("Player latency: %s", strings.Join(entries, ", "))
+}
@@ -1,14 +1,9 @@
 package network
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
 	"server/game"
 	"sync"
-	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -17,6 +12,7 @@ import (
 type UserData struct {
 	ClientIP    string
 	Fingerprint *uint32
+	SessionID   string         // Changes with every join, used to submit the stats of a run only once
 	Role        string         `json:"role"`
 	Discord     DiscordDetails `json:"discord"`
 	Skins       SkinDetails    `json:"skins"`
@@ -33,8 +29,14 @@ type SkinDetails struct {
 
 // UserConnection holds user data associated with a WebSocket connection.
 type UserConnection struct {
-	UserData UserData        // The user data
-	Conn     *websocket.Conn // WebSocket connection
+	UserData UserData         // The user data
+	Conn     *websocket.Conn  // WebSocket connection
+	Clock    *ConnectionClock // Round trip time and clock offset estimates
+	Journal  *SendJournal     // Recently sent messages for incremental resyncs
+
+	WriteLock *sync.Mutex // Serializes writes, gorilla allows one concurrent writer per connection
+
+	Compression bool // Client negotiated permessage-deflate
 }
 
 var (
@@ -75,6 +77,10 @@ func StoreUserData(conn *websocket.Conn, userData UserData) bool {
 	activeConnections[conn] = UserConnection{
 		UserData: userData,
 		Conn:     conn,
+		Clock:    &ConnectionClock{},
+		Journal:  &SendJournal{},
+
+		WriteLock: &sync.Mutex{},
 	}
 
 	// Index the connection by IP (store multiple connections per IP)
@@ -83,6 +89,26 @@ func StoreUserData(conn *websocket.Conn, userData UserData) bool {
 	return true
 }
 
+// setConnectionCompression stores if snapshots can be compressed for the connection
+func setConnectionCompression(conn *websocket.Conn, enabled bool) {
+	connectionMutex.Lock()
+	defer connectionMutex.Unlock()
+
+	if userConn, exists := activeConnections[conn]; exists {
+		userConn.Compression = enabled
+		activeConnections[conn] = userConn
+	}
+}
+
+// getUserConnection returns a copy of the connection entry, the pointers in it are shared
+func getUserConnection(conn *websocket.Conn) (UserConnection, bool) {
+	connectionMutex.Lock()
+	defer connectionMutex.Unlock()
+
+	userConn, exists := activeConnections[conn]
+	return userConn, exists
+}
+
 // GetUserDataByConn retrieves the UserData for a given WebSocket connection.
 func GetUserDataByConn(conn *websocket.Conn) (UserData, bool) {
 	connectionMutex.Lock()
@@ -255,12 +281,16 @@ func MapRoleToPermission(role string) game.Permission {
 }
 
 type UserStatsPayload struct {
-	UserId string `json:"userId"`
-	Data   struct {
+	UserId    string `json:"userId"`
+	SessionId string `json:"sessionId,omitempty"`
+	Data      struct {
 		Score    int `json:"score"`
 		XP       int `json:"xp"`
 		Kills    int `json:"kills"`
 		Playtime int `json:"playtime"`
+
+		Session      game.SessionStats `json:"session"`
+		Achievements []string          `json:"achievements,omitempty"`
 	} `json:"data"`
 }
 
@@ -268,76 +298,41 @@ func ScoreToXP(score uint32) uint32 {
 	return score / 1000
 }
 
-// Send the progression update request
-func UpdateUserStats(userId string, score uint32, kills uint32, playtime time.Duration) ([]int, bool) {
-	// Validate input
-	if userId == "" {
-		return nil, false
-	}
-
-	// Convert playtime to seconds (playtime is given in Duration, so convert to seconds)
-	playtimeSeconds := int(playtime.Seconds()) // Convert to seconds
-
-	// Construct the payload
-	payload := UserStatsPayload{
-		UserId: userId,
-		Data: struct {
-			Score    int `json:"score"`
-			XP       int `json:"xp"`
-			Kills    int `json:"kills"`
-			Playtime int `json:"playtime"`
-		}{
-			Score:    int(score),
-			XP:       int(ScoreToXP(score)),
-			Kills:    int(kills),
-			Playtime: playtimeSeconds, // Transmitting playtime in seconds
-		},
-	}
-
-	// Convert the payload to JSON
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("Failed to marshal JSON: %v", err)
-		return nil, false
-	}
-
-	// Create the HTTP request
-	req, err := http.NewRequest(http.MethodPost, "https://auth.blobl.io/api/user/update/stats", bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		log.Printf("Failed to create HTTP request: %v", err)
-		return nil, false
-	}
-
-	req.Header.Set("Content-Type", "application/json")
+// NewUserStatsPayload converts the stats of a run into the format of the stats update API
+func NewUserStatsPayload(userId string, stats UserStats) UserStatsPayload {
+	var payload UserStatsPayload
+	payload.UserId = userId
+	payload.SessionId = stats.SessionID
+	payload.Data.Score = int(stats.Score)
+	payload.Data.XP = int(ScoreToXP(stats.Score))
+	payload.Data.Kills = int(stats.Kills)
+	payload.Data.Playtime = int(stats.Playtime.Seconds()) // Transmitting playtime in seconds
+	payload.Data.Session = stats.Session
+	payload.Data.Achievements = stats.Achievements
+	return payload
+}
 
-	// Use an HTTP client with a timeout
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("HTTP request failed: %v", err)
-		return nil, false
+// SubmitUserStats queues the stats of a finished session for reliable delivery.
+// Unlocked skins are applied to the connection once the update went through
+func SubmitUserStats(userData UserData, conn *websocket.Conn, stats UserStats) {
+	if userData.Discord.ID == "" {
+		return
 	}
-	defer resp.Body.Close()
 
-	// Handle the response
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Failed to update progression: %s", resp.Status)
-		return nil, false
-	}
+	statsOutbox.Enqueue(userData.SessionID, userData.Discord.ID, stats, conn)
+}
 
-	// Parse the response body to get the newly unlocked skins
-	var response struct {
-		Message            string `json:"message"`
-		NewlyUnlockedSkins []int  `json:"newlyUnlockedSkins"`
-	}
+// StartSessionForConn assigns a new session ID to the connection when a player joins
+func StartSessionForConn(conn *websocket.Conn) (string, bool) {
+	connectionMutex.Lock()
+	defer connectionMutex.Unlock()
 
-	// Decode the JSON response
-	err = json.NewDecoder(resp.Body).Decode(&response)
-	if err != nil {
-		log.Printf("Failed to parse response JSON: %v", err)
-		return nil, false
+	userConn, exists := activeConnections[conn]
+	if !exists {
+		return "", false
 	}
 
-	// Return the newly unlocked skins
-	return response.NewlyUnlockedSkins, true
+	userConn.UserData.SessionID = NewSessionID()
+	activeConnections[conn] = userConn
+	return userConn.UserData.SessionID, true
 }
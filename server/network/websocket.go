@@ -10,13 +10,14 @@ import (
 	"golang.org/x/time/rate"
 )
 
-var SERVER_VERSION byte = 6
+var SERVER_VERSION byte = 19
 var SERVER_REBOOTING bool = false
 
 var (
 	upgrader = websocket.Upgrader{
-		ReadBufferSize:  8192,
-		WriteBufferSize: 16192,
+		ReadBufferSize:    8192,
+		WriteBufferSize:   16192,
+		EnableCompression: true, // Only used for snapshots, see sendToClient
 	}
 	limiter = rate.NewLimiter(rate.Every(time.Second), 5) // Global rate limiter for the server
 )
@@ -25,6 +26,7 @@ func init() {
 	workerPool = NewWorkerPool(4)
 	// Start listening for events from the game package
 	go listenForEvents()
+	go startTimeSyncLoop()
 }
 
 var workerPool *WorkerPool
@@ -36,155 +38,140 @@ func handleEvent(event game.Event) {
 		}
 	}()
 	// Handle the event based on its type
-	switch event.Type {
-	case game.ResourceUpdate:
-		player := event.Payload.(*game.Player)
-		sendResourceUpdate(player)
-	case game.UnitSpawn:
-		e := event.Payload.(*game.UnitSpawnEvent)
+	switch e := event.(type) {
+	case *game.ResourceUpdateEvent:
+		player := e.Player
+		sendResourceUpdate(player, e.Tick)
+	case *game.UnitSpawnEvent:
 		unit := e.Unit
 		barracks := e.Barracks
-		broadcastUnitSpawn(barracks.Owner, barracks.ID, unit)
-	case game.UnitPositionUpdates:
-		e := event.Payload.(*game.UnitPositionUpdatesEvent)
+		broadcastUnitSpawn(barracks.Owner, barracks.ID, unit, e.Tick)
+	case *game.UnitPositionUpdatesEvent:
 		player := e.Player
 		units := e.Units
-		broadcastUnitPositionUpdates(player.ID, units)
-	case game.UnitsRotationUpdate:
-		e := event.Payload.(*game.UnitsTargetPointUpdateEvent)
+		broadcastUnitPositionUpdates(player.ID, units, e.Tick)
+	case *game.UnitsTargetPointUpdateEvent:
 		player := e.Player
 		units := e.Units
-		BroadcastUnitsRotationUpdate(player.ID, units)
-	case game.UnitRemove:
-		e := event.Payload.(*game.UnitRemoveEvent)
-		player := e.Player
-		unitID := e.UnitID
-		broadcastRemoveUnit(player.ID, unitID)
-	case game.TurretRotationUpdate:
-		e := event.Payload.(*game.TurretRotationUpdateEvent)
+		BroadcastUnitsRotationUpdate(player.ID, units, e.Tick)
+	case *game.UnitRemoveEvent:
+		broadcastRemoveUnit(e.Player.ID, e.UnitID, e.Unit)
+	case *game.TurretRotationUpdateEvent:
 		owner := e.Owner
 		turret := e.Turret
 		targetPosition := e.TargetPosition
-		broadcastTurretRotationUpdate(owner, turret, targetPosition)
-	case game.BuildingRemoved:
+		broadcastTurretRotationUpdate(owner, turret, targetPosition, e.Tick)
+	case *game.BuildingRemovedEvent:
 		/*
 			Only gets called when a builing got destroyed trough an enemy,
 			so sending destroyed buildings one by one here is still okay...
 
 			If nothing else todo, try packaging all destroyed buildings in one package.
 		*/
-		e := event.Payload.(*game.BuildingRemovedEvent)
 		base := e.Base
 		building := e.Building
 		broadcastBuildingsDestroyed(base, []game.ID{building.ID})
-	case game.BuildingPlaced:
-		e := event.Payload.(*game.BuildingPlacedEvent)
+	case *game.BuildingPlacedEvent:
 		base := e.Base
 		building := e.Building
 		broadcastBuildingPlaced(base, building.ID)
-	case game.BaseHealthUpdate:
-		base := event.Payload.(*game.Base)
-		broadcastBaseHealthUpdate(base)
-	case game.PlayerKilled:
-		e := event.Payload.(*game.PlayerKilledEvent)
+	case *game.BaseHealthUpdateEvent:
+		base := e.Base
+		broadcastBaseHealthUpdate(base, e.Tick)
+	case *game.PlayerKilledEvent:
 		player := e.Player
 		killer := e.Killer
 
-		sendKilledNotification(player, killer.ID)
+		killedByID := player.ID // Destroyed by the play zone
+		if killer != nil {
+			killedByID = killer.ID
+		}
+
+		sendKilledNotification(player, killedByID)
 		broadcastPlayerLeft(player.ID)
 
-		userData, userOk := GetUserDataByConn(player.Conn)
+		userData, _ := GetUserDataByConn(player.Conn)
 		_, playerScore, kills, playtime, _ := game.RemovePlayer(player.Conn)
 
-		if userOk {
-			if userData.Discord.ID != "" {
-				go func() {
-					newUnlockedSkins, ok := UpdateUserStats(userData.Discord.ID, playerScore, kills, playtime)
-					if ok {
-						AddUnlockedSkinsLocally(player.Conn, newUnlockedSkins)
-					}
-				}()
-				RemovePlayingDiscordAccount(userData.Discord.ID)
-			}
-		}
+		finishUserSession(userData, player, playerScore, kills, playtime)
 
 		ClearFingerprintForConn(player.Conn)
 
 		removePlayerMessageState(player.ID)
-	case game.Kick:
-		e := event.Payload.(*game.KickEvent)
+	case *game.KickEvent:
 		player := e.Player
 		reason := e.Reason
 
 		sendKickNotification(player, reason)
 		broadcastPlayerLeft(player.ID)
 
-		userData, userOk := GetUserDataByConn(player.Conn)
+		userData, _ := GetUserDataByConn(player.Conn)
 		_, playerScore, kills, playtime, _ := game.RemovePlayer(player.Conn)
 
-		if userOk {
-			if userData.Discord.ID != "" {
-				go func() {
-					newUnlockedSkins, ok := UpdateUserStats(userData.Discord.ID, playerScore, kills, playtime)
-					if ok {
-						AddUnlockedSkinsLocally(player.Conn, newUnlockedSkins)
-					}
-				}()
-
-				RemovePlayingDiscordAccount(userData.Discord.ID)
-			}
-		}
+		finishUserSession(userData, player, playerScore, kills, playtime)
 
 		ClearFingerprintForConn(player.Conn)
 
 		removePlayerMessageState(player.ID)
-	case game.UnitBulletSpawn:
-		e := event.Payload.(*game.UnitBulletSpawnEvent)
+	case *game.UnitBulletSpawnEvent:
 		player := e.Player
 		bullet := e.Bullet
 		unit := e.Unit
-		broadcastUnitBulletSpawn(player.ID, unit.ID, bullet)
-	case game.BulletSpawn:
-		e := event.Payload.(*game.BulletSpawnEvent)
+		broadcastUnitBulletSpawn(player.ID, unit, bullet)
+	case *game.BulletSpawnEvent:
 		owner := e.Owner
 		bullet := e.Bullet
 		turret := e.Turret
 		broadcastBulletSpawn(owner, turret.ID, bullet)
-	case game.BulletRemove:
-		e := event.Payload.(*game.BulletRemoveEvent)
+	case *game.BulletRemoveEvent:
 		owner := e.Owner
 		bulletID := e.BulletID
-		broadcastBulletRemove(owner, bulletID)
-	case game.BulletPositionUpdate:
-		e := event.Payload.(*game.BulletPositionUpdateEvent)
+		broadcastBulletRemove(owner, e.Bullet, bulletID, e.Position)
+	case *game.BulletPositionUpdateEvent:
 		owner := e.Owner
 		bullet := e.Bullet
-		broadcastBulletPositionUpdate(owner, bullet)
-	case game.LeaderboardUpdate:
-		e := event.Payload.(*game.LeaderboardUpdateEvent)
+		broadcastBulletPositionUpdate(owner, bullet, e.Position, e.TargetPosition, e.Tick)
+	case *game.LeaderboardUpdateEvent:
 		changes := e.Changes
 		broadcastLeaderboardUpdate(changes)
-	case game.RemoveSpawnProtection:
-		e := event.Payload.(*game.RemoveSpawnProtectionEvent)
+	case *game.RemoveSpawnProtectionEvent:
 		player := e.Player
 		broadcastRemoveSpawnProtection(player.ID)
-	case game.NeutralBaseCaptured:
-		e := event.Payload.(*game.NeutralBaseCapturedEvent)
+	case *game.NeutralBaseCapturedEvent:
 		neutral := e.NeutralBase
 		broadcastNeutralBaseCaptured(neutral)
+	case *game.AllianceUpdateEvent:
+		broadcastAllianceUpdate(e.Alliance)
+	case *game.NeutralBaseCaptureProgressEvent:
+		broadcastNeutralBaseCaptureProgress(e.NeutralBase, e.Progress, e.CapturingPlayer, e.Contested, e.Tick)
+	case *game.AchievementUnlockedEvent:
+		sendAchievementUnlocked(e.Player, e.AchievementID)
+	case *game.UnitVisibilityEvent:
+		sendUnitVisibility(e.Player, e.Hidden, e.Revealed, e.Tick)
+	case *game.BaseVisibilityEvent:
+		sendBaseVisibility(e.Player, e.Hidden, e.Revealed, e.Tick)
+	case *game.WorldEventStartEvent:
+		broadcastWorldEventStart(e.Event)
+	case *game.WorldEventEndEvent:
+		broadcastWorldEventEnd(e.Event, e.CollectedBy)
+	case *game.PlayZoneUpdateEvent:
+		broadcastPlayZone(e.Zone)
+	case *game.RoundWonEvent:
+		broadcastRoundWon(e.Winner)
 	}
 }
 
 func listenForEvents() {
-	listener := make(chan game.Event, 10000)
-	game.AddListener(listener)
-
-	for {
-		select {
-		case event := <-listener:
-			workerPool.JobQueue <- event
-		}
-	}
+	// A single subscription keeps the events in the order they happened. While the network falls behind, a newer
+	// update of the same entity replaces the queued one in place (see CoalesceKey), every other event queues up
+	events := game.Bus.Subscribe(game.SubscriptionOptions{
+		Excluded:   []game.EventType{game.AchievementFlush},
+		BufferSize: 10000,
+		Policy:     game.BUFFER_COALESCE,
+	})
+
+	coalescer := NewEventCoalescer()
+	coalescer.Run(events.Events(), workerPool.JobQueue)
 }
 
 func WsEndpoint(w http.ResponseWriter, r *http.Request, userData UserData) {
@@ -206,6 +193,7 @@ func WsEndpoint(w http.ResponseWriter, r *http.Request, userData UserData) {
 	defer conn.Close()
 
 	StoreUserData(conn, userData)
+	setupCompression(conn, r)
 
 	onConnect(conn)
 
@@ -232,17 +220,14 @@ func onConnect(conn *websocket.Conn) {
 func removePlayerByConnection(conn *websocket.Conn) {
 
 	userData, userOk := GetUserDataByConn(conn)
+	player, _ := game.GetPlayerByConn(conn)
 
 	playerID, playerScore, kills, playtime, ok := game.RemovePlayer(conn)
 
 	if ok {
 		broadcastPlayerLeft(playerID)
 		removePlayerMessageState(playerID)
-		// Update user stats in a non-blocking way if a Discord ID exists
-		if userOk && userData.Discord.ID != "" {
-			go UpdateUserStats(userData.Discord.ID, playerScore, kills, playtime)
-			RemovePlayingDiscordAccount(userData.Discord.ID)
-		}
+		finishUserSession(userData, player, playerScore, kills, playtime)
 	} else {
 		log.Println("Client disconnected but was not an player in the game.")
 	}
@@ -252,6 +237,33 @@ func removePlayerByConnection(conn *websocket.Conn) {
 	}
 }
 
+// finishUserSession records the final score of a removed player, submits their stats and frees their Discord account.
+// Killed, kicked and disconnected players all end their session here
+func finishUserSession(userData UserData, player *game.Player, score uint32, kills uint32, playtime time.Duration) {
+	if player == nil {
+		return
+	}
+
+	name := game.HighScoreName(player.Name)
+	if userData.Discord.ID == "" {
+		game.RecordHighScore(name, name, false, score, kills, playtime)
+		return
+	}
+
+	game.RecordHighScore(userData.Discord.ID, name, true, score, kills, playtime)
+
+	// The events of the last moments, like the kill that ended the session, may still be evaluated
+	game.FlushAchievements()
+	SubmitUserStats(userData, player.Conn, UserStats{
+		Score:        score,
+		Kills:        kills,
+		Playtime:     playtime,
+		Session:      player.GetSessionStats(),
+		Achievements: player.GetEarnedAchievements(),
+	})
+	RemovePlayingDiscordAccount(userData.Discord.ID)
+}
+
 func CloseConnection(conn *websocket.Conn) {
 	if conn == nil {
 		return